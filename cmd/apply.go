@@ -0,0 +1,543 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/spf13/cobra"
+)
+
+type applyCmdFlags struct {
+	file  string
+	prune bool
+}
+
+var (
+	applyFlags applyCmdFlags
+)
+
+const manifestHelp = `A manifest is a YAML or JSON file listing objects by type: ssh-keys, networks, ips, storages, and servers. Objects are matched with the objects in the project by name, IP addresses without a name by their address. Servers refer to storages, networks, and IP addresses by name, either of an object in the manifest or of an object already in the project.
+
+    name: shop
+    ssh-keys:
+      - name: admin
+        key: ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGJ8 admin@example.com
+    networks:
+      - name: backend
+    ips:
+      - name: web-1
+        family: 4
+    storages:
+      - name: web-1
+        capacity: 20
+        type: storage_high
+        template: Ubuntu 22.04 LTS
+        hostname: web-1
+        ssh-keys: [admin]
+    servers:
+      - name: web-1
+        cores: 2
+        memory: 4
+        power: true
+        storages: [web-1]
+        networks: [backend]
+        ips: [web-1]
+        labels: [env=prod]
+
+Storages are initialized from template only when they are created. The first storage of a server is its boot device. A server is connected to exactly the storages, networks, and IP addresses listed, all others are detached. Power is left as it is unless given. Storages cannot shrink and IP addresses cannot change their family.
+
+Every object created or updated is labeled gscloud/managed-by=NAME, NAME being the name of the manifest or "gscloud" if it has none. With --prune, objects carrying that label that are no longer in the manifest are removed.`
+
+var applyCmd = &cobra.Command{
+	Use:     "apply -f FILE",
+	Example: `gscloud apply -f stack.yaml`,
+	Short:   "Create or update objects from a manifest",
+	Long: `Make the objects in a project match a manifest. Objects are created and updated in dependency order: SSH keys, networks, IP addresses, storages, and servers. Objects removed with --prune are confirmed like with the rm commands, all before any change is made, unless --yes is given. See confirm_destructive in gscloud-config(1).
+
+` + manifestHelp + `
+
+# EXAMPLES
+
+Look at the changes, then make them:
+
+	$ gscloud diff -f stack.yaml
+	$ gscloud apply -f stack.yaml
+
+Also remove servers and other objects dropped from the manifest:
+
+	$ gscloud apply --prune -f stack.yaml
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		plan, err := planFromFile(ctx)
+		if err != nil {
+			return NewError(cmd, "Could not plan changes", err)
+		}
+		err = confirmPrune(ctx, plan)
+		if err != nil {
+			return NewError(cmd, "Removing objects failed", err)
+		}
+		done := make([]change, 0, len(plan))
+		for _, c := range plan {
+			err = applyChange(ctx, &c, plan)
+			if err != nil {
+				return NewError(cmd, fmt.Sprintf("Could not %s %s %s", c.Action, c.Kind, c.Name), err)
+			}
+			done = append(done, c)
+			if !structuredOutput() && !rootFlags.quiet {
+				writeApplied(c)
+			}
+		}
+		if structuredOutput() {
+			err = renderOutput(os.Stdout, nil, nil, done)
+			if err != nil {
+				return NewError(cmd, "Could not render output", err)
+			}
+		}
+		return nil
+	},
+}
+
+var diffCmd = &cobra.Command{
+	Use:     "diff -f FILE",
+	Example: `gscloud diff -f stack.yaml`,
+	Short:   "Show changes apply would make",
+	Long: `Show the changes gscloud-apply(1) would make to the objects in a project, without making them.
+
+Objects to create are marked by "+", objects to update by "~", and objects to remove by "-". Changes to an object are listed below it.
+
+` + manifestHelp + `
+
+# EXAMPLES
+
+Show what apply --prune would do:
+
+	$ gscloud diff --prune -f stack.yaml
+	+ storage data-1
+	~ server web-1
+	    memory: 2 → 4
+	    attach storage data-1
+	- server web-2 (37d53278-8e5f-47e1-a63f-54513e4b4d53)
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		plan, err := planFromFile(ctx)
+		if err != nil {
+			return NewError(cmd, "Could not plan changes", err)
+		}
+		if structuredOutput() {
+			err = renderOutput(os.Stdout, nil, nil, plan)
+			if err != nil {
+				return NewError(cmd, "Could not render output", err)
+			}
+			return nil
+		}
+		writePlan(os.Stdout, plan)
+		return nil
+	},
+}
+
+// planFromFile makes the plan for the manifest given by --file.
+func planFromFile(ctx context.Context) ([]change, error) {
+	m, err := readManifest(applyFlags.file)
+	if err != nil {
+		return nil, err
+	}
+	live, err := getLiveObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := makePlan(m, live, applyFlags.prune)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// writeApplied prints a line telling about a change that has been made.
+func writeApplied(c change) {
+	switch c.Action {
+	case "create":
+		fmt.Printf("Created %s %s: %s\n", c.Kind, c.Name, c.ID)
+		if c.Password != "" {
+			fmt.Println("Password:", c.Password)
+		}
+	case "update":
+		fmt.Printf("Updated %s %s: %s\n", c.Kind, c.Name, strings.Join(c.Changes, ", "))
+	case "remove":
+		fmt.Fprintf(os.Stderr, "Removed %s\n", c.ID)
+	}
+}
+
+// idInPlan returns the ID of the object of kind called name in the plan,
+// which is known once the object has been created.
+func idInPlan(plan []change, kind, name string) (string, error) {
+	for _, c := range plan {
+		if c.Kind == kind && c.Name == name && c.ID != "" {
+			return c.ID, nil
+		}
+	}
+	return "", fmt.Errorf("%s %s has not been created", kind, name)
+}
+
+// applyChange makes a change. The ID of objects created is stored in c and
+// plan.
+func applyChange(ctx context.Context, c *change, plan []change) error {
+	var err error
+	switch c.Action {
+	case "create":
+		err = createObject(ctx, c, plan)
+		for i := range plan {
+			if plan[i].Kind == c.Kind && plan[i].Name == c.Name {
+				plan[i].ID = c.ID
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if c.Kind == "server" {
+			return updateServerRelations(ctx, c, plan)
+		}
+		return nil
+	case "update":
+		if c.update {
+			err = updateObject(ctx, c)
+			if err != nil {
+				return err
+			}
+		}
+		if c.Kind == "server" {
+			return updateServerRelations(ctx, c, plan)
+		}
+		return nil
+	case "remove":
+		return removeObject(ctx, c)
+	}
+	return fmt.Errorf("unknown action %q", c.Action)
+}
+
+func createObject(ctx context.Context, c *change, plan []change) error {
+	switch obj := c.object.(type) {
+	case *manifestSSHKey:
+		resp, err := rt.SSHKeyOperator().CreateSshkey(ctx, gsclient.SshkeyCreateRequest{
+			Name:   obj.Name,
+			Sshkey: strings.TrimSpace(obj.Key),
+			Labels: c.labels,
+		})
+		c.ID = resp.ObjectUUID
+		return err
+
+	case *manifestNetwork:
+		networkOp := rt.NetworkOperator()
+		resp, err := networkOp.CreateNetwork(ctx, gsclient.NetworkCreateRequest{
+			Name:       obj.Name,
+			L2Security: obj.L2Security,
+			Labels:     c.labels,
+		})
+		if err != nil {
+			return err
+		}
+		c.ID = resp.ObjectUUID
+		return waitForNetwork(ctx, networkOp, c.ID)
+
+	case *manifestIP:
+		resp, err := rt.IPOperator().CreateIP(ctx, gsclient.IPCreateRequest{
+			Name:       obj.Name,
+			Family:     gsclient.IPAddressType(obj.Family),
+			Failover:   obj.Failover,
+			ReverseDNS: obj.ReverseDNS,
+			Labels:     c.labels,
+		})
+		c.ID = resp.ObjectUUID
+		return err
+
+	case *manifestStorage:
+		req := gsclient.StorageCreateRequest{
+			Name:     obj.Name,
+			Capacity: obj.Capacity,
+			Labels:   c.labels,
+		}
+		if obj.Type != "" {
+			storageType, err := toStorageType(obj.Type)
+			if err != nil {
+				return err
+			}
+			req.StorageType = storageType
+		}
+		if obj.Template != "" {
+			templateID, err := idForTemplate(ctx, obj.Template)
+			if err != nil {
+				return err
+			}
+			var keys []string
+			for _, k := range obj.SSHKeys {
+				id, err := idInPlan(plan, "ssh-key", k)
+				if err != nil {
+					id, err = idForSSHKey(ctx, k)
+				}
+				if err != nil {
+					return err
+				}
+				keys = append(keys, id)
+			}
+			c.Password = generatePassword()
+			req.Template = &gsclient.StorageTemplate{
+				TemplateUUID: templateID,
+				Password:     c.Password,
+				PasswordType: gsclient.PlainPasswordType,
+				Hostname:     obj.Hostname,
+				Sshkeys:      keys,
+			}
+		}
+		storageOp := rt.StorageOperator()
+		resp, err := storageOp.CreateStorage(ctx, req)
+		if err != nil {
+			return err
+		}
+		c.ID = resp.ObjectUUID
+		return waitForStorage(ctx, storageOp, c.ID)
+
+	case *manifestServer:
+		req := gsclient.ServerCreateRequest{
+			Name:   obj.Name,
+			Cores:  obj.Cores,
+			Memory: obj.Memory,
+			Labels: c.labels,
+		}
+		if obj.Profile != "" {
+			profile, err := toHardwareProfile(obj.Profile)
+			if err != nil {
+				return err
+			}
+			req.HardwareProfile = profile
+		}
+		serverOp := rt.ServerOperator()
+		resp, err := serverOp.CreateServer(ctx, req)
+		if err != nil {
+			return err
+		}
+		c.ID = resp.ObjectUUID
+		return waitForServer(ctx, serverOp, c.ID)
+	}
+	return fmt.Errorf("cannot create %s", c.Kind)
+}
+
+func updateObject(ctx context.Context, c *change) error {
+	switch obj := c.object.(type) {
+	case *manifestSSHKey:
+		return rt.SSHKeyOperator().UpdateSshkey(ctx, c.ID, gsclient.SshkeyUpdateRequest{
+			Sshkey: strings.TrimSpace(obj.Key),
+			Labels: &c.labels,
+		})
+
+	case *manifestNetwork:
+		return rt.NetworkOperator().UpdateNetwork(ctx, c.ID, gsclient.NetworkUpdateRequest{
+			L2Security: obj.L2Security,
+			Labels:     &c.labels,
+		})
+
+	case *manifestIP:
+		return rt.IPOperator().UpdateIP(ctx, c.ID, gsclient.IPUpdateRequest{
+			Failover:   obj.Failover,
+			ReverseDNS: obj.ReverseDNS,
+			Labels:     &c.labels,
+		})
+
+	case *manifestStorage:
+		req := gsclient.StorageUpdateRequest{
+			Capacity: obj.Capacity,
+			Labels:   &c.labels,
+		}
+		if obj.Type != "" {
+			storageType, err := toStorageType(obj.Type)
+			if err != nil {
+				return err
+			}
+			req.StorageType = storageType
+		}
+		storageOp := rt.StorageOperator()
+		err := storageOp.UpdateStorage(ctx, c.ID, req)
+		if err != nil {
+			return err
+		}
+		return waitForStorage(ctx, storageOp, c.ID)
+
+	case *manifestServer:
+		serverOp := rt.ServerOperator()
+		err := serverOp.UpdateServer(ctx, c.ID, gsclient.ServerUpdateRequest{
+			Cores:  obj.Cores,
+			Memory: obj.Memory,
+			Labels: &c.labels,
+		})
+		if err != nil {
+			return err
+		}
+		return waitForServer(ctx, serverOp, c.ID)
+	}
+	return fmt.Errorf("cannot update %s", c.Kind)
+}
+
+// updateServerRelations detaches and attaches storages, networks, and IP
+// addresses, then turns the server on or off if needed.
+func updateServerRelations(ctx context.Context, c *change, plan []change) error {
+	for _, r := range c.detach {
+		var err error
+		switch r.Kind {
+		case "storage":
+			err = rt.ServerStorageRelationOperator().DeleteServerStorage(ctx, c.ID, r.ID)
+		case "network":
+			err = rt.ServerNetworkRelationOperator().DeleteServerNetwork(ctx, c.ID, r.ID)
+		case "ip":
+			err = rt.ServerIPRelationOperator().DeleteServerIP(ctx, c.ID, r.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("detaching %s: %w", r, err)
+		}
+	}
+	for _, r := range c.attach {
+		id := r.ID
+		if id == "" {
+			var err error
+			id, err = idInPlan(plan, r.Kind, r.Name)
+			if err != nil {
+				return err
+			}
+		}
+		var err error
+		switch r.Kind {
+		case "storage":
+			err = rt.ServerStorageRelationOperator().CreateServerStorage(ctx, c.ID, gsclient.ServerStorageRelationCreateRequest{
+				ObjectUUID: id,
+				BootDevice: r.Boot,
+			})
+		case "network":
+			err = rt.ServerNetworkRelationOperator().CreateServerNetwork(ctx, c.ID, gsclient.ServerNetworkRelationCreateRequest{
+				ObjectUUID: id,
+			})
+		case "ip":
+			err = rt.ServerIPRelationOperator().CreateServerIP(ctx, c.ID, gsclient.ServerIPRelationCreateRequest{
+				ObjectUUID: id,
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("attaching %s: %w", r, err)
+		}
+	}
+
+	obj := c.object.(*manifestServer)
+	if obj.Power == nil {
+		return nil
+	}
+	serverOp := rt.ServerOperator()
+	if c.Action == "update" {
+		server, err := serverOp.GetServer(ctx, c.ID)
+		if err != nil {
+			return err
+		}
+		if server.Properties.Power == *obj.Power {
+			return nil
+		}
+	} else if !*obj.Power {
+		return nil
+	}
+	var err error
+	if *obj.Power {
+		err = serverOp.StartServer(ctx, c.ID)
+	} else {
+		err = serverOp.ShutdownServer(ctx, c.ID)
+	}
+	if err != nil {
+		return err
+	}
+	return waitForServerPower(ctx, serverOp, c.ID, *obj.Power)
+}
+
+// confirmPrune asks for confirmation before each object removed by the
+// plan, as the rm commands do. All removals are confirmed before any change
+// is made.
+func confirmPrune(ctx context.Context, plan []change) error {
+	for _, c := range plan {
+		if c.Action != "remove" {
+			continue
+		}
+		c := c
+		err := confirmRemoval(func() (removal, error) {
+			return describeChange(ctx, c)
+		})
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", c.Kind, c.Name, err)
+		}
+	}
+	return nil
+}
+
+// describeChange describes an object to be removed by a change.
+func describeChange(ctx context.Context, c change) (removal, error) {
+	switch c.Kind {
+	case "server":
+		s, err := rt.ServerOperator().GetServer(ctx, c.ID)
+		if err != nil {
+			return removal{}, err
+		}
+		return describeServer(s, false), nil
+	case "storage":
+		s, err := rt.StorageOperator().GetStorage(ctx, c.ID)
+		if err != nil {
+			return removal{}, err
+		}
+		return describeStorage(s), nil
+	case "network":
+		n, err := rt.NetworkOperator().GetNetwork(ctx, c.ID)
+		if err != nil {
+			return removal{}, err
+		}
+		return describeNetwork(n), nil
+	case "ip":
+		ip, err := rt.IPOperator().GetIP(ctx, c.ID)
+		if err != nil {
+			return removal{}, err
+		}
+		return describeIP(ip), nil
+	}
+	return removal{kind: c.Kind, name: c.Name, id: c.ID}, nil
+}
+
+func removeObject(ctx context.Context, c *change) error {
+	switch c.Kind {
+	case "ssh-key":
+		return rt.SSHKeyOperator().DeleteSshkey(ctx, c.ID)
+	case "network":
+		return rt.NetworkOperator().DeleteNetwork(ctx, c.ID)
+	case "ip":
+		return rt.IPOperator().DeleteIP(ctx, c.ID)
+	case "storage":
+		return rt.StorageOperator().DeleteStorage(ctx, c.ID)
+	case "server":
+		serverOp := rt.ServerOperator()
+		if c.running {
+			err := serverOp.StopServer(ctx, c.ID)
+			if err != nil {
+				return err
+			}
+		}
+		return serverOp.DeleteServer(ctx, c.ID)
+	}
+	return fmt.Errorf("cannot remove %s", c.Kind)
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{applyCmd, diffCmd} {
+		cmd.Flags().StringVarP(&applyFlags.file, "file", "f", "", "Path to the manifest, or - to read it from stdin")
+		cmd.MarkFlagRequired("file")
+		cmd.Flags().BoolVar(&applyFlags.prune, "prune", false, "Remove objects labeled as managed by the manifest that are no longer in it")
+	}
+
+	addConfirmFlags(applyCmd)
+
+	rootCmd.AddCommand(applyCmd, diffCmd)
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+const testManifest = `
+name: shop
+ssh-keys:
+  - name: admin
+    key: ssh-ed25519 AAAA admin@example.com
+networks:
+  - name: backend
+storages:
+  - name: web-1
+    capacity: 20
+    template: Ubuntu
+    ssh-keys: [admin]
+  - name: data
+    capacity: 50
+servers:
+  - name: web-1
+    cores: 2
+    memory: 4
+    power: true
+    storages: [web-1, data]
+    networks: [backend]
+`
+
+func Test_ParseManifest(t *testing.T) {
+	m, err := parseManifest([]byte(testManifest))
+	assert.Nil(t, err)
+	assert.Equal(t, "gscloud/managed-by=shop", m.managedBy())
+	assert.Len(t, m.Storages, 2)
+	assert.Equal(t, []string{"web-1", "data"}, m.Servers[0].Storages)
+	assert.True(t, *m.Servers[0].Power)
+
+	for _, invalid := range []string{
+		"servers:\n  - name: a\n    cores: 1\n    memory: 1\n    colour: red\n",
+		"servers:\n  - name: a\n    cores: 0\n    memory: 1\n",
+		"networks:\n  - name: a\n  - name: a\n",
+		"storages:\n  - name: a\n    capacity: 10\n    type: fast\n",
+		"ips:\n  - name: a\n",
+		"ssh-keys:\n  - name: a\n",
+	} {
+		_, err := parseManifest([]byte(invalid))
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func Test_MakePlanCreate(t *testing.T) {
+	m, _ := parseManifest([]byte(testManifest))
+	plan, err := makePlan(m, liveObjects{}, false)
+	assert.Nil(t, err)
+
+	var steps []string
+	for _, c := range plan {
+		steps = append(steps, c.Action+" "+c.Kind+" "+c.Name)
+		assert.Equal(t, []string{"gscloud/managed-by=shop"}, c.labels)
+	}
+	assert.Equal(t, []string{
+		"create ssh-key admin",
+		"create network backend",
+		"create storage web-1",
+		"create storage data",
+		"create server web-1",
+	}, steps)
+	server := plan[4]
+	assert.Equal(t, []relation{
+		{Kind: "storage", Name: "web-1", Boot: true},
+		{Kind: "storage", Name: "data"},
+		{Kind: "network", Name: "backend"},
+	}, server.attach)
+}
+
+func Test_MakePlanUpdate(t *testing.T) {
+	m, _ := parseManifest([]byte(testManifest))
+	managed := []string{"gscloud/managed-by=shop"}
+	live := liveObjects{
+		SSHKeys: []gsclient.Sshkey{
+			{Properties: gsclient.SshkeyProperties{ObjectUUID: "k1", Name: "admin", Sshkey: "ssh-ed25519 AAAA admin@example.com\n", Labels: managed}},
+		},
+		Networks: []gsclient.Network{
+			{Properties: gsclient.NetworkProperties{ObjectUUID: "n1", Name: "backend", Labels: managed}},
+			{Properties: gsclient.NetworkProperties{ObjectUUID: "n2", Name: "Public Network"}},
+		},
+		Storages: []gsclient.Storage{
+			{Properties: gsclient.StorageProperties{ObjectUUID: "s1", Name: "web-1", Capacity: 10, StorageType: "storage", Labels: managed}},
+			{Properties: gsclient.StorageProperties{ObjectUUID: "s2", Name: "old", Capacity: 10, Labels: managed}},
+			{Properties: gsclient.StorageProperties{ObjectUUID: "s3", Name: "other", Capacity: 10}},
+		},
+		Servers: []gsclient.Server{
+			{Properties: gsclient.ServerProperties{
+				ObjectUUID: "v1", Name: "web-1", Cores: 2, Memory: 2, Power: true,
+				Labels: []string{"gscloud/managed-by=shop", "gscloud/protect=true", "stale"},
+				Relations: gsclient.ServerRelations{
+					Storages: []gsclient.ServerStorageRelationProperties{{ObjectUUID: "s1", ObjectName: "web-1"}, {ObjectUUID: "s2", ObjectName: "old"}},
+					Networks: []gsclient.ServerNetworkRelationProperties{{ObjectUUID: "n2", ObjectName: "Public Network"}},
+				},
+			}},
+		},
+	}
+
+	plan, err := makePlan(m, live, true)
+	assert.Nil(t, err)
+	assert.Len(t, plan, 4)
+
+	assert.Equal(t, "update", plan[0].Action)
+	assert.Equal(t, "storage", plan[0].Kind)
+	assert.Equal(t, []string{"capacity: 10 → 20"}, plan[0].Changes)
+	assert.Equal(t, "", plan[0].object.(*manifestStorage).Type)
+
+	assert.Equal(t, "create", plan[1].Action)
+	assert.Equal(t, "data", plan[1].Name)
+
+	server := plan[2]
+	assert.Equal(t, "update", server.Action)
+	assert.Equal(t, "v1", server.ID)
+	assert.Equal(t, []string{"gscloud/managed-by=shop", "gscloud/protect=true"}, server.labels)
+	assert.Equal(t, []string{
+		"memory: 2 → 4",
+		"labels: gscloud/managed-by=shop,gscloud/protect=true,stale → gscloud/managed-by=shop,gscloud/protect=true",
+		"detach storage old",
+		"detach network Public Network",
+		"attach storage data",
+		"attach network backend",
+	}, server.Changes)
+
+	assert.Equal(t, change{Action: "remove", Kind: "storage", Name: "old", ID: "s2"}, plan[3])
+
+	buf := new(bytes.Buffer)
+	writePlan(buf, plan[1:2])
+	assert.Equal(t, "+ storage data\n", buf.String())
+	buf.Reset()
+	writePlan(buf, plan[3:])
+	assert.Equal(t, "- storage old (s2)\n", buf.String())
+}
+
+func Test_MakePlanInvalid(t *testing.T) {
+	live := liveObjects{
+		Storages: []gsclient.Storage{
+			{Properties: gsclient.StorageProperties{ObjectUUID: "s1", Name: "big", Capacity: 100}},
+			{Properties: gsclient.StorageProperties{ObjectUUID: "s2", Name: "twin", Capacity: 10}},
+			{Properties: gsclient.StorageProperties{ObjectUUID: "s3", Name: "twin", Capacity: 10}},
+		},
+	}
+	for _, text := range []string{
+		"storages:\n  - name: big\n    capacity: 10\n",
+		"storages:\n  - name: twin\n    capacity: 10\n",
+		"servers:\n  - name: a\n    cores: 1\n    memory: 1\n    storages: [missing]\n",
+	} {
+		m, err := parseManifest([]byte(text))
+		assert.Nil(t, err)
+		_, err = makePlan(m, live, false)
+		assert.NotNil(t, err, text)
+	}
+}
+
+func Test_ConfirmPrune(t *testing.T) {
+	ctx := context.Background()
+	plan := []change{
+		{Action: "create", Kind: "network", Name: "backend"},
+		{Action: "remove", Kind: "ssh-key", Name: "old", ID: "6a0ab6b0-e2a2-4c04-a2b4-8b4f6f2a7d5e"},
+	}
+	// Standard input of tests is not a terminal.
+	err := confirmPrune(ctx, plan)
+	assert.ErrorIs(t, err, errNoTerminal)
+	assert.Contains(t, err.Error(), "ssh-key old")
+	assert.Nil(t, confirmPrune(ctx, plan[:1]))
+
+	confirmFlags.yes = true
+	defer func() { confirmFlags.yes = false }()
+	assert.Nil(t, confirmPrune(ctx, plan))
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+type bulkCmdFlags struct {
+	all      bool
+	labels   []string
+	name     string
+	parallel int
+}
+
+var bulkFlags bulkCmdFlags
+
+// bulkError is returned when a command failed for some of the objects it
+// acted on. It unwraps to the error of the first object that timed out, or
+// else of the first object that failed, so that the exit code tells about a
+// timeout.
+type bulkError struct {
+	failed []string
+	total  int
+	err    error
+}
+
+func (e *bulkError) Error() string {
+	return fmt.Sprintf("command failed for %d of %d objects: %s", len(e.failed), e.total, strings.Join(e.failed, ", "))
+}
+
+// Unwrap returns the error of the first object that timed out or failed.
+func (e *bulkError) Unwrap() error { return e.err }
+
+// addBulkFlags lets a command acting on the object of type kindName given
+// by its only argument act on several objects: given by ID or name, all
+// objects with --all, or the ones selected by --label and --name. The
+// command runs for up to --parallel objects at a time.
+func addBulkFlags(cmd *cobra.Command, kindName string) {
+	cmd.Flags().BoolVar(&bulkFlags.all, "all", false, "Act on all objects")
+	cmd.Flags().StringArrayVarP(&bulkFlags.labels, "label", "l", nil, "Act on all objects with a label matching PATTERN")
+	cmd.Flags().StringVar(&bulkFlags.name, "name", "", "Act on all objects with a name matching PATTERN")
+	cmd.Flags().IntVar(&bulkFlags.parallel, "parallel", 1, "Act on up to N objects at the same time")
+	cmd.Args = bulkArgs
+	run := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return runBulk(cmd, kindName, args, run)
+	}
+}
+
+// selecting tells whether objects are selected by flags rather than given
+// as arguments.
+func (f bulkCmdFlags) selecting() bool {
+	return f.all || len(f.labels) > 0 || f.name != ""
+}
+
+// bulkArgs accepts one or more IDs, or none if objects are selected by
+// --all, --label, or --name.
+func bulkArgs(cmd *cobra.Command, args []string) error {
+	if bulkFlags.selecting() {
+		if len(args) > 0 {
+			return fmt.Errorf("accepts no IDs with --all, --label, or --name, received %d", len(args))
+		}
+		return nil
+	}
+	return cobra.MinimumNArgs(1)(cmd, args)
+}
+
+// runBulk calls run once for each object given by args or selected by
+// flags, each time with the ID or name of that object as the only argument.
+// A single object given by args is handled as before. Otherwise failures
+// are reported per object as they happen, and an error is returned if run
+// failed for any object.
+func runBulk(cmd *cobra.Command, kindName string, args []string, run func(*cobra.Command, []string) error) error {
+	if bulkFlags.parallel < 1 {
+		return NewError(cmd, "Invalid value for --parallel", errors.New("must be at least 1"))
+	}
+	if !bulkFlags.selecting() && len(args) == 1 {
+		return run(cmd, args)
+	}
+	targets := unique(args)
+	if bulkFlags.selecting() {
+		var err error
+		targets, err = selectObjects(context.Background(), kindName, bulkFlags.labels, bulkFlags.name)
+		if err != nil {
+			return NewError(cmd, "Could not select objects", err)
+		}
+		if len(targets) == 0 {
+			fmt.Fprintf(os.Stderr, "No %s selected\n", kindName)
+			return nil
+		}
+	}
+
+	parallel := bulkFlags.parallel
+	if rootFlags.dryRun {
+		// Keep the requests printed in the order of the objects.
+		parallel = 1
+	}
+	errs := make([]error, len(targets))
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, target string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			errs[i] = run(cmd, []string{target})
+			if errs[i] != nil {
+				fmt.Fprintf(os.Stderr, "Failed %s: %s\n", target, errs[i])
+			}
+		}(i, target)
+	}
+	wg.Wait()
+
+	bulkErr := &bulkError{total: len(targets)}
+	timedOut := false
+	for i, err := range errs {
+		if err != nil {
+			var timeoutErr *TimeoutError
+			if !timedOut && errors.As(err, &timeoutErr) {
+				bulkErr.err = err
+				timedOut = true
+			} else if bulkErr.err == nil {
+				bulkErr.err = err
+			}
+			bulkErr.failed = append(bulkErr.failed, targets[i])
+		}
+	}
+	if len(bulkErr.failed) > 0 {
+		return bulkErr
+	}
+	return nil
+}
+
+// selectObjects returns the IDs of the objects of type kindName with labels
+// matching all label patterns and, unless name is empty, a name matching
+// name. Patterns are matched as by --label and --filter of the ls
+// commands. Without patterns, all objects are selected.
+func selectObjects(ctx context.Context, kindName string, labels []string, name string) ([]string, error) {
+	kind, err := lookupLabeledKind(kindName)
+	if err != nil {
+		return nil, err
+	}
+	var labelPatterns []labelPattern
+	for _, l := range labels {
+		p, err := compileLabelPattern(l)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label %q: %w", l, err)
+		}
+		labelPatterns = append(labelPatterns, p)
+	}
+	namePattern, err := compileGlob(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name %q: %w", name, err)
+	}
+	objs, err := kind.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, obj := range objs {
+		selected := name == "" || namePattern.MatchString(obj.Name)
+		for _, p := range labelPatterns {
+			if !p.matches(obj.Labels) {
+				selected = false
+				break
+			}
+		}
+		if selected {
+			ids = append(ids, obj.ID)
+		}
+	}
+	return ids, nil
+}
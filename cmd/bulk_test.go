@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/gridscale/gscloud/runtime"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+var bulkServers = []gsclient.Server{
+	{Properties: gsclient.ServerProperties{ObjectUUID: "s1", Name: "web-1", Labels: []string{"env=prod", "team=shop"}}},
+	{Properties: gsclient.ServerProperties{ObjectUUID: "s2", Name: "web-2", Labels: []string{"env=staging"}}},
+	{Properties: gsclient.ServerProperties{ObjectUUID: "s3", Name: "db-1", Labels: []string{"env=prod"}}},
+}
+
+// bulkServerOp is a server operator safe to call from several goroutines.
+type bulkServerOp struct {
+	gsclient.ServerOperator
+	mu      sync.Mutex
+	started []string
+}
+
+func (o *bulkServerOp) GetServerList(ctx context.Context) ([]gsclient.Server, error) {
+	return bulkServers, nil
+}
+
+func (o *bulkServerOp) StartServer(ctx context.Context, id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.started = append(o.started, id)
+	if id == "s3" {
+		return errors.New("test")
+	}
+	return nil
+}
+
+func Test_SelectObjects(t *testing.T) {
+	rt, _ = runtime.NewTestRuntime()
+	op := mockServerOp{}
+	op.On("GetServerList").Return(bulkServers, nil)
+	rt.SetServerOperator(&op)
+	ctx := context.Background()
+
+	type testCase struct {
+		labels []string
+		name   string
+		ids    []string
+	}
+	testCases := []testCase{
+		{ids: []string{"s1", "s2", "s3"}},
+		{labels: []string{"env=prod"}, ids: []string{"s1", "s3"}},
+		{labels: []string{"env=*", "team"}, ids: []string{"s1"}},
+		{labels: []string{"env=prod"}, name: "web-*", ids: []string{"s1"}},
+		{name: "app-*"},
+	}
+	for _, tc := range testCases {
+		ids, err := selectObjects(ctx, "server", tc.labels, tc.name)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.ids, ids)
+	}
+}
+
+func Test_RunBulk(t *testing.T) {
+	defer func() { bulkFlags = bulkCmdFlags{parallel: 1} }()
+	rt, _ = runtime.NewTestRuntime()
+	op := &bulkServerOp{}
+	rt.SetServerOperator(op)
+
+	bulkFlags = bulkCmdFlags{labels: []string{"env=prod"}, parallel: 2}
+	assert.NotNil(t, serverOnCmd.Args(new(cobra.Command), []string{"s2"}))
+	assert.Nil(t, serverOnCmd.Args(new(cobra.Command), nil))
+	err := serverOnCmd.RunE(new(cobra.Command), nil)
+	var bulkErr *bulkError
+	assert.True(t, errors.As(err, &bulkErr))
+	assert.Equal(t, []string{"s3"}, bulkErr.failed)
+	assert.Equal(t, 2, bulkErr.total)
+	assert.ElementsMatch(t, []string{"s1", "s3"}, op.started)
+
+	op.started = nil
+	bulkFlags = bulkCmdFlags{parallel: 2}
+	assert.NotNil(t, serverOnCmd.Args(new(cobra.Command), nil))
+	err = serverOnCmd.RunE(new(cobra.Command), []string{"s1", "s2", "s1"})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"s1", "s2"}, op.started)
+
+	bulkFlags = bulkCmdFlags{all: true, parallel: 0}
+	assert.NotNil(t, serverOnCmd.RunE(new(cobra.Command), nil))
+}
+
+func Test_RunBulkTimeout(t *testing.T) {
+	defer func() { bulkFlags = bulkCmdFlags{parallel: 1} }()
+	bulkFlags = bulkCmdFlags{parallel: 1}
+	run := func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "s1":
+			return errors.New("failed")
+		case "s2":
+			return &TimeoutError{What: "server s2", Timeout: time.Minute}
+		}
+		return nil
+	}
+	err := runBulk(new(cobra.Command), "server", []string{"s1", "s2", "s3"}, run)
+	var bulkErr *bulkError
+	assert.True(t, errors.As(err, &bulkErr))
+	assert.Equal(t, []string{"s1", "s2"}, bulkErr.failed)
+	assert.Equal(t, exitCodeTimeout, exitCode(err))
+
+	err = runBulk(new(cobra.Command), "server", []string{"s1", "s3"}, run)
+	assert.Equal(t, 1, exitCode(err))
+}
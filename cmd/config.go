@@ -0,0 +1,537 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gridscale/gscloud/runtime"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+type configCmdFlags struct {
+	store  string
+	dir    string
+	name   string
+	userID string
+	token  string
+	url    string
+	use    bool
+}
+
+var (
+	configFlags configCmdFlags
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Operations on the configuration file",
+	Long: `List, add, change, or remove the projects in the configuration file, and choose the default project.
+
+The default project is used unless another one is selected by --project, --account, or the environment variable GRIDSCALE_ACCOUNT. Without a default project, the project called "default" is used.
+
+# DEFAULTS
+
+A project can give defaults for flags not given on the command line. Flags given on the command line always win. "output" is the default output format, and "labels" are given to every object created. Defaults for the flags of create commands are grouped by object type; the prefix "with-" of a flag can be left out:
+
+    projects:
+      - name: prod
+        userId: 7e6b0bd4-3ac6-4c88-b36c-2d5dbd5d5a3b
+        token: keyring:prod
+        defaults:
+          output: json
+          labels: [env=prod]
+          server:
+            cores: 2
+            mem: 4
+            profile: q35
+            availability-zone: a
+            template: Ubuntu 22.04 LTS
+          storage:
+            type: storage_high
+
+Defaults for unknown object types or flags are an error.
+
+# CONFIRMATION
+
+Removing servers, storages, networks, templates, and IP addresses, as well as objects removed by apply --prune, needs to be confirmed, after the name, size, and relations of the object are shown. --yes skips the confirmation. The setting confirm_destructive tells when to ask:
+
+    confirm_destructive: tty
+    projects:
+      - ...
+
+"tty", the default, asks if standard input is a terminal. Otherwise, objects are not removed unless --yes is given. "always" asks even if standard input is not a terminal, and reads the answer from it. "never" removes objects without asking.
+
+# PROTECTION
+
+Objects carrying the protection label are never removed or shrunk by gscloud, see gscloud-protect(1). The label is gscloud/protect=true, unless protect_label says otherwise:
+
+    protect_label: keep=forever`,
+}
+
+// projectInfo is a project as listed by config ls and show. The token is
+// only shown if it is a reference.
+type projectInfo struct {
+	Name    string `yaml:"name" json:"name"`
+	UserID  string `yaml:"userId" json:"userId"`
+	Token   string `yaml:"token" json:"token"`
+	URL     string `yaml:"url" json:"url"`
+	Default bool   `yaml:"default" json:"default"`
+}
+
+func newProjectInfo(conf *runtime.Config, p runtime.ProjectEntry) projectInfo {
+	return projectInfo{
+		Name:    p.Name,
+		UserID:  p.UserID,
+		Token:   tokenSource(p.Token),
+		URL:     p.URL,
+		Default: p.Name == defaultProject(conf),
+	}
+}
+
+// tokenSource returns a token reference as it is, and hides plain tokens.
+func tokenSource(token string) string {
+	switch {
+	case token == "":
+		return ""
+	case runtime.IsSecretReference(token):
+		return token
+	}
+	return "(plain)"
+}
+
+var configLsCmd = &cobra.Command{
+	Use:     "ls [flags]",
+	Aliases: []string{"list"},
+	Short:   "List projects",
+	Long:    `List the projects in the configuration file. The default project is marked by "*". Tokens are only shown if they refer to a secret kept elsewhere.`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, err := runtime.ParseConfig()
+		if err != nil {
+			return NewError(cmd, "Could not read configuration", err)
+		}
+		var rows [][]string
+		projects := []projectInfo{}
+		for _, p := range conf.Projects {
+			info := newProjectInfo(conf, p)
+			projects = append(projects, info)
+			if quietOutput() {
+				fmt.Println(p.Name)
+				continue
+			}
+			mark := ""
+			if info.Default {
+				mark = "*"
+			}
+			rows = append(rows, []string{mark, info.Name, info.UserID, info.URL, info.Token})
+		}
+		if quietOutput() {
+			return nil
+		}
+		err = renderOutput(os.Stdout, []string{"default", "name", "user-id", "url", "token"}, rows, projects)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
+		}
+		return nil
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:     "show [flags] [NAME]",
+	Example: `gscloud config show staging`,
+	Short:   "Show project",
+	Long:    `Show a project in the configuration file, the default project if none is given. The token is only shown if it refers to a secret kept elsewhere.`,
+	Args:    cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, err := runtime.ParseConfig()
+		if err != nil {
+			return NewError(cmd, "Could not read configuration", err)
+		}
+		name := defaultProject(conf)
+		if len(args) > 0 {
+			name = args[0]
+		}
+		p := conf.Project(name)
+		if p == nil {
+			return NewError(cmd, "Could not find project", fmt.Errorf("project '%s' does not exist", name))
+		}
+		info := newProjectInfo(conf, *p)
+		if structuredOutput() {
+			err = renderOutput(os.Stdout, nil, nil, info)
+			if err != nil {
+				return NewError(cmd, "Could not render output", err)
+			}
+			return nil
+		}
+		out, err := yaml.Marshal(info)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
+		}
+		fmt.Print(string(out))
+		return nil
+	},
+}
+
+var configAddCmd = &cobra.Command{
+	Use:     "add [flags] NAME",
+	Example: `gscloud config add --user-id 7e6b0bd4-3ac6-4c88-b36c-2d5dbd5d5a3b --token keyring:staging staging`,
+	Short:   "Add project",
+	Long: `Add a project to the configuration file. The configuration file is created if it does not exist yet.
+
+The token is either the API token itself or a reference to it, see gscloud-config-migrate-secrets(1). With **--use**, the project becomes the default project.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, err := runtime.ParseConfig()
+		if err != nil {
+			return NewError(cmd, "Could not read configuration", err)
+		}
+		if conf.Project(args[0]) != nil {
+			return NewError(cmd, "Could not add project", fmt.Errorf("project '%s' already exists", args[0]))
+		}
+		conf.Projects = append(conf.Projects, runtime.ProjectEntry{
+			Name:   args[0],
+			UserID: configFlags.userID,
+			Token:  configFlags.token,
+			URL:    configFlags.url,
+		})
+		if configFlags.use {
+			conf.DefaultProject = args[0]
+		}
+		err = runtime.WriteConfig(conf, configFilePath())
+		if err != nil {
+			return NewError(cmd, "Could not write configuration", err)
+		}
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:     "set [flags] NAME",
+	Example: `gscloud config set --token env:STAGING_TOKEN staging`,
+	Short:   "Change project",
+	Long:    `Change the name, user ID, token, or API URL of a project in the configuration file. Only the properties given are changed.`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, err := runtime.ParseConfig()
+		if err != nil {
+			return NewError(cmd, "Could not read configuration", err)
+		}
+		p := conf.Project(args[0])
+		if p == nil {
+			return NewError(cmd, "Could not find project", fmt.Errorf("project '%s' does not exist", args[0]))
+		}
+		flags := cmd.Flags()
+		if flags.Changed("name") && configFlags.name != p.Name {
+			if conf.Project(configFlags.name) != nil {
+				return NewError(cmd, "Could not change project", fmt.Errorf("project '%s' already exists", configFlags.name))
+			}
+			if conf.DefaultProject == p.Name {
+				conf.DefaultProject = configFlags.name
+			}
+			p.Name = configFlags.name
+		}
+		if flags.Changed("user-id") {
+			p.UserID = configFlags.userID
+		}
+		if flags.Changed("token") {
+			p.Token = configFlags.token
+		}
+		if flags.Changed("url") {
+			p.URL = configFlags.url
+		}
+		err = runtime.WriteConfig(conf, configFilePath())
+		if err != nil {
+			return NewError(cmd, "Could not write configuration", err)
+		}
+		return nil
+	},
+}
+
+var configRmCmd = &cobra.Command{
+	Use:     "rm [flags] NAME",
+	Aliases: []string{"remove"},
+	Short:   "Remove project",
+	Long:    `Remove a project from the configuration file. Secrets its token refers to are left as they are.`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, err := runtime.ParseConfig()
+		if err != nil {
+			return NewError(cmd, "Could not read configuration", err)
+		}
+		if conf.Project(args[0]) == nil {
+			return NewError(cmd, "Could not find project", fmt.Errorf("project '%s' does not exist", args[0]))
+		}
+		var projects []runtime.ProjectEntry
+		for _, p := range conf.Projects {
+			if p.Name != args[0] {
+				projects = append(projects, p)
+			}
+		}
+		conf.Projects = projects
+		if conf.DefaultProject == args[0] {
+			conf.DefaultProject = ""
+		}
+		err = runtime.WriteConfig(conf, configFilePath())
+		if err != nil {
+			return NewError(cmd, "Could not write configuration", err)
+		}
+		fmt.Fprintf(os.Stderr, "Removed %s\n", args[0])
+		return nil
+	},
+}
+
+var configUseCmd = &cobra.Command{
+	Use:     "use [flags] NAME",
+	Example: `gscloud config use staging`,
+	Short:   "Set default project",
+	Long:    `Make a project the default project, used unless another one is selected by --project, --account, or GRIDSCALE_ACCOUNT.`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, err := runtime.ParseConfig()
+		if err != nil {
+			return NewError(cmd, "Could not read configuration", err)
+		}
+		if conf.Project(args[0]) == nil {
+			return NewError(cmd, "Could not find project", fmt.Errorf("project '%s' does not exist", args[0]))
+		}
+		conf.DefaultProject = args[0]
+		err = runtime.WriteConfig(conf, configFilePath())
+		if err != nil {
+			return NewError(cmd, "Could not write configuration", err)
+		}
+		return nil
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:     "validate [flags] [NAME...]",
+	Example: `gscloud config validate staging prod`,
+	Short:   "Check projects",
+	Long: `Check that the projects in the configuration file, or the projects given, can access the API. For each project, its token is resolved and the list of locations is requested from the API.
+
+Environment variables overriding user ID, token, or URL apply to all projects checked. The command fails if any project cannot access the API.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		type result struct {
+			Name   string `json:"name"`
+			Status string `json:"status"`
+			Error  string `json:"error,omitempty"`
+		}
+
+		conf, err := runtime.ParseConfig()
+		if err != nil {
+			return NewError(cmd, "Could not read configuration", err)
+		}
+		names := args
+		if len(names) == 0 {
+			for _, p := range conf.Projects {
+				names = append(names, p.Name)
+			}
+		}
+		ctx := context.Background()
+		var rows [][]string
+		results := []result{}
+		var failed []string
+		for _, name := range names {
+			r := result{Name: name, Status: "ok"}
+			err := validateProject(ctx, conf, name)
+			if err != nil {
+				r.Status = "failed"
+				r.Error = err.Error()
+				failed = append(failed, name)
+			}
+			results = append(results, r)
+			rows = append(rows, []string{r.Name, r.Status, r.Error})
+		}
+		err = renderOutput(os.Stdout, []string{"name", "status", "error"}, rows, results)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("%d of %d projects failed: %s", len(failed), len(names), strings.Join(failed, ", "))
+		}
+		return nil
+	},
+}
+
+// validateProject makes a cheap API request for the project called name.
+func validateProject(ctx context.Context, conf *runtime.Config, name string) error {
+	if conf.Project(name) == nil {
+		return fmt.Errorf("project '%s' does not exist", name)
+	}
+	r, err := runtime.NewRuntime(*conf, name, false)
+	if err != nil {
+		return err
+	}
+	_, err = r.Client().GetLocationList(ctx)
+	return err
+}
+
+// defaultProject returns the name of the project used unless one is
+// selected by --project: the one given by --account or GRIDSCALE_ACCOUNT,
+// else the default project of conf, else "default".
+func defaultProject(conf *runtime.Config) string {
+	_, accountEnvPresent := os.LookupEnv("GRIDSCALE_ACCOUNT")
+	if rootCmd.PersistentFlags().Changed("account") || accountEnvPresent || conf.DefaultProject == "" {
+		return rootFlags.account
+	}
+	return conf.DefaultProject
+}
+
+// configFilePath returns the path of the configuration file in use, or the
+// path a new one is created at.
+func configFilePath() string {
+	if rootFlags.configFile != "" {
+		return rootFlags.configFile
+	}
+	if path := viper.ConfigFileUsed(); path != "" {
+		return path
+	}
+	return filepath.Join(runtime.ConfigPath(), "config.yaml")
+}
+
+var configMigrateSecretsCmd = &cobra.Command{
+	Use:     "migrate-secrets [flags]",
+	Example: `gscloud config migrate-secrets --store file`,
+	Short:   "Move API tokens out of the configuration file",
+	Long: `Move the API tokens of all projects out of the configuration file and replace them by a reference. Tokens already given as a reference are left as they are.
+
+The token of a project is either the token itself or one of these references:
+
+    token: keyring:NAME      secret NAME in the keyring of the operating system
+    token: env:VAR           value of the environment variable VAR
+    token: file:PATH         contents of the file at PATH
+    token: cmd:COMMAND       output of COMMAND, run by the shell
+
+References are resolved whenever a command accesses the API of the project.
+
+With **--store keyring**, the default, tokens are stored in the keyring under the name of their project: in the macOS keychain, or in the Secret Service (e.g. GNOME Keyring) via secret-tool(1) on Linux and BSD. With **--store file**, each token is written to a file readable only by the user, in the directory given by **--dir**.
+
+The configuration file is only written once all tokens have been stored and read back. It is readable only by the user.
+
+# EXAMPLES
+
+Keep tokens in the keyring:
+
+    $ gscloud config migrate-secrets
+
+Refer to a token in a password manager instead, by editing the configuration file:
+
+    projects:
+      - name: default
+        userId: 7e6b0bd4-3ac6-4c88-b36c-2d5dbd5d5a3b
+        token: cmd:pass show gridscale/default
+        url: https://api.gridscale.io
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filePath := configFilePath()
+		if !exists(filePath) {
+			return NewError(cmd, "Could not migrate secrets", errors.New("no configuration file found, see gscloud-make-config(1)"))
+		}
+		conf, err := runtime.ParseConfig()
+		if err != nil {
+			return NewError(cmd, "Could not read configuration", err)
+		}
+
+		var store func(name, token string) (string, error)
+		switch configFlags.store {
+		case "keyring":
+			store = runtime.StoreInKeyring
+		case "file":
+			dir := configFlags.dir
+			if dir == "" {
+				dir = filepath.Join(filepath.Dir(filePath), "secrets")
+			}
+			err = os.MkdirAll(dir, 0700)
+			if err != nil {
+				return NewError(cmd, "Could not migrate secrets", err)
+			}
+			store = func(name, token string) (string, error) {
+				return runtime.StoreInFile(filepath.Join(dir, name+".token"), token)
+			}
+		default:
+			return NewError(cmd, "Could not migrate secrets", fmt.Errorf("unknown store %q, expected keyring or file", configFlags.store))
+		}
+
+		moved, err := migrateSecrets(conf, store)
+		if err != nil {
+			return NewError(cmd, "Could not migrate secrets", err)
+		}
+		if len(moved) == 0 {
+			fmt.Println("No tokens to move")
+			return nil
+		}
+		err = runtime.WriteConfig(conf, filePath)
+		if err != nil {
+			return NewError(cmd, "Could not write configuration", err)
+		}
+		for _, i := range moved {
+			fmt.Printf("Moved token of project %s: %s\n", conf.Projects[i].Name, conf.Projects[i].Token)
+		}
+		return nil
+	},
+}
+
+// migrateSecrets replaces the tokens in conf by the references store returns
+// for them, and returns the indexes of the projects changed. Tokens that are
+// empty or a reference already are left as they are.
+func migrateSecrets(conf *runtime.Config, store func(name, token string) (string, error)) ([]int, error) {
+	var moved []int
+	for i, p := range conf.Projects {
+		if p.Token == "" || runtime.IsSecretReference(p.Token) {
+			continue
+		}
+		if p.Name == "" {
+			return nil, errors.New("project without name")
+		}
+		ref, err := store(p.Name, p.Token)
+		if err != nil {
+			return nil, fmt.Errorf("storing token of project %s: %w", p.Name, err)
+		}
+		token, err := runtime.ResolveSecret(ref)
+		if err != nil || token != p.Token {
+			return nil, fmt.Errorf("token of project %s could not be read back from %s", p.Name, ref)
+		}
+		conf.Projects[i].Token = ref
+		moved = append(moved, i)
+	}
+	return moved, nil
+}
+
+// commandWithoutRuntime tells whether the command reads the configuration
+// file, but does not access the API of a project.
+func commandWithoutRuntime(cmdLine []string) bool {
+	foundCommand, _, _ := rootCmd.Find(cmdLine[1:])
+	for c := foundCommand; c != nil; c = c.Parent() {
+		if c == configCmd {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	configAddCmd.Flags().StringVar(&configFlags.userID, "user-id", "", "User ID of the project")
+	configAddCmd.MarkFlagRequired("user-id")
+	configAddCmd.Flags().StringVar(&configFlags.token, "token", "", "API token of the project, or a reference to it")
+	configAddCmd.MarkFlagRequired("token")
+	configAddCmd.Flags().StringVar(&configFlags.url, "url", defaultAPIURL, "URL of the API")
+	configAddCmd.Flags().BoolVar(&configFlags.use, "use", false, "Make the project the default project")
+
+	configSetCmd.Flags().StringVar(&configFlags.name, "name", "", "New name of the project")
+	configSetCmd.Flags().StringVar(&configFlags.userID, "user-id", "", "User ID of the project")
+	configSetCmd.Flags().StringVar(&configFlags.token, "token", "", "API token of the project, or a reference to it")
+	configSetCmd.Flags().StringVar(&configFlags.url, "url", "", "URL of the API")
+
+	configMigrateSecretsCmd.Flags().StringVar(&configFlags.store, "store", "keyring", "Where to store tokens, keyring or file")
+	configMigrateSecretsCmd.Flags().StringVar(&configFlags.dir, "dir", "", "Directory to store tokens in with --store file (default: secrets next to the configuration file)")
+
+	configCmd.AddCommand(configLsCmd, configShowCmd, configAddCmd, configSetCmd, configRmCmd, configUseCmd, configValidateCmd, configMigrateSecretsCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/gridscale/gscloud/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MigrateSecrets(t *testing.T) {
+	conf := &runtime.Config{Projects: []runtime.ProjectEntry{
+		{Name: "staging", Token: "stagingToken"},
+		{Name: "prod", Token: "env:PROD_TOKEN"},
+		{Name: "dev"},
+	}}
+	store := func(name, token string) (string, error) {
+		os.Setenv("GSCLOUD_TEST_"+name, token)
+		return "env:GSCLOUD_TEST_" + name, nil
+	}
+	defer os.Unsetenv("GSCLOUD_TEST_staging")
+
+	moved, err := migrateSecrets(conf, store)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0}, moved)
+	assert.Equal(t, "env:GSCLOUD_TEST_staging", conf.Projects[0].Token)
+	assert.Equal(t, "env:PROD_TOKEN", conf.Projects[1].Token)
+	assert.Equal(t, "", conf.Projects[2].Token)
+
+	conf.Projects[2].Token = "devToken"
+	_, err = migrateSecrets(conf, func(name, token string) (string, error) {
+		return "", errors.New("no keyring")
+	})
+	assert.NotNil(t, err)
+	_, err = migrateSecrets(conf, func(name, token string) (string, error) {
+		return "env:GSCLOUD_TEST_staging", nil
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, "devToken", conf.Projects[2].Token)
+}
+
+func Test_DefaultProject(t *testing.T) {
+	if _, ok := os.LookupEnv("GRIDSCALE_ACCOUNT"); ok {
+		t.Skip("GRIDSCALE_ACCOUNT is set")
+	}
+	conf := &runtime.Config{Projects: []runtime.ProjectEntry{{Name: "default"}, {Name: "prod"}}}
+	assert.Equal(t, "default", defaultProject(conf))
+	conf.DefaultProject = "prod"
+	assert.Equal(t, "prod", defaultProject(conf))
+	assert.Equal(t, "prod", conf.Project("prod").Name)
+	assert.Nil(t, conf.Project("staging"))
+
+	info := newProjectInfo(conf, runtime.ProjectEntry{Name: "prod", UserID: "u1", Token: "secret"})
+	assert.Equal(t, projectInfo{Name: "prod", UserID: "u1", Token: "(plain)", Default: true}, info)
+	assert.Equal(t, "keyring:prod", tokenSource("keyring:prod"))
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gridscale/gscloud/runtime"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+type confirmCmdFlags struct {
+	yes bool
+}
+
+var confirmFlags confirmCmdFlags
+
+var (
+	// confirmMu keeps questions about objects removed at the same time from
+	// getting mixed up.
+	confirmMu sync.Mutex
+	// stdin reads answers from standard input. It is shared, so that
+	// answers read ahead are not lost between questions.
+	stdin = bufio.NewReader(os.Stdin)
+)
+
+var (
+	errNotConfirmed = errors.New("not confirmed")
+	errNoTerminal   = errors.New("confirmation needed, but standard input is not a terminal. Re-run with --yes to remove without confirmation")
+)
+
+// removal describes an object to be removed: its type, name, and ID, and
+// lines about its size and relations.
+type removal struct {
+	kind    string
+	name    string
+	id      string
+	details []string
+}
+
+// addConfirmFlags adds --yes to a command removing objects.
+func addConfirmFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&confirmFlags.yes, "yes", "y", false, "Remove without asking for confirmation")
+}
+
+// confirmRemoval asks for confirmation before an object is removed, as set
+// by confirm_destructive in the configuration file. describe is only called
+// when asking. An error is returned unless the removal is confirmed or no
+// confirmation is needed.
+func confirmRemoval(describe func() (removal, error)) error {
+	if confirmFlags.yes || rootFlags.dryRun {
+		return nil
+	}
+	mode := runtime.ConfirmTTY
+	if rt != nil {
+		mode = rt.ConfirmDestructive()
+	}
+	if mode == runtime.ConfirmNever {
+		return nil
+	}
+	if mode == runtime.ConfirmTTY && !isTerminal(os.Stdin) {
+		return errNoTerminal
+	}
+	confirmMu.Lock()
+	defer confirmMu.Unlock()
+	r, err := describe()
+	if err != nil {
+		return err
+	}
+	return askRemoval(os.Stderr, stdin, !isTerminal(os.Stdin), r)
+}
+
+// askRemoval writes r to w and reads the answer from in. Only "y" or "yes"
+// confirm the removal. With echo, the answer is written to w, as it is not
+// shown otherwise.
+func askRemoval(w io.Writer, in io.Reader, echo bool, r removal) error {
+	fmt.Fprintf(w, "%s %s (%s)\n", r.kind, r.name, r.id)
+	for _, line := range r.details {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+	fmt.Fprintf(w, "Remove %s %s? [y/N] ", r.kind, r.name)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(w)
+		return errNotConfirmed
+	}
+	if echo {
+		fmt.Fprintln(w, strings.TrimSpace(answer))
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return errNotConfirmed
+}
+
+// isTerminal tells whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// relatedNames returns the names of objects related to another one,
+// separated by commas, or "none".
+func relatedNames(list []string) string {
+	if len(list) == 0 {
+		return "none"
+	}
+	return strings.Join(list, ", ")
+}
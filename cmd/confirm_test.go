@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AskRemoval(t *testing.T) {
+	var storage gsclient.Storage
+	storage.Properties.ObjectUUID = "479b7973-376a-4b23-98fc-50e94131a6e3"
+	storage.Properties.Name = "data-1"
+	storage.Properties.Capacity = 50
+	storage.Properties.StorageType = "storage_high"
+	storage.Properties.Relations.Servers = []gsclient.StorageServerRelation{{ObjectName: "web-1"}}
+	r := describeStorage(storage)
+
+	var out bytes.Buffer
+	err := askRemoval(&out, strings.NewReader("y\n"), true, r)
+	assert.Nil(t, err)
+	assert.Equal(t, ""+
+		"storage data-1 (479b7973-376a-4b23-98fc-50e94131a6e3)\n"+
+		"  size: 50 GB, storage_high\n"+
+		"  servers: web-1\n"+
+		"  snapshots: 0\n"+
+		"Remove storage data-1? [y/N] y\n", out.String())
+
+	for _, answer := range []string{"Yes\n", " yes "} {
+		assert.Nil(t, askRemoval(&out, strings.NewReader(answer), false, r))
+	}
+	for _, answer := range []string{"\n", "n\n", "yess\n", ""} {
+		assert.Equal(t, errNotConfirmed, askRemoval(&out, strings.NewReader(answer), false, r))
+	}
+}
+
+func Test_ConfirmRemoval(t *testing.T) {
+	describe := func() (removal, error) {
+		t.Error("unexpected call of describe")
+		return removal{}, nil
+	}
+	// Standard input of tests is not a terminal.
+	assert.Equal(t, errNoTerminal, confirmRemoval(describe))
+
+	confirmFlags.yes = true
+	defer func() { confirmFlags.yes = false }()
+	assert.Nil(t, confirmRemoval(describe))
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gridscale/gscloud/runtime"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// applyProjectDefaults sets the flags of cmd that are not given on the
+// command line to the defaults of the project. Flags of create commands
+// default to the values given for their object type. Defaults for unknown
+// object types or flags are an error, so that typos do not go unnoticed.
+func applyProjectDefaults(cmd *cobra.Command, defaults runtime.ProjectDefaults) error {
+	if defaults.Output != "" && !cmd.Flags().Changed("output") && !cmd.Flags().Changed("json") {
+		renderOpts.Output = defaults.Output
+	}
+
+	kinds := make([]string, 0, len(defaults.Flags))
+	for kind := range defaults.Flags {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		createCmd := findCreateCommand(cmd.Root(), kind)
+		if createCmd == nil {
+			return fmt.Errorf("defaults: unknown object type %q", kind)
+		}
+		vals, ok := defaults.Flags[kind].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("defaults: expected flags and their values for %s", kind)
+		}
+		for key, val := range vals {
+			flag := defaultFlag(createCmd, key)
+			if flag == nil {
+				return fmt.Errorf("defaults: %s create has no flag %q", kind, key)
+			}
+			if createCmd != cmd || flag.Changed {
+				continue
+			}
+			// Value.Set leaves the flag unchanged, as if it had been
+			// given no value on the command line.
+			err := flag.Value.Set(defaultValue(val))
+			if err != nil {
+				return fmt.Errorf("defaults: %s.%s: %w", kind, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// findCreateCommand returns the create command of the object type kind,
+// e.g. "server create" for "server".
+func findCreateCommand(cmd *cobra.Command, kind string) *cobra.Command {
+	for _, c := range cmd.Commands() {
+		if c.Name() == kind {
+			for _, sub := range c.Commands() {
+				if sub.Name() == "create" {
+					return sub
+				}
+			}
+		}
+		if found := findCreateCommand(c, kind); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// defaultFlag returns the flag of cmd called key. The prefix "with-" can be
+// left out, e.g. "template" for --with-template.
+func defaultFlag(cmd *cobra.Command, key string) *pflag.Flag {
+	if flag := cmd.Flags().Lookup(key); flag != nil {
+		return flag
+	}
+	return cmd.Flags().Lookup("with-" + key)
+}
+
+// defaultValue returns val as given on the command line. Lists become values
+// separated by commas.
+func defaultValue(val interface{}) string {
+	if list, ok := val.([]interface{}); ok {
+		var vals []string
+		for _, v := range list {
+			vals = append(vals, fmt.Sprint(v))
+		}
+		return strings.Join(vals, ",")
+	}
+	return fmt.Sprint(val)
+}
+
+// createLabels returns the labels given to objects created: the default
+// labels of the project and the ones given by --label, which replace default
+// labels with the same key.
+func createLabels() []string {
+	var labels []string
+	if rt != nil {
+		labels = rt.Project().Defaults.Labels
+	}
+	for _, label := range labelFlags.create {
+		labels, _ = addLabel(labels, label)
+	}
+	return labels
+}
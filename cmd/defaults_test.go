@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/gridscale/gscloud/runtime"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ApplyProjectDefaults(t *testing.T) {
+	savedOutput := renderOpts.Output
+	defer func() {
+		renderOpts.Output = savedOutput
+		serverCreateCmd.Flags().VisitAll(func(f *pflag.Flag) {
+			f.Value.Set(f.DefValue)
+			f.Changed = false
+		})
+	}()
+
+	defaults := runtime.ProjectDefaults{
+		Output: "yaml",
+		Flags: map[string]interface{}{
+			"server":  map[string]interface{}{"cores": 4, "mem": 8, "template": "Ubuntu"},
+			"storage": map[string]interface{}{"type": "storage_high"},
+		},
+	}
+	serverCreateCmd.Flags().Set("mem", "2")
+	err := applyProjectDefaults(serverCreateCmd, defaults)
+	assert.Nil(t, err)
+	assert.Equal(t, "yaml", renderOpts.Output)
+	assert.Equal(t, 4, serverFlags.cores)
+	assert.Equal(t, 2, serverFlags.memory)
+	assert.Equal(t, "Ubuntu", serverFlags.template)
+	assert.False(t, serverCreateCmd.Flags().Changed("cores"))
+
+	for _, flags := range []map[string]interface{}{
+		{"sever": map[string]interface{}{"cores": 4}},
+		{"server": map[string]interface{}{"colour": "red"}},
+		{"server": "big"},
+	} {
+		err := applyProjectDefaults(serverCreateCmd, runtime.ProjectDefaults{Flags: flags})
+		assert.NotNil(t, err, flags)
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/gridscale/gscloud/runtime"
+	"github.com/spf13/cobra"
+)
+
+// dryRun runs a command with --dry-run. Requests changing objects are
+// recorded by the runtime instead of being sent, and printed after the
+// command has run, in place of its own output. Commands that do not change
+// anything print their output as usual.
+func dryRun(cmd *cobra.Command, args []string, run func(*cobra.Command, []string) error) error {
+	// Objects that are not created do not become ready.
+	rootFlags.wait = false
+
+	var stdout, stderr bytes.Buffer
+	restoreStdout, err := captureOutput(&os.Stdout, &stdout)
+	if err != nil {
+		return err
+	}
+	restoreStderr, err := captureOutput(&os.Stderr, &stderr)
+	if err != nil {
+		restoreStdout()
+		return err
+	}
+	runErr := run(cmd, args)
+	restoreStderr()
+	restoreStdout()
+
+	requests := rt.DryRunRequests()
+	if len(requests) == 0 {
+		io.Copy(os.Stderr, &stderr)
+		io.Copy(os.Stdout, &stdout)
+		return runErr
+	}
+	if path := os.Getenv(fanOutEnv); path != "" {
+		// Output passed on to be merged is replaced as well.
+		err = os.Truncate(path, 0)
+		if err != nil {
+			return err
+		}
+	}
+	err = writeRequests(os.Stdout, requests)
+	if err != nil {
+		return NewError(cmd, "Could not render output", err)
+	}
+	return runErr
+}
+
+// captureOutput points *f to a pipe whose contents are copied to buf, until
+// the function returned is called.
+func captureOutput(f **os.File, buf *bytes.Buffer) (func(), error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	saved := *f
+	*f = w
+	done := make(chan struct{})
+	go func() {
+		io.Copy(buf, r)
+		r.Close()
+		close(done)
+	}()
+	return func() {
+		w.Close()
+		<-done
+		*f = saved
+	}, nil
+}
+
+// writeRequests writes the requests of a dry run to w as a table of methods,
+// paths, and JSON bodies, or as they are in structured output.
+func writeRequests(w io.Writer, requests []runtime.Request) error {
+	var rows [][]string
+	for _, r := range requests {
+		body := ""
+		if r.Body != nil {
+			data, err := json.Marshal(r.Body)
+			if err != nil {
+				return err
+			}
+			body = string(data)
+		}
+		rows = append(rows, []string{r.Method, r.Path, body})
+	}
+	return renderOutput(w, []string{"method", "path", "body"}, rows, requests)
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gridscale/gscloud/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WriteRequests(t *testing.T) {
+	requests := []runtime.Request{
+		{Method: "PATCH", Path: "/objects/storages/479b7973-376a-4b23-98fc-50e94131a6e3", Body: map[string]int{"capacity": 20}},
+		{Method: "DELETE", Path: "/objects/ips/8e0c2ba1-4e4c-43d8-a8ba-3f5c2a7b8d60"},
+	}
+
+	var buf bytes.Buffer
+	err := writeRequests(&buf, requests)
+	assert.Nil(t, err)
+	assert.Equal(t, ""+
+		"METHOD  PATH                                                    BODY             \n"+
+		"PATCH   /objects/storages/479b7973-376a-4b23-98fc-50e94131a6e3  {\"capacity\":20}  \n"+
+		"DELETE  /objects/ips/8e0c2ba1-4e4c-43d8-a8ba-3f5c2a7b8d60                        \n",
+		buf.String())
+
+	savedJSON := rootFlags.json
+	defer func() { rootFlags.json = savedJSON }()
+	rootFlags.json = true
+	buf.Reset()
+	err = writeRequests(&buf, requests)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `[
+		{"method": "PATCH", "path": "/objects/storages/479b7973-376a-4b23-98fc-50e94131a6e3", "body": {"capacity": 20}},
+		{"method": "DELETE", "path": "/objects/ips/8e0c2ba1-4e4c-43d8-a8ba-3f5c2a7b8d60"}
+	]`, buf.String())
+}
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+type exportCmdFlags struct {
+	types []string
+}
+
+var (
+	exportFlags exportCmdFlags
+)
+
+var exportCmd = &cobra.Command{
+	Use:     "export [flags]",
+	Example: `gscloud export --types server,storage > stack.yaml`,
+	Short:   "Export objects as a manifest",
+	Long: `Write the objects in a project as a manifest, as read by gscloud-apply(1). Objects refer to each other by name: servers list their storages, networks, and IP addresses. IP addresses without a name are named by their address.
+
+Objects of all types are exported unless **--types** is given, a list of ssh-key, network, ip, storage, and server. Public networks are never exported, but servers still refer to them.
+
+Objects and labels are sorted by name, so that exporting the same objects again gives the same manifest. The storages of a server start with its boot device; its networks are in the order they are connected. Storages refer to the template they were last initialized from. Passwords, hostnames, and SSH keys given to templates are not known and hence not exported.
+
+Objects of the same type must have distinct names to be exported.
+
+# EXAMPLES
+
+Export all objects of a project:
+
+	$ gscloud export > stack.yaml
+
+Recreate the servers of one project in another one:
+
+	$ gscloud --project staging export --types server,storage,network,ip > stack.yaml
+	$ gscloud --project prod apply -f stack.yaml
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		types, err := exportTypes(exportFlags.types)
+		if err != nil {
+			return NewError(cmd, "Could not export", err)
+		}
+		ctx := context.Background()
+		live, err := getLiveObjects(ctx)
+		if err != nil {
+			return NewError(cmd, "Could not get objects", err)
+		}
+		templates := map[string]string{}
+		if types["storage"] {
+			list, err := rt.TemplateOperator().GetTemplateList(ctx)
+			if err != nil {
+				return NewError(cmd, "Could not get templates", err)
+			}
+			for _, t := range list {
+				templates[t.Properties.ObjectUUID] = t.Properties.Name
+			}
+		}
+		m, err := exportManifest(live, templates, types)
+		if err != nil {
+			return NewError(cmd, "Could not export", err)
+		}
+		if structuredOutput() {
+			err = renderOutput(os.Stdout, nil, nil, m)
+			if err != nil {
+				return NewError(cmd, "Could not render output", err)
+			}
+			return nil
+		}
+		out, err := yaml.Marshal(m)
+		if err != nil {
+			return NewError(cmd, "Could not export", err)
+		}
+		fmt.Print(string(out))
+		return nil
+	},
+}
+
+// exportTypes returns the set of object types given by --types, all types
+// if none is given. Plurals are accepted as well.
+func exportTypes(vals []string) (map[string]bool, error) {
+	types := map[string]bool{}
+	if len(vals) == 0 {
+		vals = manifestKinds
+	}
+	for _, v := range vals {
+		kind := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(v)), "s")
+		known := false
+		for _, k := range manifestKinds {
+			if k == kind {
+				known = true
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown type %q, expected one of: %s", v, strings.Join(manifestKinds, ", "))
+		}
+		types[kind] = true
+	}
+	return types, nil
+}
+
+// exportManifest returns a manifest of the objects of the given types in
+// live. templates maps template IDs to names.
+func exportManifest(live liveObjects, templates map[string]string, types map[string]bool) (manifest, error) {
+	var m manifest
+	for _, kind := range manifestKinds {
+		if !types[kind] {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, o := range live.byKind(kind) {
+			if kind == "network" && isPublicNetwork(live, o.ID) {
+				continue
+			}
+			if seen[o.Name] {
+				return manifest{}, fmt.Errorf("more than one %s is named %q, rename all but one to export them", kind, o.Name)
+			}
+			seen[o.Name] = true
+		}
+	}
+
+	if types["ssh-key"] {
+		for _, k := range live.SSHKeys {
+			m.SSHKeys = append(m.SSHKeys, manifestSSHKey{
+				Name:   k.Properties.Name,
+				Key:    strings.TrimSpace(k.Properties.Sshkey),
+				Labels: exportLabels(k.Properties.Labels),
+			})
+		}
+		sort.Slice(m.SSHKeys, func(i, j int) bool { return m.SSHKeys[i].Name < m.SSHKeys[j].Name })
+	}
+
+	if types["network"] {
+		for _, n := range live.Networks {
+			if n.Properties.PublicNet {
+				continue
+			}
+			m.Networks = append(m.Networks, manifestNetwork{
+				Name:       n.Properties.Name,
+				L2Security: n.Properties.L2Security,
+				Labels:     exportLabels(n.Properties.Labels),
+			})
+		}
+		sort.Slice(m.Networks, func(i, j int) bool { return m.Networks[i].Name < m.Networks[j].Name })
+	}
+
+	if types["ip"] {
+		for _, ip := range live.IPs {
+			m.IPs = append(m.IPs, manifestIP{
+				Name:       ipName(ip),
+				Family:     ip.Properties.Family,
+				Failover:   ip.Properties.Failover,
+				ReverseDNS: ip.Properties.ReverseDNS,
+				Labels:     exportLabels(ip.Properties.Labels),
+			})
+		}
+		sort.Slice(m.IPs, func(i, j int) bool { return m.IPs[i].Name < m.IPs[j].Name })
+	}
+
+	if types["storage"] {
+		for _, s := range live.Storages {
+			template := s.Properties.LastUsedTemplate
+			if name, ok := templates[template]; ok {
+				template = name
+			}
+			m.Storages = append(m.Storages, manifestStorage{
+				Name:     s.Properties.Name,
+				Capacity: s.Properties.Capacity,
+				Type:     s.Properties.StorageType,
+				Template: template,
+				Labels:   exportLabels(s.Properties.Labels),
+			})
+		}
+		sort.Slice(m.Storages, func(i, j int) bool { return m.Storages[i].Name < m.Storages[j].Name })
+	}
+
+	if types["server"] {
+		// Servers refer to objects by ID where their name is not unique.
+		ref := func(kind, id, name string) string {
+			count := 0
+			for _, o := range live.byKind(kind) {
+				if o.Name == name {
+					count++
+				}
+			}
+			if count > 1 {
+				return id
+			}
+			return name
+		}
+		for _, s := range live.Servers {
+			power := s.Properties.Power
+			server := manifestServer{
+				Name:   s.Properties.Name,
+				Cores:  s.Properties.Cores,
+				Memory: s.Properties.Memory,
+				Power:  &power,
+				Labels: exportLabels(s.Properties.Labels),
+			}
+			if profile := s.Properties.HardwareProfile; profile != "" && profile != string(gsclient.DefaultServerHardware) {
+				if _, err := toHardwareProfile(profile); err == nil {
+					server.Profile = profile
+				}
+			}
+
+			storages := append([]gsclient.ServerStorageRelationProperties{}, s.Properties.Relations.Storages...)
+			sort.SliceStable(storages, func(i, j int) bool {
+				if storages[i].BootDevice != storages[j].BootDevice {
+					return storages[i].BootDevice
+				}
+				return storages[i].ObjectName < storages[j].ObjectName
+			})
+			for _, r := range storages {
+				server.Storages = append(server.Storages, ref("storage", r.ObjectUUID, r.ObjectName))
+			}
+
+			networks := append([]gsclient.ServerNetworkRelationProperties{}, s.Properties.Relations.Networks...)
+			sort.SliceStable(networks, func(i, j int) bool {
+				if networks[i].Ordering != networks[j].Ordering {
+					return networks[i].Ordering < networks[j].Ordering
+				}
+				return networks[i].ObjectName < networks[j].ObjectName
+			})
+			for _, r := range networks {
+				server.Networks = append(server.Networks, ref("network", r.ObjectUUID, r.ObjectName))
+			}
+
+			for _, r := range s.Properties.Relations.PublicIPs {
+				name := r.IP
+				for _, ip := range live.IPs {
+					if ip.Properties.ObjectUUID == r.ObjectUUID {
+						name = ipName(ip)
+					}
+				}
+				server.IPs = append(server.IPs, ref("ip", r.ObjectUUID, name))
+			}
+			sort.Strings(server.IPs)
+
+			m.Servers = append(m.Servers, server)
+		}
+		sort.Slice(m.Servers, func(i, j int) bool { return m.Servers[i].Name < m.Servers[j].Name })
+	}
+	return m, nil
+}
+
+func isPublicNetwork(live liveObjects, id string) bool {
+	for _, n := range live.Networks {
+		if n.Properties.ObjectUUID == id {
+			return n.Properties.PublicNet
+		}
+	}
+	return false
+}
+
+// exportLabels returns labels sorted, without the managed-by label, which
+// apply adds anyway.
+func exportLabels(labels []string) []string {
+	var exported []string
+	for _, l := range labels {
+		if !strings.HasPrefix(l, managedByLabel+"=") {
+			exported = append(exported, l)
+		}
+	}
+	sort.Strings(exported)
+	return exported
+}
+
+func init() {
+	exportCmd.Flags().StringSliceVar(&exportFlags.types, "types", nil, "Types of objects to export, separated by commas")
+
+	rootCmd.AddCommand(exportCmd)
+}
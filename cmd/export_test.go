@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+var testExportLive = liveObjects{
+	SSHKeys: []gsclient.Sshkey{
+		{Properties: gsclient.SshkeyProperties{ObjectUUID: "k1", Name: "admin", Sshkey: "ssh-ed25519 AAAA admin@example.com\n", Labels: []string{"gscloud/managed-by=gscloud"}}},
+	},
+	Networks: []gsclient.Network{
+		{Properties: gsclient.NetworkProperties{ObjectUUID: "n2", Name: "Public Network", PublicNet: true}},
+		{Properties: gsclient.NetworkProperties{ObjectUUID: "n1", Name: "backend", Labels: []string{"gscloud/managed-by=gscloud"}}},
+	},
+	IPs: []gsclient.IP{
+		{Properties: gsclient.IPProperties{ObjectUUID: "i1", IP: "192.0.2.1", Family: 4, ReverseDNS: "www.example.com", Labels: []string{"gscloud/managed-by=gscloud"}}},
+	},
+	Storages: []gsclient.Storage{
+		{Properties: gsclient.StorageProperties{ObjectUUID: "s2", Name: "web-data", Capacity: 50, StorageType: "storage", Labels: []string{"gscloud/managed-by=gscloud"}}},
+		{Properties: gsclient.StorageProperties{ObjectUUID: "s1", Name: "web-root", Capacity: 10, StorageType: "storage_high", LastUsedTemplate: "t1", Labels: []string{"gscloud/managed-by=gscloud", "role=root"}}},
+	},
+	Servers: []gsclient.Server{
+		{Properties: gsclient.ServerProperties{
+			ObjectUUID: "v1", Name: "web", Cores: 2, Memory: 4, Power: true, HardwareProfile: "default",
+			Labels: []string{"team=web", "gscloud/managed-by=gscloud", "env=prod"},
+			Relations: gsclient.ServerRelations{
+				Storages:  []gsclient.ServerStorageRelationProperties{{ObjectUUID: "s2", ObjectName: "web-data"}, {ObjectUUID: "s1", ObjectName: "web-root", BootDevice: true}},
+				Networks:  []gsclient.ServerNetworkRelationProperties{{ObjectUUID: "n1", ObjectName: "backend", Ordering: 1}, {ObjectUUID: "n2", ObjectName: "Public Network"}},
+				PublicIPs: []gsclient.ServerIPRelationProperties{{ObjectUUID: "i1", IP: "192.0.2.1", Family: 4}},
+			},
+		}},
+	},
+}
+
+const testExportManifest = `ssh-keys:
+- name: admin
+  key: ssh-ed25519 AAAA admin@example.com
+networks:
+- name: backend
+ips:
+- name: 192.0.2.1
+  family: 4
+  reverse-dns: www.example.com
+storages:
+- name: web-data
+  capacity: 50
+  type: storage
+- name: web-root
+  capacity: 10
+  type: storage_high
+  template: Ubuntu
+  labels:
+  - role=root
+servers:
+- name: web
+  cores: 2
+  memory: 4
+  power: true
+  storages:
+  - web-root
+  - web-data
+  networks:
+  - Public Network
+  - backend
+  ips:
+  - 192.0.2.1
+  labels:
+  - env=prod
+  - team=web
+`
+
+func Test_ExportManifest(t *testing.T) {
+	types, _ := exportTypes(nil)
+	m, err := exportManifest(testExportLive, map[string]string{"t1": "Ubuntu"}, types)
+	assert.Nil(t, err)
+	out, err := yaml.Marshal(m)
+	assert.Nil(t, err)
+	assert.Equal(t, testExportManifest, string(out))
+
+	// Applying the export to the same objects changes nothing.
+	parsed, err := parseManifest(out)
+	assert.Nil(t, err)
+	plan, err := makePlan(parsed, testExportLive, true)
+	assert.Nil(t, err)
+	assert.Empty(t, plan)
+}
+
+func Test_ExportTypes(t *testing.T) {
+	types, err := exportTypes([]string{"servers", "IP"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{"server": true, "ip": true}, types)
+
+	m, err := exportManifest(testExportLive, nil, types)
+	assert.Nil(t, err)
+	assert.Empty(t, m.Storages)
+	assert.Len(t, m.IPs, 1)
+	assert.Equal(t, []string{"web-root", "web-data"}, m.Servers[0].Storages)
+
+	_, err = exportTypes([]string{"firewall"})
+	assert.NotNil(t, err)
+}
+
+func Test_ExportDuplicateNames(t *testing.T) {
+	live := liveObjects{
+		Storages: []gsclient.Storage{
+			{Properties: gsclient.StorageProperties{ObjectUUID: "s1", Name: "data"}},
+			{Properties: gsclient.StorageProperties{ObjectUUID: "s2", Name: "data"}},
+		},
+		Servers: []gsclient.Server{
+			{Properties: gsclient.ServerProperties{Name: "web", Relations: gsclient.ServerRelations{
+				Storages: []gsclient.ServerStorageRelationProperties{{ObjectUUID: "s2", ObjectName: "data"}},
+			}}},
+		},
+	}
+	_, err := exportManifest(live, nil, map[string]bool{"storage": true})
+	assert.NotNil(t, err)
+
+	m, err := exportManifest(live, nil, map[string]bool{"server": true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"s2"}, m.Servers[0].Storages)
+}
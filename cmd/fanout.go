@@ -0,0 +1,422 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gridscale/gscloud/runtime"
+	"github.com/spf13/cobra"
+)
+
+// fanOutEnv names the environment variable telling gscloud that it runs for
+// one of several projects. Its value is the path of a file that output is
+// written to, to be merged with the output for the other projects.
+const fanOutEnv = "GSCLOUD_FANOUT_OUTPUT"
+
+// maxFanOutProcesses limits how many projects a command runs for at the same
+// time.
+const maxFanOutProcesses = 8
+
+// fanOutProjects holds the projects a command runs for if more than one is
+// selected by --project or --all-projects.
+var fanOutProjects []string
+
+// fanOutOutput is what renderOutput writes to the file named by fanOutEnv.
+type fanOutOutput struct {
+	Heading []string        `json:"heading,omitempty"`
+	Rows    [][]string      `json:"rows,omitempty"`
+	Objects json.RawMessage `json:"objects,omitempty"`
+}
+
+// fanOutResult is the output of a command run for one project.
+type fanOutResult struct {
+	project string
+	outputs []fanOutOutput
+	stdout  []byte
+	stderr  []byte
+	err     error
+}
+
+// selectProjects returns the projects selected by a list of projects
+// separated by commas, or all projects in conf. It returns nil if a single
+// project is given.
+func selectProjects(conf runtime.Config, project string, all bool) ([]string, error) {
+	var names []string
+	for _, p := range conf.Projects {
+		names = append(names, p.Name)
+	}
+	if all {
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no projects configured")
+		}
+		return names, nil
+	}
+	if !strings.Contains(project, ",") {
+		return nil, nil
+	}
+	var selected []string
+	seen := map[string]bool{}
+	for _, p := range strings.Split(project, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" || seen[p] {
+			continue
+		}
+		found := false
+		for _, name := range names {
+			if name == p {
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("account '%s' does not exist", p)
+		}
+		seen[p] = true
+		selected = append(selected, p)
+	}
+	return selected, nil
+}
+
+// projectArgs returns the command line args with the project selection
+// replaced by the given project.
+func projectArgs(args []string, project string) []string {
+	selected := []string{"--project", project}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return append(selected, args[i:]...)
+		case arg == "--project" || arg == "--account":
+			i++
+		case strings.HasPrefix(arg, "--project=") || strings.HasPrefix(arg, "--account="):
+		case arg == "--all-projects" || strings.HasPrefix(arg, "--all-projects="):
+		default:
+			selected = append(selected, arg)
+		}
+	}
+	return selected
+}
+
+// fanOut runs the command line once for each project, for up to
+// maxFanOutProcesses projects at the same time, and merges the results.
+// Tables get a leading project column, objects a project key, and are
+// ordered again by --sort-by and --reverse. Other output is printed line by
+// line, each line prefixed by the project, except IDs printed with --quiet.
+func fanOut(cmd *cobra.Command, projects []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return NewError(cmd, "Could not run command for projects", err)
+	}
+	results := make([]fanOutResult, len(projects))
+	slots := make(chan struct{}, maxFanOutProcesses)
+	var wg sync.WaitGroup
+	for i, project := range projects {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, project string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			results[i] = runForProject(executable, project)
+		}(i, project)
+	}
+	wg.Wait()
+
+	var failed []string
+	for _, r := range results {
+		writePrefixed(os.Stdout, r.project, r.stdout, !rootFlags.quiet)
+		writePrefixed(os.Stderr, r.project, r.stderr, true)
+		if r.err != nil {
+			failed = append(failed, r.project)
+		}
+	}
+	heading, rows, objs, err := mergeFanOut(results)
+	if err != nil {
+		return NewError(cmd, "Could not merge output of projects", err)
+	}
+	rows, objs, err = orderFanOut(heading, rows, objs)
+	if err != nil {
+		return NewError(cmd, "Could not merge output of projects", err)
+	}
+	if heading != nil || objs != nil {
+		err = renderOutput(os.Stdout, heading, rows, objs)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("command failed for %d of %d projects: %s", len(failed), len(projects), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// runForProject runs gscloud for project and collects its output.
+func runForProject(executable, project string) fanOutResult {
+	r := fanOutResult{project: project}
+	file, err := ioutil.TempFile("", "gscloud-")
+	if err != nil {
+		r.err = err
+		r.stderr = []byte(err.Error() + "\n")
+		return r
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	var stdout, stderr bytes.Buffer
+	c := exec.Command(executable, projectArgs(os.Args[1:], project)...)
+	c.Env = append(os.Environ(), fanOutEnv+"="+file.Name())
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	r.err = c.Run()
+	r.stdout = stdout.Bytes()
+	r.stderr = stderr.Bytes()
+
+	data, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		r.err = err
+		return r
+	}
+	r.outputs, err = parseFanOutOutput(data)
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	return r
+}
+
+// writeFanOutOutput appends output to the file named by fanOutEnv, one JSON
+// document per line.
+func writeFanOutOutput(path string, heading []string, rows [][]string, objs interface{}) error {
+	out := fanOutOutput{Heading: heading, Rows: rows}
+	if objs != nil {
+		data, err := json.Marshal(objs)
+		if err != nil {
+			return err
+		}
+		out.Objects = data
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func parseFanOutOutput(data []byte) ([]fanOutOutput, error) {
+	var outputs []fanOutOutput
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var out fanOutOutput
+		err := dec.Decode(&out)
+		if err == io.EOF {
+			return outputs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, out)
+	}
+}
+
+// mergeFanOut merges the tables and objects printed for each project. The
+// heading is that of the first table. Objects given as a list are merged
+// into one list, single objects become items of that list.
+func mergeFanOut(results []fanOutResult) ([]string, [][]string, []interface{}, error) {
+	var heading []string
+	var rows [][]string
+	var objs []interface{}
+	for _, r := range results {
+		for _, out := range r.outputs {
+			if out.Heading != nil && heading == nil {
+				heading = append([]string{"project"}, out.Heading...)
+			}
+			for _, row := range out.Rows {
+				rows = append(rows, append([]string{r.project}, row...))
+			}
+			if len(out.Objects) == 0 || string(out.Objects) == "null" {
+				continue
+			}
+			var val interface{}
+			if err := json.Unmarshal(out.Objects, &val); err != nil {
+				return nil, nil, nil, err
+			}
+			items, ok := val.([]interface{})
+			if !ok {
+				items = []interface{}{val}
+			}
+			for _, item := range items {
+				obj, ok := item.(map[string]interface{})
+				if !ok {
+					obj = map[string]interface{}{"value": item}
+				}
+				obj["project"] = r.project
+				objs = append(objs, obj)
+			}
+		}
+	}
+	if objs == nil && heading != nil {
+		objs = []interface{}{}
+	}
+	return heading, rows, objs, nil
+}
+
+// orderFanOut orders the merged rows by --sort-by and --reverse, as each
+// project's rows are ordered by listOrder. Objects are kept in the same order
+// as the rows they are shown in. A column that is not in heading is looked
+// up in the properties of the objects.
+func orderFanOut(heading []string, rows [][]string, objs []interface{}) ([][]string, []interface{}, error) {
+	if listFlags.sortBy == "" && !listFlags.reverse {
+		return rows, objs, nil
+	}
+	aligned := len(objs) == len(rows)
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	if listFlags.sortBy != "" {
+		values, err := fanOutValues(heading, rows, objs, listFlags.sortBy)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Each project's rows are sorted and reversed already, so a stable
+		// sort in the final direction keeps their order for equal values.
+		sort.SliceStable(order, func(i, j int) bool {
+			if listFlags.reverse {
+				return lessValue(values[order[j]], values[order[i]])
+			}
+			return lessValue(values[order[i]], values[order[j]])
+		})
+	} else {
+		// Each project's rows are reversed already, so only the order of
+		// the projects is left to reverse.
+		projects := map[string]int{}
+		for _, row := range rows {
+			if _, ok := projects[row[0]]; !ok {
+				projects[row[0]] = len(projects)
+			}
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return projects[rows[order[i]][0]] > projects[rows[order[j]][0]]
+		})
+	}
+
+	orderedRows := make([][]string, 0, len(rows))
+	for _, i := range order {
+		orderedRows = append(orderedRows, rows[i])
+	}
+	if !aligned {
+		return orderedRows, objs, nil
+	}
+	orderedObjs := make([]interface{}, 0, len(objs))
+	for _, i := range order {
+		orderedObjs = append(orderedObjs, objs[i])
+	}
+	return orderedRows, orderedObjs, nil
+}
+
+// fanOutValues returns the values of the column called name of the merged
+// rows. Columns not in heading are taken from the properties of objs, the
+// object in each item next to its project key.
+func fanOutValues(heading []string, rows [][]string, objs []interface{}, name string) ([]string, error) {
+	values := make([]string, len(rows))
+	if column, ok := columnIndex(heading, name); ok {
+		for i, row := range rows {
+			values[i] = row[column]
+		}
+		return values, nil
+	}
+	if len(objs) != len(rows) {
+		return nil, fmt.Errorf("no such column %q", name)
+	}
+	key := columnKey(name)
+	for i, obj := range objs {
+		found := false
+		for _, candidate := range []string{key, key + "-name", key + "-uuid"} {
+			for k, v := range fanOutProperties(obj) {
+				if columnKey(k) == candidate {
+					values[i] = jsonValueString(v)
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no such column %q", name)
+		}
+	}
+	return values, nil
+}
+
+// fanOutProperties returns the properties of a merged object, such as the
+// value of "server" in {"project": ..., "server": {...}}.
+func fanOutProperties(obj interface{}) map[string]interface{} {
+	m, ok := obj.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for k, v := range m {
+		if props, ok := v.(map[string]interface{}); ok && k != "project" {
+			return props
+		}
+	}
+	return nil
+}
+
+// jsonValueString formats a decoded JSON value like propertyString formats
+// the property it was encoded from.
+func jsonValueString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case bool:
+		return strconv.FormatBool(t)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case []interface{}:
+		var items []string
+		for _, item := range t {
+			s, ok := item.(string)
+			if !ok {
+				data, _ := json.Marshal(t)
+				return string(data)
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ",")
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// writePrefixed writes each line of data to w, prefixed by the project if
+// prefix is set.
+func writePrefixed(w io.Writer, project string, data []byte, prefix bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if prefix {
+			fmt.Fprintf(w, "%s: %s\n", project, scanner.Text())
+		} else {
+			fmt.Fprintln(w, scanner.Text())
+		}
+	}
+}
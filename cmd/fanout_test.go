@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gridscale/gscloud/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SelectProjects(t *testing.T) {
+	conf := runtime.Config{Projects: []runtime.ProjectEntry{{Name: "staging"}, {Name: "prod"}, {Name: "dev"}}}
+
+	projects, err := selectProjects(conf, "prod", false)
+	assert.Nil(t, err)
+	assert.Nil(t, projects)
+
+	projects, err = selectProjects(conf, "prod, dev,prod", false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"prod", "dev"}, projects)
+
+	projects, err = selectProjects(conf, "", true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"staging", "prod", "dev"}, projects)
+
+	_, err = selectProjects(conf, "prod,test", false)
+	assert.NotNil(t, err)
+	_, err = selectProjects(runtime.Config{}, "", true)
+	assert.NotNil(t, err)
+}
+
+func Test_ProjectArgs(t *testing.T) {
+	assert.Equal(t,
+		[]string{"--project", "prod", "server", "ls", "--json"},
+		projectArgs([]string{"--project", "staging,prod", "server", "ls", "--json"}, "prod"))
+	assert.Equal(t,
+		[]string{"--project", "prod", "server", "rm", "--", "--all-projects"},
+		projectArgs([]string{"--all-projects", "--account=x", "server", "rm", "--", "--all-projects"}, "prod"))
+}
+
+func Test_MergeFanOut(t *testing.T) {
+	results := []fanOutResult{
+		{project: "staging", outputs: []fanOutOutput{{
+			Heading: []string{"id", "name"},
+			Rows:    [][]string{{"1", "web"}},
+			Objects: json.RawMessage(`[{"server":{"name":"web"}}]`),
+		}}},
+		{project: "prod", outputs: []fanOutOutput{{
+			Heading: []string{"id", "name"},
+			Objects: json.RawMessage(`[]`),
+		}}},
+		{project: "dev", outputs: []fanOutOutput{{
+			Objects: json.RawMessage(`{"server":"2"}`),
+		}}},
+	}
+	heading, rows, objs, err := mergeFanOut(results)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"project", "id", "name"}, heading)
+	assert.Equal(t, [][]string{{"staging", "1", "web"}}, rows)
+	out, _ := json.Marshal(objs)
+	assert.JSONEq(t, `[
+		{"project": "staging", "server": {"name": "web"}},
+		{"project": "dev", "server": "2"}
+	]`, string(out))
+
+	heading, rows, objs, err = mergeFanOut(results[1:2])
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"project", "id", "name"}, heading)
+	assert.Empty(t, rows)
+	assert.Equal(t, []interface{}{}, objs)
+}
+
+func Test_OrderFanOut(t *testing.T) {
+	heading := []string{"project", "id", "name"}
+	rows := [][]string{
+		{"staging", "1", "web-2"},
+		{"staging", "2", "db-1"},
+		{"prod", "3", "web-1"},
+	}
+	objs := []interface{}{
+		map[string]interface{}{"project": "staging", "server": map[string]interface{}{"object_uuid": "1", "location_uuid": "loc-2"}},
+		map[string]interface{}{"project": "staging", "server": map[string]interface{}{"object_uuid": "2", "location_uuid": "loc-3"}},
+		map[string]interface{}{"project": "prod", "server": map[string]interface{}{"object_uuid": "3", "location_uuid": "loc-1"}},
+	}
+	type testCase struct {
+		flags    listCmdFlags
+		expected []string
+	}
+	testCases := []testCase{
+		{flags: listCmdFlags{}, expected: []string{"1", "2", "3"}},
+		{flags: listCmdFlags{sortBy: "name"}, expected: []string{"2", "3", "1"}},
+		{flags: listCmdFlags{sortBy: "name", reverse: true}, expected: []string{"1", "3", "2"}},
+		{flags: listCmdFlags{sortBy: "location"}, expected: []string{"3", "1", "2"}},
+		{flags: listCmdFlags{reverse: true}, expected: []string{"3", "1", "2"}},
+	}
+	defer func() { listFlags = listCmdFlags{} }()
+	for _, tc := range testCases {
+		listFlags = tc.flags
+		orderedRows, orderedObjs, err := orderFanOut(heading, rows, objs)
+		assert.Nil(t, err)
+		var ids []string
+		for i, row := range orderedRows {
+			ids = append(ids, row[1])
+			assert.Equal(t, row[1], fanOutProperties(orderedObjs[i])["object_uuid"])
+		}
+		assert.Equal(t, tc.expected, ids)
+	}
+
+	listFlags = listCmdFlags{sortBy: "colour"}
+	_, _, err := orderFanOut(heading, rows, objs)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+type firewallCmdFlags struct {
+	name      string
+	rulesFile string
+}
+
+var (
+	firewallFlags firewallCmdFlags
+)
+
+var firewallCmd = &cobra.Command{
+	Use:   "firewall",
+	Short: "Operations on firewalls",
+	Long:  `List, create, or remove firewalls.`,
+}
+
+var firewallWideColumns = []string{"id", "name", "rules", "networks", "private", "changed", "status", "description", "labels"}
+
+var firewallLsCmd = &cobra.Command{
+	Use:     "ls [flags]",
+	Aliases: []string{"list"},
+	Short:   "List firewalls",
+	Long:    `List firewall objects.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		firewallOp := rt.FirewallOperator()
+		ctx := context.Background()
+		out := new(bytes.Buffer)
+		firewalls, err := firewallOp.GetFirewallList(ctx)
+		if err != nil {
+			return NewError(cmd, "Could not get list of firewalls", err)
+		}
+		var rows [][]string
+		var labels [][]string
+		heading := []string{"id", "name", "rules", "networks", "private", "changed", "status"}
+		for _, fw := range firewalls {
+			private := "no"
+			if fw.Properties.Private {
+				private = "yes"
+			}
+			rules := fw.Properties.Rules
+			count := len(rules.RulesV4In) + len(rules.RulesV4Out) + len(rules.RulesV6In) + len(rules.RulesV6Out)
+			fill := [][]string{
+				{
+					fw.Properties.ObjectUUID,
+					fw.Properties.Name,
+					strconv.Itoa(count),
+					strconv.Itoa(len(fw.Properties.Relations.Networks)),
+					private,
+					fw.Properties.ChangeTime.Local().Format(time.RFC3339),
+					fw.Properties.Status,
+				},
+			}
+			rows = append(rows, fill...)
+			labels = append(labels, fw.Properties.Labels)
+		}
+		order, err := listOrder(heading, rows, labels, firewalls)
+		if err != nil {
+			return NewError(cmd, "Could not list firewalls", err)
+		}
+		rows = selectRows(rows, order)
+		selected := make([]gsclient.Firewall, 0, len(order))
+		for _, i := range order {
+			selected = append(selected, firewalls[i])
+		}
+		if quietOutput() {
+			for _, info := range rows {
+				fmt.Println(info[0])
+			}
+			return nil
+		}
+		heading, rows, err = listColumns(heading, rows, selected, firewallWideColumns)
+		if err != nil {
+			return NewError(cmd, "Could not list firewalls", err)
+		}
+		err = renderOutput(out, heading, rows, selected)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
+		}
+		fmt.Print(out)
+		return nil
+	},
+}
+
+var firewallCreateCmd = &cobra.Command{
+	Use:     "create [flags]",
+	Example: `gscloud firewall create --name web --rules-file web-rules.yaml`,
+	Short:   "Create firewall",
+	Long: `Create a new firewall.
+
+Rules are read from a YAML or JSON file given with **--rules-file**. The file contains up to four lists of rules: rules-v4-in, rules-v4-out, rules-v6-in, and rules-v6-out. The same format is produced by gscloud-firewall-export(1).
+
+# EXAMPLES
+
+A rules file allowing inbound SSH and HTTPS over IPv4:
+
+	rules-v4-in:
+	- order: 0
+	  protocol: tcp
+	  dst_port: "22"
+	  action: accept
+	  comment: SSH
+	- order: 1
+	  protocol: tcp
+	  dst_port: "443"
+	  action: accept
+
+Create a firewall from the file above:
+
+	$ gscloud firewall create --name web --rules-file web-rules.yaml
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		type output struct {
+			Firewall string `json:"firewall"`
+		}
+
+		var rules gsclient.FirewallRules
+		var err error
+		if firewallFlags.rulesFile != "" {
+			rules, err = readFirewallRules(firewallFlags.rulesFile)
+			if err != nil {
+				return NewError(cmd, "Could not read rules", err)
+			}
+		}
+		firewallOp := rt.FirewallOperator()
+		ctx := context.Background()
+		fw, err := firewallOp.CreateFirewall(ctx, gsclient.FirewallCreateRequest{
+			Name:   firewallFlags.name,
+			Rules:  rules,
+			Labels: createLabels(),
+		})
+		if err != nil {
+			return NewError(cmd, "Creating firewall failed", err)
+		}
+		if !structuredOutput() {
+			fmt.Println("Firewall created:", fw.ObjectUUID)
+		} else {
+			err = renderOutput(os.Stdout, nil, nil, output{Firewall: fw.ObjectUUID})
+			if err != nil {
+				return NewError(cmd, "Could not render output", err)
+			}
+		}
+		return nil
+	},
+}
+
+var firewallSetCmd = &cobra.Command{
+	Use:     "set [flags] ID",
+	Example: `gscloud firewall set --rules-file web-rules.yaml 9b3d5f9e-4c4e-4ad2-8c3f-0a7b6e2f1d11`,
+	Short:   "Update firewall",
+	Long: `Update properties of an existing firewall.
+
+Rules given with **--rules-file** replace all existing rules of the firewall.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		firewallOp := rt.FirewallOperator()
+		ctx := context.Background()
+		id, err := idForFirewall(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find firewall", err)
+		}
+		updateReq := gsclient.FirewallUpdateRequest{
+			Name: firewallFlags.name,
+		}
+		if firewallFlags.rulesFile != "" {
+			rules, err := readFirewallRules(firewallFlags.rulesFile)
+			if err != nil {
+				return NewError(cmd, "Could not read rules", err)
+			}
+			updateReq.Rules = &rules
+		}
+		err = firewallOp.UpdateFirewall(ctx, id, updateReq)
+		if err != nil {
+			return NewError(cmd, "Could not update firewall", err)
+		}
+		return nil
+	},
+}
+
+var firewallRmCmd = &cobra.Command{
+	Use:     "rm [flags] [ID...]",
+	Aliases: []string{"remove"},
+	Short:   "Remove firewall",
+	Long:    `Remove an existing firewall. Protected firewalls are not removed, see gscloud-protect(1).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		firewallOp := rt.FirewallOperator()
+		ctx := context.Background()
+		id, err := idForFirewall(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find firewall", err)
+		}
+		firewall, err := firewallOp.GetFirewall(ctx, id)
+		if err != nil {
+			return NewError(cmd, "Could not get firewall", err)
+		}
+		err = checkProtected("firewall", firewall.Properties.Name, firewall.Properties.Labels)
+		if err != nil {
+			return NewError(cmd, "Removing firewall failed", err)
+		}
+		err = firewallOp.DeleteFirewall(ctx, id)
+		if err != nil {
+			return NewError(cmd, "Deleting firewall failed", err)
+		}
+		fmt.Fprintf(os.Stderr, "Removed %s\n", id)
+		return nil
+	},
+}
+
+var firewallExportCmd = &cobra.Command{
+	Use:     "export [flags] ID",
+	Example: `gscloud firewall export 9b3d5f9e-4c4e-4ad2-8c3f-0a7b6e2f1d11 > web-rules.yaml`,
+	Short:   "Export firewall rules",
+	Long: `Print the rules of a firewall as YAML, or as JSON if --json is given. The output can be used as input for **--rules-file**. Rules are ordered by their order attribute so the output is stable.
+
+# EXAMPLES
+
+Keep the rules of a firewall under version control:
+
+	$ gscloud firewall export 9b3d5f9e-4c4e-4ad2-8c3f-0a7b6e2f1d11 > web-rules.yaml
+	$ git diff web-rules.yaml
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		firewallOp := rt.FirewallOperator()
+		ctx := context.Background()
+		id, err := idForFirewall(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find firewall", err)
+		}
+		fw, err := firewallOp.GetFirewall(ctx, id)
+		if err != nil {
+			return NewError(cmd, "Could not get firewall", err)
+		}
+		err = writeFirewallRules(os.Stdout, fw.Properties.Rules, outputOptions().Output == "json")
+		if err != nil {
+			return NewError(cmd, "Could not export rules", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	firewallCreateCmd.Flags().StringVarP(&firewallFlags.name, "name", "n", "", "Name of the firewall")
+	firewallCreateCmd.MarkFlagRequired("name")
+	firewallCreateCmd.Flags().StringVar(&firewallFlags.rulesFile, "rules-file", "", "Path to a YAML or JSON file containing firewall rules")
+
+	addCreateLabelFlag(firewallCreateCmd)
+
+	firewallSetCmd.Flags().StringVarP(&firewallFlags.name, "name", "n", "", "New name of the firewall")
+	firewallSetCmd.Flags().StringVar(&firewallFlags.rulesFile, "rules-file", "", "Path to a YAML or JSON file containing firewall rules")
+
+	addListFlags(firewallLsCmd)
+	addBulkFlags(firewallRmCmd, "firewall")
+
+	firewallCmd.AddCommand(firewallLsCmd, firewallCreateCmd, firewallSetCmd, firewallRmCmd, firewallExportCmd)
+	rootCmd.AddCommand(firewallCmd)
+}
+
+// readFirewallRules reads firewall rules from a YAML or JSON file. Unknown
+// keys are rejected to catch typos early.
+func readFirewallRules(path string) (gsclient.FirewallRules, error) {
+	var rules gsclient.FirewallRules
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return rules, err
+	}
+	// JSON is valid YAML, so one parser handles both formats.
+	err = yaml.UnmarshalStrict(data, &rules)
+	if err != nil {
+		return rules, err
+	}
+	return rules, nil
+}
+
+// writeFirewallRules writes firewall rules as YAML or, if asJSON is set, as
+// indented JSON to w.
+func writeFirewallRules(w io.Writer, rules gsclient.FirewallRules, asJSON bool) error {
+	rules.RulesV4In = sortedFirewallRules(rules.RulesV4In)
+	rules.RulesV4Out = sortedFirewallRules(rules.RulesV4Out)
+	rules.RulesV6In = sortedFirewallRules(rules.RulesV6In)
+	rules.RulesV6Out = sortedFirewallRules(rules.RulesV6Out)
+	var data []byte
+	var err error
+	if asJSON {
+		data, err = json.MarshalIndent(rules, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(rules)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// sortedFirewallRules returns a copy of rules sorted by order.
+func sortedFirewallRules(rules []gsclient.FirewallRuleProperties) []gsclient.FirewallRuleProperties {
+	if rules == nil {
+		return nil
+	}
+	sorted := make([]gsclient.FirewallRuleProperties, len(rules))
+	copy(sorted, rules)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Order < sorted[j].Order })
+	return sorted
+}
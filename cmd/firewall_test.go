@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockFirewallRules = gsclient.FirewallRules{
+	RulesV4In: []gsclient.FirewallRuleProperties{
+		{Order: 1, Protocol: gsclient.TCPTransport, DstPort: "443", Action: "accept"},
+		{Order: 0, Protocol: gsclient.TCPTransport, DstPort: "22", Action: "accept", Comment: "SSH, admins only"},
+	},
+	RulesV6Out: []gsclient.FirewallRuleProperties{
+		{Order: 0, Protocol: gsclient.UDPTransport, DstCidr: "2001:db8::/32", Action: "drop"},
+	},
+}
+
+func Test_FirewallRulesRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gscloud")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, asJSON := range []bool{false, true} {
+		buf := new(bytes.Buffer)
+		err := writeFirewallRules(buf, mockFirewallRules, asJSON)
+		assert.Nil(t, err)
+
+		path := filepath.Join(dir, "rules")
+		err = ioutil.WriteFile(path, buf.Bytes(), 0600)
+		assert.Nil(t, err)
+
+		rules, err := readFirewallRules(path)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, rules.RulesV4In[0].Order)
+		assert.Equal(t, "22", rules.RulesV4In[0].DstPort)
+		assert.Equal(t, mockFirewallRules.RulesV6Out, rules.RulesV6Out)
+		assert.Nil(t, rules.RulesV6In)
+
+		again := new(bytes.Buffer)
+		writeFirewallRules(again, rules, asJSON)
+		assert.Equal(t, buf.String(), again.String())
+	}
+}
+
+func Test_ReadFirewallRulesUnknownKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gscloud")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "rules.yaml")
+	ioutil.WriteFile(path, []byte("rules-v4-inn:\n- order: 0\n  action: accept\n"), 0600)
+	_, err = readFirewallRules(path)
+	assert.NotNil(t, err)
+}
@@ -9,7 +9,6 @@ import (
 	"sync"
 
 	"github.com/gridscale/gsclient-go/v3"
-	"github.com/gridscale/gscloud/render"
 	"github.com/gridscale/gscloud/runtime"
 	"github.com/spf13/cobra"
 )
@@ -53,7 +52,7 @@ Show summary for a given account:
 
 		account := rt.Project()
 
-		if !rootFlags.json {
+		if !structuredOutput() {
 			out := new(bytes.Buffer)
 			heading := []string{"setting", "value"}
 			fill := [][]string{
@@ -64,7 +63,10 @@ Show summary for a given account:
 			}
 			var rows [][]string
 			rows = append(rows, fill...)
-			render.AsTable(out, heading, rows, renderOpts)
+			err := renderOutput(out, heading, rows, nil)
+			if err != nil {
+				return NewError(cmd, "Could not render output", err)
+			}
 			fmt.Print(out)
 		}
 
@@ -150,34 +152,27 @@ Show summary for a given account:
 		}()
 
 		out := new(bytes.Buffer)
-		if !rootFlags.json {
-			heading := []string{"object", "count"}
-			var rows [][]string
-			for v := range ch {
-				if v.Err != nil {
-					return v.Err
-				}
-				count := v.Agg["count"].(int)
-				rows = append(rows, []string{v.Obj, strconv.Itoa(count)})
-			}
-			render.AsTable(out, heading, rows, renderOpts)
-		} else {
-			m := map[string]map[string]interface{}{}
-			for v := range ch {
-				if v.Err != nil {
-					return v.Err
-				}
-				m[v.Obj] = v.Agg
+		heading := []string{"object", "count"}
+		var rows [][]string
+		m := map[string]map[string]interface{}{}
+		for v := range ch {
+			if v.Err != nil {
+				return v.Err
 			}
-
-			jsonOutput := output{
-				ProjectEntry: account,
-				ServerAgg:    m["Servers"],
-				StorageAgg:   m["Storages"],
-				IPAddrAgg:    m["IP addresses"],
-				PaasAgg:      m["Platform services"],
-			}
-			render.AsJSON(out, jsonOutput)
+			count := v.Agg["count"].(int)
+			rows = append(rows, []string{v.Obj, strconv.Itoa(count)})
+			m[v.Obj] = v.Agg
+		}
+		jsonOutput := output{
+			ProjectEntry: account,
+			ServerAgg:    m["Servers"],
+			StorageAgg:   m["Storages"],
+			IPAddrAgg:    m["IP addresses"],
+			PaasAgg:      m["Platform services"],
+		}
+		err := renderOutput(out, heading, rows, jsonOutput)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
 		}
 		fmt.Print(out)
 		return nil
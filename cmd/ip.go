@@ -9,7 +9,6 @@ import (
 	"os"
 
 	"github.com/gridscale/gsclient-go/v3"
-	"github.com/gridscale/gscloud/render"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -33,6 +32,8 @@ var ipCmd = &cobra.Command{
 	Long:  `List, add, or remove IP address objects.`,
 }
 
+var ipWideColumns = []string{"ID", "name", "IP", "assigned", "failover", "family", "reverse DNS", "prefix", "location", "labels"}
+
 var ipLsCmd = &cobra.Command{
 	Use:     "ls [-4|-6]",
 	Aliases: []string{"list"},
@@ -52,61 +53,99 @@ var ipLsCmd = &cobra.Command{
 			return NewError(cmd, "Could not get list of IP addresses", err)
 		}
 		var rows [][]string
+		var labels [][]string
+		var addrs []gsclient.IP
 		out := new(bytes.Buffer)
-		if !rootFlags.json {
-			heading := []string{"ID", "name", "IP", "assigned", "failover", "family", "reverse DNS"}
-			for _, addr := range ipAddresses {
-				if ipFlags.v4 && addr.Properties.Family == 6 {
-					continue
-				}
-
-				if ipFlags.v6 && addr.Properties.Family == 4 {
-					continue
-				}
-
-				isFailover := "no"
-				if addr.Properties.Failover {
-					isFailover = "yes"
-				}
-				isAssigned := "free"
-				relations := addr.Properties.Relations
-				if len(relations.Servers) > 0 || len(relations.Loadbalancers) > 0 {
-					isAssigned = "assigned"
-				}
-				properties := [][]string{
-					{
-						addr.Properties.ObjectUUID,
-						addr.Properties.Name,
-						addr.Properties.IP,
-						isAssigned,
-						isFailover,
-						fmt.Sprintf("v%d", addr.Properties.Family),
-						addr.Properties.ReverseDNS,
-					},
-				}
-				rows = append(rows, properties...)
+		heading := []string{"ID", "name", "IP", "assigned", "failover", "family", "reverse DNS"}
+		for _, addr := range ipAddresses {
+			if ipFlags.v4 && addr.Properties.Family == 6 {
+				continue
 			}
-			if rootFlags.quiet {
-				for _, row := range rows {
-					fmt.Println(row[5])
-				}
-				return nil
+
+			if ipFlags.v6 && addr.Properties.Family == 4 {
+				continue
 			}
-			render.AsTable(out, heading, rows, renderOpts)
-		} else {
-			render.AsJSON(out, ipAddresses)
+
+			isFailover := "no"
+			if addr.Properties.Failover {
+				isFailover = "yes"
+			}
+			isAssigned := "free"
+			relations := addr.Properties.Relations
+			if len(relations.Servers) > 0 || len(relations.Loadbalancers) > 0 {
+				isAssigned = "assigned"
+			}
+			properties := [][]string{
+				{
+					addr.Properties.ObjectUUID,
+					addr.Properties.Name,
+					addr.Properties.IP,
+					isAssigned,
+					isFailover,
+					fmt.Sprintf("v%d", addr.Properties.Family),
+					addr.Properties.ReverseDNS,
+				},
+			}
+			rows = append(rows, properties...)
+			labels = append(labels, addr.Properties.Labels)
+			addrs = append(addrs, addr)
+		}
+		order, err := listOrder(heading, rows, labels, addrs)
+		if err != nil {
+			return NewError(cmd, "Could not list IP addresses", err)
+		}
+		rows = selectRows(rows, order)
+		selected := make([]gsclient.IP, 0, len(order))
+		for _, i := range order {
+			selected = append(selected, addrs[i])
+		}
+		if quietOutput() {
+			for _, info := range rows {
+				fmt.Println(info[5])
+			}
+			return nil
+		}
+		heading, rows, err = listColumns(heading, rows, selected, ipWideColumns)
+		if err != nil {
+			return NewError(cmd, "Could not list IP addresses", err)
+		}
+		err = renderOutput(out, heading, rows, selected)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
 		}
 		fmt.Print(out)
 		return nil
 	},
 }
 
+// describeIP describes an IP address to be removed.
+func describeIP(ip gsclient.IP) removal {
+	var owners []string
+	for _, rel := range ip.Properties.Relations.Servers {
+		owners = append(owners, "server "+rel.ServerName)
+	}
+	for _, rel := range ip.Properties.Relations.Loadbalancers {
+		owners = append(owners, "load balancer "+rel.LoadbalancerName)
+	}
+	return removal{
+		kind: "IP address",
+		name: ip.Properties.IP,
+		id:   ip.Properties.ObjectUUID,
+		details: []string{
+			fmt.Sprintf("address: %s, IPv%d", ip.Properties.Prefix, ip.Properties.Family),
+			"assigned to: " + relatedNames(owners),
+		},
+	}
+}
+
 var ipRmCmd = &cobra.Command{
-	Use:     "rm [flags] ID|ADDR",
+	Use:     "rm [flags] [ID|ADDR...]",
 	Aliases: []string{"remove"},
 	Short:   "Delete an IP address",
 	Long: `Remove an existing IP address object by ID or address.
 
+The address and the server or load balancer it is assigned to are shown and you are asked for confirmation first, unless --yes is given. See confirm_destructive in gscloud-config(1) for when confirmation is needed. Protected IP addresses are not removed, see gscloud-protect(1).
+
 # EXAMPLES
 
 Delete by ID:
@@ -118,7 +157,6 @@ Delete by address:
     $ gscloud ip rm 2a06:2380:2:1::24
 
 `,
-	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var id string
 		var err error
@@ -133,6 +171,20 @@ Delete by address:
 		} else {
 			id = args[0]
 		}
+		ip, err := ipOp.GetIP(ctx, id)
+		if err != nil {
+			return NewError(cmd, "Could not get IP address", err)
+		}
+		err = checkProtected("IP address", ip.Properties.IP, ip.Properties.Labels)
+		if err != nil {
+			return NewError(cmd, "Removing IP address failed", err)
+		}
+		err = confirmRemoval(func() (removal, error) {
+			return describeIP(ip), nil
+		})
+		if err != nil {
+			return NewError(cmd, "Removing IP address failed", err)
+		}
 		err = ipOp.DeleteIP(ctx, id)
 		if err != nil {
 			return NewError(cmd, "Releasing IP address failed", err)
@@ -228,6 +280,7 @@ Create a new IPv4 address:
 			Failover:   ipFlags.failover,
 			Name:       ipFlags.name,
 			ReverseDNS: ipFlags.reverseDNS,
+			Labels:     createLabels(),
 		})
 		if err != nil {
 			return NewError(cmd, fmt.Sprintf("Adding IPv%d address failed", family), err)
@@ -294,7 +347,7 @@ Releasing an unassigned IP address will exit with status code 0:
 			log.Println("Not assigned")
 			return nil
 		}
-		err = rt.Client().UnlinkIP(ctx, ownerID, ipID)
+		err = rt.ServerIPRelationOperator().UnlinkIP(ctx, ownerID, ipID)
 		if err != nil {
 			return NewError(cmd, "Could not remove address from server", err)
 		}
@@ -329,7 +382,7 @@ Assign an IPv4 address to a server or load balancer:
 		} else {
 			addrID = args[0]
 		}
-		err = rt.Client().LinkIP(ctx, ipFlags.targetID, addrID)
+		err = rt.ServerIPRelationOperator().LinkIP(ctx, ipFlags.targetID, addrID)
 		if err != nil {
 			return NewError(cmd, "Could not assign IP address", err)
 		}
@@ -340,12 +393,16 @@ Assign an IPv4 address to a server or load balancer:
 func init() {
 	ipLsCmd.PersistentFlags().BoolVarP(&ipFlags.v4, "v4", "4", false, "IPv4 only")
 	ipLsCmd.PersistentFlags().BoolVarP(&ipFlags.v6, "v6", "6", false, "IPv6 only")
+	addListFlags(ipLsCmd)
+	addConfirmFlags(ipRmCmd)
+	addBulkFlags(ipRmCmd, "ip")
 
 	ipAddCmd.PersistentFlags().BoolVarP(&ipFlags.v4, "v4", "4", false, "Add a new IPv4 address")
 	ipAddCmd.PersistentFlags().BoolVarP(&ipFlags.v6, "v6", "6", false, "Add a new IPv6 address")
 	ipAddCmd.PersistentFlags().StringVarP(&ipFlags.name, "name", "n", "", "Optional name of the IP address being created. Can be omitted")
 	ipAddCmd.PersistentFlags().BoolVar(&ipFlags.failover, "failover", false, "Enable failover. If given, IP is no longer available for DHCP and cannot be assigned")
 	ipAddCmd.PersistentFlags().StringVar(&ipFlags.reverseDNS, "reverse-dns", "", "Optional reverse DNS entry for the IP address")
+	addCreateLabelFlag(ipAddCmd)
 
 	ipSetCmd.PersistentFlags().StringVarP(&ipFlags.name, "name", "n", "", "Change name of the IP address")
 	ipSetCmd.PersistentFlags().BoolVar(&ipFlags.failover, "failover", false, "Enable failover")
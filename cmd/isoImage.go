@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/gridscale/gsclient-go/v3"
-	"github.com/gridscale/gscloud/render"
 	"github.com/spf13/cobra"
 )
 
@@ -26,6 +25,8 @@ var isoImageCmd = &cobra.Command{
 	Long:  `List, create, or remove ISO images.`,
 }
 
+var isoImageWideColumns = []string{"id", "name", "changed", "private", "source url", "capacity", "status", "location", "labels"}
+
 var isoImageLsCmd = &cobra.Command{
 	Use:     "ls [flags]",
 	Aliases: []string{"list"},
@@ -38,17 +39,8 @@ var isoImageLsCmd = &cobra.Command{
 		if err != nil {
 			return NewError(cmd, "Could not get list of images", err)
 		}
-		if rootFlags.json {
-			render.AsJSON(os.Stdout, images)
-			return nil
-		}
-		if rootFlags.quiet {
-			for _, image := range images {
-				fmt.Println(image.Properties.ObjectUUID)
-			}
-			return nil
-		}
 		var rows [][]string
+		var labels [][]string
 		heading := []string{"id", "name", "changed", "private", "source url"}
 		for _, image := range images {
 			var private string
@@ -67,26 +59,60 @@ var isoImageLsCmd = &cobra.Command{
 				},
 			}
 			rows = append(rows, fill...)
+			labels = append(labels, image.Properties.Labels)
+		}
+		order, err := listOrder(heading, rows, labels, images)
+		if err != nil {
+			return NewError(cmd, "Could not list images", err)
+		}
+		rows = selectRows(rows, order)
+		selected := make([]gsclient.ISOImage, 0, len(order))
+		for _, i := range order {
+			selected = append(selected, images[i])
+		}
+		if quietOutput() {
+			for _, info := range rows {
+				fmt.Println(info[0])
+			}
+			return nil
+		}
+		heading, rows, err = listColumns(heading, rows, selected, isoImageWideColumns)
+		if err != nil {
+			return NewError(cmd, "Could not list images", err)
+		}
+		err = renderOutput(os.Stdout, heading, rows, selected)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
 		}
-		render.AsTable(os.Stdout, heading, rows, renderOpts)
 		return nil
 	},
 }
 
 var isoImageRmCmd = &cobra.Command{
-	Use:     "rm [ID]",
+	Use:     "rm [flags] [ID...]",
 	Aliases: []string{"remove"},
 	Short:   "Remove ISO image",
-	Long:    `Remove an existing ISO image.`,
-	Args:    cobra.ExactArgs(1),
+	Long:    `Remove an existing ISO image. Protected ISO images are not removed, see gscloud-protect(1).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		imageOp := rt.ISOImageOperator()
 		ctx := context.Background()
-		err := imageOp.DeleteISOImage(ctx, args[0])
+		id, err := idForISOImage(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find ISO image", err)
+		}
+		image, err := imageOp.GetISOImage(ctx, id)
+		if err != nil {
+			return NewError(cmd, "Could not get ISO image", err)
+		}
+		err = checkProtected("ISO image", image.Properties.Name, image.Properties.Labels)
+		if err != nil {
+			return NewError(cmd, "Deleting image failed", err)
+		}
+		err = imageOp.DeleteISOImage(ctx, id)
 		if err != nil {
 			return NewError(cmd, "Deleting image failed", err)
 		}
-		fmt.Fprintf(os.Stderr, "Removed %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Removed %s\n", id)
 		return nil
 	},
 }
@@ -114,14 +140,18 @@ Create a Fedora CoreOS image:
 		image, err := imageOp.CreateISOImage(ctx, gsclient.ISOImageCreateRequest{
 			Name:      isoImageFlags.name,
 			SourceURL: isoImageFlags.sourceURL,
+			Labels:    createLabels(),
 		})
 		if err != nil {
 			return NewError(cmd, "Creating image failed", err)
 		}
-		if !rootFlags.json {
+		if !structuredOutput() {
 			fmt.Println("Image created:", image.ObjectUUID)
 		} else {
-			render.AsJSON(os.Stdout, output{Image: image.ObjectUUID})
+			err = renderOutput(os.Stdout, nil, nil, output{Image: image.ObjectUUID})
+			if err != nil {
+				return NewError(cmd, "Could not render output", err)
+			}
 		}
 		return nil
 	},
@@ -132,6 +162,10 @@ func init() {
 	isoImageCreateCmd.MarkFlagRequired("name")
 	isoImageCreateCmd.Flags().StringVar(&isoImageFlags.sourceURL, "source-url", "", "URL from where the image is downloaded")
 	isoImageCreateCmd.MarkFlagRequired("source-url")
+	addCreateLabelFlag(isoImageCreateCmd)
+
+	addListFlags(isoImageLsCmd)
+	addBulkFlags(isoImageRmCmd, "iso-image")
 
 	isoImageCmd.AddCommand(isoImageLsCmd, isoImageRmCmd, isoImageCreateCmd)
 	rootCmd.AddCommand(isoImageCmd)
@@ -11,7 +11,6 @@ import (
 	"sort"
 	"time"
 
-	"github.com/gridscale/gscloud/render"
 	"github.com/gridscale/gscloud/runtime"
 	"github.com/gridscale/gscloud/utils"
 	"github.com/kardianos/osext"
@@ -67,22 +66,20 @@ var getKubernetesReleasesCmd = &cobra.Command{
 			}
 		}
 		sort.Sort(sort.Reverse(utils.StringSorter(releases)))
-		if !rootFlags.json {
-			heading := []string{"releases"}
-			var rows [][]string
+		heading := []string{"releases"}
+		var rows [][]string
+		for _, rel := range releases {
+			rows = append(rows, []string{rel})
+		}
+		if quietOutput() {
 			for _, rel := range releases {
-				rows = append(rows, []string{rel})
-			}
-			render.AsTable(out, heading, rows, renderOpts)
-			if rootFlags.quiet {
-				for _, rel := range releases {
-					fmt.Println(rel)
-				}
-				return nil
+				fmt.Println(rel)
 			}
-
-		} else {
-			render.AsJSON(out, releases)
+			return nil
+		}
+		err = renderOutput(out, heading, rows, releases)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
 		}
 		fmt.Print(out)
 		return nil
@@ -106,6 +103,11 @@ KUBECONFIG
 		credentialPlugin, _ := cmd.Flags().GetBool("credential-plugin")
 		kubeConfigEnv := os.Getenv("KUBECONFIG")
 
+		clusterID, err := idForPaaSService(context.Background(), clusterID)
+		if err != nil {
+			return NewError(cmd, "Could not find cluster", err)
+		}
+
 		pathOptions := clientcmd.NewDefaultPathOptions()
 		if kubeConfigFile != "" {
 			kubeConfigEnv = kubeConfigFile
@@ -205,12 +207,17 @@ var execCredentialCmd = &cobra.Command{
 		kubeConfigFile, _ := cmd.Flags().GetString("kubeconfig")
 		clusterID, _ := cmd.Flags().GetString("cluster")
 
+		clusterID, err := idForPaaSService(context.Background(), clusterID)
+		if err != nil {
+			return NewError(cmd, "Could not find cluster", err)
+		}
+
 		kubectlDefaults := clientcmd.NewDefaultPathOptions()
 		if kubeConfigFile != "" {
 			kubectlDefaults.GlobalFile = kubeConfigFile
 		}
 
-		_, err := kubectlDefaults.GetStartingConfig()
+		_, err = kubectlDefaults.GetStartingConfig()
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
 		}
@@ -270,13 +277,13 @@ var execCredentialCmd = &cobra.Command{
 
 func init() {
 	saveKubeconfigCmd.Flags().String("kubeconfig", "", "(optional) absolute path to the kubeconfig file. Overrides KUBECONFIG environment variable")
-	saveKubeconfigCmd.Flags().String("cluster", "", "The cluster's UUID")
+	saveKubeconfigCmd.Flags().String("cluster", "", "The cluster's name or UUID")
 	saveKubeconfigCmd.MarkFlagRequired("cluster")
 	saveKubeconfigCmd.Flags().Bool("credential-plugin", false, "Enables credential plugin authentication method (exec-credential)")
 	clusterCmd.AddCommand(saveKubeconfigCmd)
 
 	execCredentialCmd.Flags().String("kubeconfig", "", "(optional) absolute path to the kubeconfig file")
-	execCredentialCmd.Flags().String("cluster", "", "The cluster's UUID")
+	execCredentialCmd.Flags().String("cluster", "", "The cluster's name or UUID")
 	execCredentialCmd.MarkFlagRequired("cluster")
 	clusterCmd.AddCommand(execCredentialCmd)
 
@@ -292,6 +299,10 @@ func fetchKubeConfigFromProvider(op runtime.KubernetesOperator, id string) (kube
 		return kubeConfig{}, time.Time{}, err
 	}
 
+	if err := waitForPaaSService(context.Background(), op, id); err != nil {
+		return kubeConfig{}, time.Time{}, err
+	}
+
 	platformService, err := op.GetPaaSService(context.Background(), id)
 	if err != nil {
 		return kubeConfig{}, time.Time{}, err
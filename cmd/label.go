@@ -0,0 +1,407 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/spf13/cobra"
+)
+
+// labeledObject is an object as far as its labels are concerned.
+type labeledObject struct {
+	ID     string
+	Name   string
+	Labels []string
+}
+
+// labeledKind gives access to the labels of the objects of one type. id
+// returns the ID of an object given by ID or name, get the object, list all
+// objects, and set replaces the labels of an object.
+type labeledKind struct {
+	id   func(ctx context.Context, val string) (string, error)
+	get  func(ctx context.Context, id string) (labeledObject, error)
+	list func(ctx context.Context) ([]labeledObject, error)
+	set  func(ctx context.Context, id string, labels []string) error
+}
+
+// labeledKinds are the object types with labels, by the name of their
+// commands.
+var labeledKinds = map[string]labeledKind{
+	"server": {
+		id: idForServer,
+		get: func(ctx context.Context, id string) (labeledObject, error) {
+			o, err := rt.ServerOperator().GetServer(ctx, id)
+			return labeledObject{o.Properties.ObjectUUID, o.Properties.Name, o.Properties.Labels}, err
+		},
+		list: func(ctx context.Context) ([]labeledObject, error) {
+			list, err := rt.ServerOperator().GetServerList(ctx)
+			var objs []labeledObject
+			for _, o := range list {
+				objs = append(objs, labeledObject{o.Properties.ObjectUUID, o.Properties.Name, o.Properties.Labels})
+			}
+			return objs, err
+		},
+		set: func(ctx context.Context, id string, labels []string) error {
+			return rt.ServerOperator().UpdateServer(ctx, id, gsclient.ServerUpdateRequest{Labels: &labels})
+		},
+	},
+	"storage": {
+		id: idForStorage,
+		get: func(ctx context.Context, id string) (labeledObject, error) {
+			o, err := rt.StorageOperator().GetStorage(ctx, id)
+			return labeledObject{o.Properties.ObjectUUID, o.Properties.Name, o.Properties.Labels}, err
+		},
+		list: func(ctx context.Context) ([]labeledObject, error) {
+			list, err := rt.StorageOperator().GetStorageList(ctx)
+			var objs []labeledObject
+			for _, o := range list {
+				objs = append(objs, labeledObject{o.Properties.ObjectUUID, o.Properties.Name, o.Properties.Labels})
+			}
+			return objs, err
+		},
+		set: func(ctx context.Context, id string, labels []string) error {
+			return rt.StorageOperator().UpdateStorage(ctx, id, gsclient.StorageUpdateRequest{Labels: &labels})
+		},
+	},
+	"network": {
+		id: idForNetwork,
+		get: func(ctx context.Context, id string) (labeledObject, error) {
+			o, err := rt.NetworkOperator().GetNetwork(ctx, id)
+			return labeledObject{o.Properties.ObjectUUID, o.Properties.Name, o.Properties.Labels}, err
+		},
+		list: func(ctx context.Context) ([]labeledObject, error) {
+			list, err := rt.NetworkOperator().GetNetworkList(ctx)
+			var objs []labeledObject
+			for _, o := range list {
+				objs = append(objs, labeledObject{o.Properties.ObjectUUID, o.Properties.Name, o.Properties.Labels})
+			}
+			return objs, err
+		},
+		set: func(ctx context.Context, id string, labels []string) error {
+			return rt.NetworkOperator().UpdateNetwork(ctx, id, gsclient.NetworkUpdateRequest{Labels: &labels})
+		},
+	},
+	"ip": {
+		id: idForIP,
+		get: func(ctx context.Context, id string) (labeledObject, error) {
+			o, err := rt.IPOperator().GetIP(ctx, id)
+			return labeledObject{o.Properties.ObjectUUID, ipName(o), o.Properties.Labels}, err
+		},
+		list: func(ctx context.Context) ([]labeledObject, error) {
+			list, err := rt.IPOperator().GetIPList(ctx)
+			var objs []labeledObject
+			for _, o := range list {
+				objs = append(objs, labeledObject{o.Properties.ObjectUUID, ipName(o), o.Properties.Labels})
+			}
+			return objs, err
+		},
+		set: func(ctx context.Context, id string, labels []string) error {
+			return rt.IPOperator().UpdateIP(ctx, id, gsclient.IPUpdateRequest{Labels: &labels})
+		},
+	},
+	"template": {
+		id: idForTemplate,
+		get: func(ctx context.Context, id string) (labeledObject, error) {
+			o, err := rt.TemplateOperator().GetTemplate(ctx, id)
+			return labeledObject{o.Properties.ObjectUUID, o.Properties.Name, o.Properties.Labels}, err
+		},
+		list: func(ctx context.Context) ([]labeledObject, error) {
+			list, err := rt.TemplateOperator().GetTemplateList(ctx)
+			var objs []labeledObject
+			for _, o := range list {
+				objs = append(objs, labeledObject{o.Properties.ObjectUUID, o.Properties.Name, o.Properties.Labels})
+			}
+			return objs, err
+		},
+		set: func(ctx context.Context, id string, labels []string) error {
+			return rt.TemplateOperator().UpdateTemplate(ctx, id, gsclient.TemplateUpdateRequest{Labels: &labels})
+		},
+	},
+	"iso-image": {
+		id: idForISOImage,
+		get: func(ctx context.Context, id string) (labeledObject, error) {
+			o, err := rt.ISOImageOperator().GetISOImage(ctx, id)
+			return labeledObject{o.Properties.ObjectUUID, o.Properties.Name, o.Properties.Labels}, err
+		},
+		list: func(ctx context.Context) ([]labeledObject, error) {
+			list, err := rt.ISOImageOperator().GetISOImageList(ctx)
+			var objs []labeledObject
+			for _, o := range list {
+				objs = append(objs, labeledObject{o.Properties.ObjectUUID, o.Properties.Name, o.Properties.Labels})
+			}
+			return objs, err
+		},
+		set: func(ctx context.Context, id string, labels []string) error {
+			return rt.ISOImageOperator().UpdateISOImage(ctx, id, gsclient.ISOImageUpdateRequest{Labels: &labels})
+		},
+	},
+	"ssh-key": {
+		id: idForSSHKey,
+		get: func(ctx context.Context, id string) (labeledObject, error) {
+			o, err := rt.SSHKeyOperator().GetSshkey(ctx, id)
+			return labeledObject{o.Properties.ObjectUUID, o.Properties.Name, o.Properties.Labels}, err
+		},
+		list: func(ctx context.Context) ([]labeledObject, error) {
+			list, err := rt.SSHKeyOperator().GetSshkeyList(ctx)
+			var objs []labeledObject
+			for _, o := range list {
+				objs = append(objs, labeledObject{o.Properties.ObjectUUID, o.Properties.Name, o.Properties.Labels})
+			}
+			return objs, err
+		},
+		set: func(ctx context.Context, id string, labels []string) error {
+			return rt.SSHKeyOperator().UpdateSshkey(ctx, id, gsclient.SshkeyUpdateRequest{Labels: &labels})
+		},
+	},
+	"firewall": {
+		id: idForFirewall,
+		get: func(ctx context.Context, id string) (labeledObject, error) {
+			o, err := rt.FirewallOperator().GetFirewall(ctx, id)
+			return labeledObject{o.Properties.ObjectUUID, o.Properties.Name, o.Properties.Labels}, err
+		},
+		list: func(ctx context.Context) ([]labeledObject, error) {
+			list, err := rt.FirewallOperator().GetFirewallList(ctx)
+			var objs []labeledObject
+			for _, o := range list {
+				objs = append(objs, labeledObject{o.Properties.ObjectUUID, o.Properties.Name, o.Properties.Labels})
+			}
+			return objs, err
+		},
+		set: func(ctx context.Context, id string, labels []string) error {
+			return rt.FirewallOperator().UpdateFirewall(ctx, id, gsclient.FirewallUpdateRequest{Labels: &labels})
+		},
+	},
+	"loadbalancer": {
+		id: idForLoadBalancer,
+		get: func(ctx context.Context, id string) (labeledObject, error) {
+			o, err := rt.LoadBalancerOperator().GetLoadBalancer(ctx, id)
+			return labeledObject{o.Properties.ObjectUUID, o.Properties.Name, o.Properties.Labels}, err
+		},
+		list: func(ctx context.Context) ([]labeledObject, error) {
+			list, err := rt.LoadBalancerOperator().GetLoadBalancerList(ctx)
+			var objs []labeledObject
+			for _, o := range list {
+				objs = append(objs, labeledObject{o.Properties.ObjectUUID, o.Properties.Name, o.Properties.Labels})
+			}
+			return objs, err
+		},
+		set: func(ctx context.Context, id string, labels []string) error {
+			// The API expects the complete object.
+			lb, err := rt.LoadBalancerOperator().GetLoadBalancer(ctx, id)
+			if err != nil {
+				return err
+			}
+			props := lb.Properties
+			return rt.LoadBalancerOperator().UpdateLoadBalancer(ctx, id, gsclient.LoadBalancerUpdateRequest{
+				Name:                props.Name,
+				ListenIPv4UUID:      props.ListenIPv4UUID,
+				ListenIPv6UUID:      props.ListenIPv6UUID,
+				Algorithm:           gsclient.LoadbalancerAlgorithm(props.Algorithm),
+				ForwardingRules:     props.ForwardingRules,
+				BackendServers:      props.BackendServers,
+				Labels:              labels,
+				RedirectHTTPToHTTPS: props.RedirectHTTPToHTTPS,
+			})
+		},
+	},
+}
+
+// labeledKindNames returns the names of the object types with labels.
+func labeledKindNames() []string {
+	var names []string
+	for name := range labeledKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupLabeledKind returns the object type called name.
+func lookupLabeledKind(name string) (labeledKind, error) {
+	kind, ok := labeledKinds[name]
+	if !ok {
+		return labeledKind{}, fmt.Errorf("unknown object type %q, expected one of %s", name, strings.Join(labeledKindNames(), ", "))
+	}
+	return kind, nil
+}
+
+// addLabel returns labels with label added, and whether they changed. A
+// label given as KEY=VALUE replaces the labels with the same KEY.
+func addLabel(labels []string, label string) ([]string, bool) {
+	if hasLabel(labels, label) {
+		return labels, false
+	}
+	kept := []string{}
+	for _, l := range labels {
+		if labelKey(l) != labelKey(label) {
+			kept = append(kept, l)
+		}
+	}
+	return append(kept, label), true
+}
+
+// removeLabel returns labels without label, and whether it was there. A
+// label given as KEY alone removes all labels with that KEY.
+func removeLabel(labels []string, label string) ([]string, bool) {
+	keyOnly := !strings.Contains(label, "=")
+	kept := []string{}
+	for _, l := range labels {
+		if l != label && !(keyOnly && labelKey(l) == label) {
+			kept = append(kept, l)
+		}
+	}
+	return kept, len(kept) != len(labels)
+}
+
+// labelKey returns the KEY of a label given as KEY=VALUE, or the label.
+func labelKey(label string) string {
+	return strings.SplitN(label, "=", 2)[0]
+}
+
+type labelCmdFlags struct {
+	create []string
+}
+
+var labelFlags labelCmdFlags
+
+// addCreateLabelFlag adds --label to a command creating objects.
+func addCreateLabelFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVarP(&labelFlags.create, "label", "l", nil, "Give the new object a label, as KEY=VALUE. Can be given more than once")
+}
+
+var labelCmd = &cobra.Command{
+	Use:   "label",
+	Short: "Operations on labels",
+	Long: fmt.Sprintf(`Show, add, or remove the labels of an object. Labels are strings, usually given as KEY=VALUE.
+
+TYPE is one of %s. Objects are given by ID or name, IP addresses also by address.
+
+Labels can also be given to new objects with --label of the create commands, and used to select objects: the ls commands only list objects with a label matching --label, and the rm commands as well as server on and off act on all objects with matching labels when --label is given instead of an ID.
+
+# EXAMPLES
+
+Label a server and list its labels:
+
+	$ gscloud label add server web-1 env=prod team=shop
+	$ gscloud label ls server web-1
+
+Turn off all servers labeled env=staging:
+
+	$ gscloud server off -l env=staging
+`, strings.Join(labeledKindNames(), ", ")),
+}
+
+var labelLsCmd = &cobra.Command{
+	Use:     "ls [flags] TYPE ID",
+	Aliases: []string{"list"},
+	Example: `gscloud label ls storage db-data`,
+	Short:   "List labels of an object",
+	Long:    `List the labels of an object. Labels given as KEY=VALUE are split into the key and value columns.`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kind, err := lookupLabeledKind(args[0])
+		if err != nil {
+			return NewError(cmd, "Invalid object type", err)
+		}
+		ctx := context.Background()
+		id, err := kind.id(ctx, args[1])
+		if err != nil {
+			return NewError(cmd, "Could not find "+args[0], err)
+		}
+		obj, err := kind.get(ctx, id)
+		if err != nil {
+			return NewError(cmd, "Could not get "+args[0], err)
+		}
+		labels := obj.Labels
+		if labels == nil {
+			labels = []string{}
+		}
+		if quietOutput() {
+			for _, label := range labels {
+				fmt.Println(label)
+			}
+			return nil
+		}
+		var rows [][]string
+		heading := []string{"key", "value"}
+		for _, label := range labels {
+			parts := strings.SplitN(label, "=", 2)
+			if len(parts) == 1 {
+				parts = append(parts, "")
+			}
+			rows = append(rows, parts)
+		}
+		out := new(bytes.Buffer)
+		err = renderOutput(out, heading, rows, labels)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
+		}
+		fmt.Print(out)
+		return nil
+	},
+}
+
+var labelAddCmd = &cobra.Command{
+	Use:     "add TYPE ID LABEL...",
+	Example: `gscloud label add server web-1 env=prod team=shop`,
+	Short:   "Add labels to an object",
+	Long:    `Add labels to an object. A label given as KEY=VALUE replaces the labels of the object with the same KEY.`,
+	Args:    cobra.MinimumNArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeLabels(cmd, args[0], args[1], args[2:], addLabel)
+	},
+}
+
+var labelRmCmd = &cobra.Command{
+	Use:     "rm TYPE ID LABEL...",
+	Aliases: []string{"remove"},
+	Example: `gscloud label rm server web-1 team`,
+	Short:   "Remove labels from an object",
+	Long:    `Remove labels from an object. A label given as KEY alone removes all labels with that KEY, whatever their value.`,
+	Args:    cobra.MinimumNArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeLabels(cmd, args[0], args[1], args[2:], removeLabel)
+	},
+}
+
+// changeLabels applies change with each of labels to the labels of an
+// object, and saves them if they changed.
+func changeLabels(cmd *cobra.Command, kindName, val string, labels []string, change func([]string, string) ([]string, bool)) error {
+	kind, err := lookupLabeledKind(kindName)
+	if err != nil {
+		return NewError(cmd, "Invalid object type", err)
+	}
+	ctx := context.Background()
+	id, err := kind.id(ctx, val)
+	if err != nil {
+		return NewError(cmd, "Could not find "+kindName, err)
+	}
+	obj, err := kind.get(ctx, id)
+	if err != nil {
+		return NewError(cmd, "Could not get "+kindName, err)
+	}
+	newLabels := obj.Labels
+	var changed bool
+	for _, label := range labels {
+		var c bool
+		newLabels, c = change(newLabels, label)
+		changed = changed || c
+	}
+	if !changed {
+		return nil
+	}
+	err = kind.set(ctx, id, newLabels)
+	if err != nil {
+		return NewError(cmd, "Could not change labels", err)
+	}
+	return nil
+}
+
+func init() {
+	labelCmd.AddCommand(labelLsCmd, labelAddCmd, labelRmCmd)
+	rootCmd.AddCommand(labelCmd)
+}
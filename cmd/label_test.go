@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AddRemoveLabel(t *testing.T) {
+	labels, changed := addLabel([]string{"env=staging", "team=shop"}, "env=prod")
+	assert.True(t, changed)
+	assert.Equal(t, []string{"team=shop", "env=prod"}, labels)
+
+	labels, changed = removeLabel(labels, "team")
+	assert.True(t, changed)
+	assert.Equal(t, []string{"env=prod"}, labels)
+
+	_, changed = removeLabel(labels, "env=staging")
+	assert.False(t, changed)
+}
+
+func Test_CreateLabels(t *testing.T) {
+	defer func() { labelFlags = labelCmdFlags{} }()
+	labelFlags.create = []string{"env=prod", "team=shop"}
+	assert.Equal(t, []string{"env=prod", "team=shop"}, createLabels())
+}
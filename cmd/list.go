@@ -0,0 +1,381 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/spf13/cobra"
+)
+
+type listCmdFlags struct {
+	filters []string
+	labels  []string
+	sortBy  string
+	reverse bool
+	columns []string
+}
+
+var (
+	listFlags listCmdFlags
+)
+
+// addListFlags adds the options shared by all ls commands to filter and sort
+// the objects listed.
+func addListFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&listFlags.filters, "filter", nil, "Only list objects whose COLUMN matches PATTERN, given as COLUMN=PATTERN")
+	cmd.Flags().StringArrayVarP(&listFlags.labels, "label", "l", nil, "Only list objects with a label matching PATTERN")
+	cmd.Flags().StringVar(&listFlags.sortBy, "sort-by", "", "Sort objects by COLUMN")
+	cmd.Flags().BoolVar(&listFlags.reverse, "reverse", false, "Reverse the order of objects")
+	addColumnsFlag(cmd)
+}
+
+// addColumnsFlag adds the option to select the columns printed by an ls
+// command.
+func addColumnsFlag(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&listFlags.columns, "columns", nil, "Print the given columns, separated by commas, or \"wide\" for more columns")
+}
+
+// listOrder returns the indexes of the rows to list, in the order to list
+// them. Rows are selected by --filter and --label and ordered by --sort-by
+// and --reverse. Columns are referred to by their heading, case-insensitive,
+// with blanks replaced by dashes, or by any other column that --columns
+// accepts. labels holds the labels of the object shown in the row with the
+// same index, and objs the objects, as for listColumns.
+func listOrder(heading []string, rows [][]string, labels [][]string, objs interface{}) ([]int, error) {
+	type filter struct {
+		values  []string
+		pattern *regexp.Regexp
+	}
+	var filters []filter
+	for _, f := range listFlags.filters {
+		parts := strings.SplitN(f, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid filter %q, expected COLUMN=PATTERN", f)
+		}
+		_, values, err := columnValues(heading, rows, objs, parts[0])
+		if err != nil {
+			return nil, err
+		}
+		pattern, err := compileGlob(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %w", f, err)
+		}
+		filters = append(filters, filter{values: values, pattern: pattern})
+	}
+	var labelPatterns []labelPattern
+	for _, l := range listFlags.labels {
+		p, err := compileLabelPattern(l)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label %q: %w", l, err)
+		}
+		labelPatterns = append(labelPatterns, p)
+	}
+
+	var order []int
+	for i := range rows {
+		selected := true
+		for _, f := range filters {
+			if !f.pattern.MatchString(f.values[i]) {
+				selected = false
+				break
+			}
+		}
+		for _, l := range labelPatterns {
+			if !l.matches(labels[i]) {
+				selected = false
+				break
+			}
+		}
+		if selected {
+			order = append(order, i)
+		}
+	}
+
+	if listFlags.sortBy != "" {
+		_, values, err := columnValues(heading, rows, objs, listFlags.sortBy)
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return lessValue(values[order[i]], values[order[j]])
+		})
+	}
+	if listFlags.reverse {
+		for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+			order[i], order[j] = order[j], order[i]
+		}
+	}
+	return order, nil
+}
+
+// selectRows returns the rows at the given indexes.
+func selectRows(rows [][]string, order []int) [][]string {
+	var selected [][]string
+	for _, i := range order {
+		selected = append(selected, rows[i])
+	}
+	return selected
+}
+
+// columnIndex returns the index of the column called name in heading.
+func columnIndex(heading []string, name string) (int, bool) {
+	for i, h := range heading {
+		if columnKey(h) == columnKey(name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func columnKey(s string) string {
+	return strings.NewReplacer(" ", "-", "_", "-").Replace(strings.ToLower(s))
+}
+
+// listColumns returns the heading and rows to print, made of the columns
+// selected by --columns. The column "wide", as well as --output wide, stands
+// for the columns in wide, the preset of the ls command such as
+// serverWideColumns: usually the default columns followed by status,
+// location, and labels. Columns not in heading are taken from the
+// properties of objs, a slice of gsclient objects in the same order as
+// rows. Such columns are referred to by the JSON name of the field, e.g.
+// location_uuid. Fields whose name ends in _name or _uuid may also be
+// referred to without that suffix, e.g. location.
+func listColumns(heading []string, rows [][]string, objs interface{}, wide []string) ([]string, [][]string, error) {
+	var names []string
+	for _, c := range listFlags.columns {
+		if columnKey(c) == "wide" {
+			names = append(names, wide...)
+		} else if c != "" {
+			names = append(names, c)
+		}
+	}
+	if len(listFlags.columns) == 0 && outputOptions().Output == "wide" {
+		names = wide
+	}
+	if len(names) == 0 {
+		return heading, rows, nil
+	}
+
+	selectedHeading := make([]string, len(names))
+	selectedRows := make([][]string, len(rows))
+	for i := range rows {
+		selectedRows[i] = make([]string, len(names))
+	}
+	for j, name := range names {
+		column, values, err := columnValues(heading, rows, objs, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		selectedHeading[j] = column
+		for i := range rows {
+			selectedRows[i][j] = values[i]
+		}
+	}
+	return selectedHeading, selectedRows, nil
+}
+
+// columnValues returns the heading and the values of the column called
+// name, in the same order as rows. Columns not in heading are taken from the
+// properties of objs, as described for listColumns.
+func columnValues(heading []string, rows [][]string, objs interface{}, name string) (string, []string, error) {
+	values := make([]string, len(rows))
+	if column, ok := columnIndex(heading, name); ok {
+		for i, row := range rows {
+			values[i] = row[column]
+		}
+		return heading[column], values, nil
+	}
+
+	objects := reflect.ValueOf(objs)
+	var props reflect.StructField
+	if objects.Kind() == reflect.Slice && objects.Type().Elem().Kind() == reflect.Struct {
+		props, _ = objects.Type().Elem().FieldByName("Properties")
+	}
+	field, ok := propertyField(props.Type, name)
+	if !ok {
+		keys := make([]string, 0, len(heading))
+		for _, h := range heading {
+			keys = append(keys, columnKey(h))
+		}
+		keys = append(keys, propertyKeys(props.Type)...)
+		return "", nil, fmt.Errorf("no such column %q, expected one of: %s", name, strings.Join(keys, ", "))
+	}
+	for i := range rows {
+		value := objects.Index(i).FieldByIndex(props.Index).FieldByIndex(field.Index)
+		values[i] = propertyString(value)
+	}
+	return name, values, nil
+}
+
+// propertyField returns the field of the properties struct t called name.
+func propertyField(t reflect.Type, name string) (reflect.StructField, bool) {
+	if t == nil || t.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	key := columnKey(name)
+	for _, candidate := range []string{key, key + "-name", key + "-uuid"} {
+		for i := 0; i < t.NumField(); i++ {
+			if jsonKey(t.Field(i)) == candidate {
+				return t.Field(i), true
+			}
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// propertyKeys returns the column names of all fields of the properties
+// struct t.
+func propertyKeys(t reflect.Type) []string {
+	var keys []string
+	if t == nil || t.Kind() != reflect.Struct {
+		return keys
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if key := jsonKey(t.Field(i)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// jsonKey returns the JSON name of field f as a column name.
+func jsonKey(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "-" || f.PkgPath != "" {
+		return ""
+	}
+	if name == "" {
+		name = f.Name
+	}
+	return columnKey(name)
+}
+
+// propertyString formats the value of a property for a table cell. Times
+// are printed like the ones in the default columns, lists of strings are
+// separated by commas, and anything more complex is printed as JSON.
+func propertyString(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch t := v.Interface().(type) {
+	case gsclient.GSTime:
+		if t.IsZero() {
+			return ""
+		}
+		return t.Local().Format(time.RFC3339)
+	case time.Time:
+		if t.IsZero() {
+			return ""
+		}
+		return t.Local().Format(time.RFC3339)
+	case []string:
+		return strings.Join(t, ",")
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// labelPattern selects objects by label. A pattern without "=" also
+// matches the key of labels given as KEY=VALUE.
+type labelPattern struct {
+	pattern *regexp.Regexp
+	keyOnly bool
+}
+
+func compileLabelPattern(s string) (labelPattern, error) {
+	pattern, err := compileGlob(s)
+	if err != nil {
+		return labelPattern{}, err
+	}
+	return labelPattern{pattern: pattern, keyOnly: !strings.Contains(s, "=")}, nil
+}
+
+// matches tells whether one of labels matches the pattern.
+func (p labelPattern) matches(labels []string) bool {
+	for _, label := range labels {
+		if p.pattern.MatchString(label) {
+			return true
+		}
+		if p.keyOnly && p.pattern.MatchString(strings.SplitN(label, "=", 2)[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileGlob turns a shell pattern into a regular expression matching the
+// whole string. "*" matches any sequence of characters, "?" matches any
+// single character, and "[...]" matches a character class, negated by a
+// leading "!".
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	runes := []rune(glob)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '[':
+			end := i + 1
+			if end < len(runes) && runes[end] == '!' {
+				end++
+			}
+			if end < len(runes) && runes[end] == ']' {
+				end++
+			}
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("missing ] in pattern %q", glob)
+			}
+			class := string(runes[i+1 : end])
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i = end
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+// lessValue compares numbers numerically and everything else as strings.
+func lessValue(a, b string) bool {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return x < y
+	}
+	return a < b
+}
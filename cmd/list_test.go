@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testListHeading = []string{"id", "name", "capacity", "source url"}
+	testListRows    = [][]string{
+		{"a", "web-1", "10", "http://example.com/a.iso"},
+		{"b", "db-1", "100", "http://example.com/b.iso"},
+		{"c", "web-2", "9", ""},
+	}
+	testListLabels = [][]string{
+		{"env=prod", "team=web"},
+		{"env=prod"},
+		{"env=test", "team=web"},
+	}
+)
+
+func Test_ListOrder(t *testing.T) {
+	type testCase struct {
+		flags    listCmdFlags
+		expected []int
+	}
+	testCases := []testCase{
+		{flags: listCmdFlags{}, expected: []int{0, 1, 2}},
+		{flags: listCmdFlags{filters: []string{"name=web-*"}}, expected: []int{0, 2}},
+		{flags: listCmdFlags{filters: []string{"NAME=web-*", "capacity=9"}}, expected: []int{2}},
+		{flags: listCmdFlags{filters: []string{"source-url=*b.iso"}}, expected: []int{1}},
+		{flags: listCmdFlags{labels: []string{"team"}}, expected: []int{0, 2}},
+		{flags: listCmdFlags{labels: []string{"env=prod", "team=*"}}, expected: []int{0}},
+		{flags: listCmdFlags{sortBy: "capacity"}, expected: []int{2, 0, 1}},
+		{flags: listCmdFlags{sortBy: "name", reverse: true}, expected: []int{2, 0, 1}},
+		{flags: listCmdFlags{reverse: true}, expected: []int{2, 1, 0}},
+	}
+	defer func() { listFlags = listCmdFlags{} }()
+	for _, tc := range testCases {
+		listFlags = tc.flags
+		order, err := listOrder(testListHeading, testListRows, testListLabels, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expected, order)
+	}
+}
+
+func Test_ListOrderInvalid(t *testing.T) {
+	defer func() { listFlags = listCmdFlags{} }()
+	for _, flags := range []listCmdFlags{
+		{filters: []string{"name"}},
+		{filters: []string{"size=10"}},
+		{filters: []string{"name=[web"}},
+		{sortBy: "size"},
+	} {
+		listFlags = flags
+		_, err := listOrder(testListHeading, testListRows, testListLabels, nil)
+		assert.NotNil(t, err)
+	}
+}
+
+func Test_ListOrderProperties(t *testing.T) {
+	heading := []string{"id", "name"}
+	rows := [][]string{{"a", "web-1"}, {"b", "db-1"}, {"c", "web-2"}}
+	servers := []gsclient.Server{
+		{Properties: gsclient.ServerProperties{ObjectUUID: "a", LocationUUID: "loc-2", Labels: []string{"team=web"}}},
+		{Properties: gsclient.ServerProperties{ObjectUUID: "b", LocationUUID: "loc-3"}},
+		{Properties: gsclient.ServerProperties{ObjectUUID: "c", LocationUUID: "loc-1", Labels: []string{"env=prod"}}},
+	}
+	type testCase struct {
+		flags    listCmdFlags
+		expected []int
+	}
+	testCases := []testCase{
+		{flags: listCmdFlags{sortBy: "location"}, expected: []int{2, 0, 1}},
+		{flags: listCmdFlags{sortBy: "labels", reverse: true}, expected: []int{0, 2, 1}},
+		{flags: listCmdFlags{filters: []string{"location-uuid=loc-[12]"}, sortBy: "name"}, expected: []int{0, 2}},
+	}
+	defer func() { listFlags = listCmdFlags{} }()
+	for _, tc := range testCases {
+		listFlags = tc.flags
+		order, err := listOrder(heading, rows, nil, servers)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expected, order)
+	}
+}
+
+func Test_ListColumns(t *testing.T) {
+	heading := []string{"id", "name", "power"}
+	rows := [][]string{{"a", "web-1", "on"}, {"b", "db-1", "off"}}
+	servers := []gsclient.Server{
+		{Properties: gsclient.ServerProperties{ObjectUUID: "a", LocationUUID: "loc-1", Cores: 2, Labels: []string{"env=prod", "team=web"}}},
+		{Properties: gsclient.ServerProperties{ObjectUUID: "b", LocationUUID: "loc-2", Cores: 4, AutoRecovery: true}},
+	}
+	wide := []string{"id", "cores"}
+	type testCase struct {
+		columns         []string
+		output          string
+		expectedHeading []string
+		expectedRows    [][]string
+	}
+	testCases := []testCase{
+		{expectedHeading: heading, expectedRows: rows},
+		{
+			columns:         []string{"name", "power", "location", "labels"},
+			expectedHeading: []string{"name", "power", "location", "labels"},
+			expectedRows:    [][]string{{"web-1", "on", "loc-1", "env=prod,team=web"}, {"db-1", "off", "loc-2", ""}},
+		},
+		{
+			columns:         []string{"NAME", "auto-recovery", "change_time", "relations"},
+			expectedHeading: []string{"name", "auto-recovery", "change_time", "relations"},
+			expectedRows: [][]string{
+				{"web-1", "false", "", `{"isoimages":null,"networks":null,"public_ips":null,"storages":null}`},
+				{"db-1", "true", "", `{"isoimages":null,"networks":null,"public_ips":null,"storages":null}`},
+			},
+		},
+		{
+			columns:         []string{"wide", "power"},
+			expectedHeading: []string{"id", "cores", "power"},
+			expectedRows:    [][]string{{"a", "2", "on"}, {"b", "4", "off"}},
+		},
+		{
+			output:          "wide",
+			expectedHeading: []string{"id", "cores"},
+			expectedRows:    [][]string{{"a", "2"}, {"b", "4"}},
+		},
+	}
+	defer func() {
+		listFlags = listCmdFlags{}
+		renderOpts.Output = ""
+	}()
+	for _, tc := range testCases {
+		listFlags = listCmdFlags{columns: tc.columns}
+		renderOpts.Output = tc.output
+		h, r, err := listColumns(heading, rows, servers, wide)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expectedHeading, h)
+		assert.Equal(t, tc.expectedRows, r)
+	}
+}
+
+func Test_ListColumnsInvalid(t *testing.T) {
+	defer func() { listFlags = listCmdFlags{} }()
+	listFlags = listCmdFlags{columns: []string{"name", "colour"}}
+	_, _, err := listColumns([]string{"id", "name"}, nil, []gsclient.Server{}, nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "colour")
+	assert.Contains(t, err.Error(), "location-uuid")
+}
@@ -0,0 +1,479 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/spf13/cobra"
+)
+
+type loadBalancerCmdFlags struct {
+	name                string
+	algorithm           string
+	forwardingRules     []string
+	backends            []string
+	listenIPv4          string
+	listenIPv6          string
+	redirectHTTPToHTTPS bool
+}
+
+var (
+	loadBalancerFlags loadBalancerCmdFlags
+)
+
+var loadBalancerCmd = &cobra.Command{
+	Use:     "loadbalancer",
+	Aliases: []string{"lb"},
+	Short:   "Operations on load balancers",
+	Long:    `List, create, or remove load balancers.`,
+}
+
+var loadBalancerWideColumns = []string{"id", "name", "algorithm", "rules", "backends", "changed", "status", "listen_ipv4", "listen_ipv6", "redirect_http_to_https", "location", "labels"}
+
+var loadBalancerLsCmd = &cobra.Command{
+	Use:     "ls [flags]",
+	Aliases: []string{"list"},
+	Short:   "List load balancers",
+	Long:    `List load balancer objects.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lbOp := rt.LoadBalancerOperator()
+		ctx := context.Background()
+		out := new(bytes.Buffer)
+		loadBalancers, err := lbOp.GetLoadBalancerList(ctx)
+		if err != nil {
+			return NewError(cmd, "Could not get list of load balancers", err)
+		}
+		var rows [][]string
+		var labels [][]string
+		heading := []string{"id", "name", "algorithm", "rules", "backends", "changed", "status"}
+		for _, lb := range loadBalancers {
+			fill := [][]string{
+				{
+					lb.Properties.ObjectUUID,
+					lb.Properties.Name,
+					lb.Properties.Algorithm,
+					strconv.Itoa(len(lb.Properties.ForwardingRules)),
+					strconv.Itoa(len(lb.Properties.BackendServers)),
+					lb.Properties.ChangeTime.Local().Format(time.RFC3339),
+					lb.Properties.Status,
+				},
+			}
+			rows = append(rows, fill...)
+			labels = append(labels, lb.Properties.Labels)
+		}
+		order, err := listOrder(heading, rows, labels, loadBalancers)
+		if err != nil {
+			return NewError(cmd, "Could not list load balancers", err)
+		}
+		rows = selectRows(rows, order)
+		selected := make([]gsclient.LoadBalancer, 0, len(order))
+		for _, i := range order {
+			selected = append(selected, loadBalancers[i])
+		}
+		if quietOutput() {
+			for _, info := range rows {
+				fmt.Println(info[0])
+			}
+			return nil
+		}
+		heading, rows, err = listColumns(heading, rows, selected, loadBalancerWideColumns)
+		if err != nil {
+			return NewError(cmd, "Could not list load balancers", err)
+		}
+		err = renderOutput(out, heading, rows, selected)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
+		}
+		fmt.Print(out)
+		return nil
+	},
+}
+
+var loadBalancerCreateCmd = &cobra.Command{
+	Use:     "create [flags]",
+	Example: `gscloud loadbalancer create --name web --listen-ipv4 203.0.113.42 --forwarding-rule 80:8080:http --backend web-1`,
+	Short:   "Create load balancer",
+	Long: `Create a new load balancer.
+
+Forwarding rules are given as LISTEN_PORT:TARGET_PORT[:MODE] where MODE is one of "http", "https", or "tcp" (default "http"). Backends can be given as IP address, server ID, or server name. Servers are resolved to their first assigned public IPv4 address, or IPv6 address if no IPv4 address is assigned. Listen addresses can be given as ID or address.
+
+# EXAMPLES
+
+Create a load balancer forwarding HTTP traffic to two servers:
+
+	$ gscloud loadbalancer create \
+		--name web \
+		--listen-ipv4 203.0.113.42 \
+		--listen-ipv6 2001:db8:0:1::1c8 \
+		--forwarding-rule 80:8080:http \
+		--backend web-1 \
+		--backend 37d53278-8e5f-47e1-a63f-54513e4b4d53
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		type output struct {
+			LoadBalancer string `json:"loadbalancer"`
+		}
+
+		ctx := context.Background()
+		algorithm, err := toLoadBalancerAlgorithm(loadBalancerFlags.algorithm)
+		if err != nil {
+			return NewError(cmd, "Cannot create load balancer", err)
+		}
+		rules, err := parseForwardingRules(loadBalancerFlags.forwardingRules)
+		if err != nil {
+			return NewError(cmd, "Cannot create load balancer", err)
+		}
+		backends, err := backendServers(ctx, loadBalancerFlags.backends)
+		if err != nil {
+			return NewError(cmd, "Cannot create load balancer", err)
+		}
+		ipv4ID, err := listenAddressID(ctx, loadBalancerFlags.listenIPv4)
+		if err != nil {
+			return NewError(cmd, "Cannot create load balancer", err)
+		}
+		ipv6ID, err := listenAddressID(ctx, loadBalancerFlags.listenIPv6)
+		if err != nil {
+			return NewError(cmd, "Cannot create load balancer", err)
+		}
+
+		lbOp := rt.LoadBalancerOperator()
+		lb, err := lbOp.CreateLoadBalancer(ctx, gsclient.LoadBalancerCreateRequest{
+			Name:                loadBalancerFlags.name,
+			ListenIPv4UUID:      ipv4ID,
+			ListenIPv6UUID:      ipv6ID,
+			Algorithm:           algorithm,
+			ForwardingRules:     rules,
+			BackendServers:      backends,
+			RedirectHTTPToHTTPS: loadBalancerFlags.redirectHTTPToHTTPS,
+			Labels:              createLabels(),
+		})
+		if err != nil {
+			return NewError(cmd, "Creating load balancer failed", err)
+		}
+		err = waitForLoadBalancer(ctx, lbOp, lb.ObjectUUID)
+		if err != nil {
+			return NewError(cmd, "Waiting for load balancer failed", err)
+		}
+		if !structuredOutput() {
+			fmt.Println("Load balancer created:", lb.ObjectUUID)
+		} else {
+			err = renderOutput(os.Stdout, nil, nil, output{LoadBalancer: lb.ObjectUUID})
+			if err != nil {
+				return NewError(cmd, "Could not render output", err)
+			}
+		}
+		return nil
+	},
+}
+
+var loadBalancerSetCmd = &cobra.Command{
+	Use:     "set [flags] ID",
+	Example: `gscloud loadbalancer set --algorithm leastconn 0b6e2ac9-2fa0-4c6b-a7d4-6a1f4e2e4f9b`,
+	Short:   "Update load balancer",
+	Long: `Update properties of an existing load balancer.
+
+Forwarding rules and backends given replace all existing ones. Properties not given are left untouched.
+
+# EXAMPLES
+
+Replace all backends of a load balancer:
+
+	$ gscloud loadbalancer set --backend web-1 --backend web-2 0b6e2ac9-2fa0-4c6b-a7d4-6a1f4e2e4f9b
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		id, err := idForLoadBalancer(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find load balancer", err)
+		}
+		lbOp := rt.LoadBalancerOperator()
+		lb, err := lbOp.GetLoadBalancer(ctx, id)
+		if err != nil {
+			return NewError(cmd, "Could not get load balancer", err)
+		}
+
+		// The API expects the complete object, so start with what is
+		// there and only replace the properties given on the command line.
+		props := lb.Properties
+		updateReq := gsclient.LoadBalancerUpdateRequest{
+			Name:                props.Name,
+			ListenIPv4UUID:      props.ListenIPv4UUID,
+			ListenIPv6UUID:      props.ListenIPv6UUID,
+			Algorithm:           gsclient.LoadbalancerAlgorithm(props.Algorithm),
+			ForwardingRules:     props.ForwardingRules,
+			BackendServers:      props.BackendServers,
+			Labels:              props.Labels,
+			RedirectHTTPToHTTPS: props.RedirectHTTPToHTTPS,
+		}
+		flags := cmd.Flags()
+		if flags.Changed("name") {
+			updateReq.Name = loadBalancerFlags.name
+		}
+		if flags.Changed("algorithm") {
+			updateReq.Algorithm, err = toLoadBalancerAlgorithm(loadBalancerFlags.algorithm)
+			if err != nil {
+				return NewError(cmd, "Could not update load balancer", err)
+			}
+		}
+		if flags.Changed("forwarding-rule") {
+			updateReq.ForwardingRules, err = parseForwardingRules(loadBalancerFlags.forwardingRules)
+			if err != nil {
+				return NewError(cmd, "Could not update load balancer", err)
+			}
+		}
+		if flags.Changed("backend") {
+			updateReq.BackendServers, err = backendServers(ctx, loadBalancerFlags.backends)
+			if err != nil {
+				return NewError(cmd, "Could not update load balancer", err)
+			}
+		}
+		if flags.Changed("listen-ipv4") {
+			updateReq.ListenIPv4UUID, err = listenAddressID(ctx, loadBalancerFlags.listenIPv4)
+			if err != nil {
+				return NewError(cmd, "Could not update load balancer", err)
+			}
+		}
+		if flags.Changed("listen-ipv6") {
+			updateReq.ListenIPv6UUID, err = listenAddressID(ctx, loadBalancerFlags.listenIPv6)
+			if err != nil {
+				return NewError(cmd, "Could not update load balancer", err)
+			}
+		}
+		if flags.Changed("redirect-http-to-https") {
+			updateReq.RedirectHTTPToHTTPS = loadBalancerFlags.redirectHTTPToHTTPS
+		}
+		err = lbOp.UpdateLoadBalancer(ctx, id, updateReq)
+		if err != nil {
+			return NewError(cmd, "Could not update load balancer", err)
+		}
+		err = waitForLoadBalancer(ctx, lbOp, id)
+		if err != nil {
+			return NewError(cmd, "Waiting for load balancer failed", err)
+		}
+		return nil
+	},
+}
+
+var loadBalancerRmCmd = &cobra.Command{
+	Use:     "rm [flags] [ID...]",
+	Aliases: []string{"remove"},
+	Short:   "Remove load balancer",
+	Long:    `Remove an existing load balancer. Listen IP addresses are not removed. Protected load balancers are not removed, see gscloud-protect(1).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		id, err := idForLoadBalancer(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find load balancer", err)
+		}
+		lbOp := rt.LoadBalancerOperator()
+		lb, err := lbOp.GetLoadBalancer(ctx, id)
+		if err != nil {
+			return NewError(cmd, "Could not get load balancer", err)
+		}
+		err = checkProtected("load balancer", lb.Properties.Name, lb.Properties.Labels)
+		if err != nil {
+			return NewError(cmd, "Deleting load balancer failed", err)
+		}
+		err = lbOp.DeleteLoadBalancer(ctx, id)
+		if err != nil {
+			return NewError(cmd, "Deleting load balancer failed", err)
+		}
+		fmt.Fprintf(os.Stderr, "Removed %s\n", id)
+		return nil
+	},
+}
+
+var loadBalancerEventsCmd = &cobra.Command{
+	Use:     "events ID",
+	Example: `gscloud loadbalancer events 0b6e2ac9-2fa0-4c6b-a7d4-6a1f4e2e4f9b`,
+	Short:   "List events",
+	Long:    `Retrieve event log for given load balancer.`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		id, err := idForLoadBalancer(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find load balancer", err)
+		}
+		lbOp := rt.LoadBalancerOperator()
+		events, err := lbOp.GetLoadBalancerEventList(ctx, id)
+		if err != nil {
+			return NewError(cmd, "Could not get list of events", err)
+		}
+
+		out := new(bytes.Buffer)
+		if quietOutput() {
+			for _, event := range events {
+				fmt.Println(event.Properties.RequestUUID)
+			}
+			return nil
+		}
+		var rows [][]string
+		heading := []string{
+			"time", "request id", "request type", "details", "initiator",
+		}
+		for _, event := range events {
+			fill := [][]string{
+				{
+					event.Properties.Timestamp.Local().Format(time.RFC3339),
+					event.Properties.RequestUUID,
+					event.Properties.RequestType,
+					event.Properties.Change,
+					event.Properties.Initiator,
+				},
+			}
+			rows = append(rows, fill...)
+		}
+		err = renderOutput(out, heading, rows, events)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
+		}
+		fmt.Print(out)
+		return nil
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{loadBalancerCreateCmd, loadBalancerSetCmd} {
+		c.Flags().StringVarP(&loadBalancerFlags.name, "name", "n", "", "Name of the load balancer")
+		c.Flags().StringVar(&loadBalancerFlags.algorithm, "algorithm", "roundrobin", "Balancing algorithm. One of \"roundrobin\", \"leastconn\"")
+		c.Flags().StringArrayVar(&loadBalancerFlags.forwardingRules, "forwarding-rule", nil, "Forwarding rule as LISTEN_PORT:TARGET_PORT[:MODE]. Can be given multiple times")
+		c.Flags().StringArrayVar(&loadBalancerFlags.backends, "backend", nil, "Backend server given as IP address, server ID, or server name. Can be given multiple times")
+		c.Flags().StringVar(&loadBalancerFlags.listenIPv4, "listen-ipv4", "", "ID or address of the IPv4 address to listen on")
+		c.Flags().StringVar(&loadBalancerFlags.listenIPv6, "listen-ipv6", "", "ID or address of the IPv6 address to listen on")
+		c.Flags().BoolVar(&loadBalancerFlags.redirectHTTPToHTTPS, "redirect-http-to-https", false, "Redirect HTTP requests to HTTPS")
+	}
+	loadBalancerCreateCmd.MarkFlagRequired("name")
+	loadBalancerCreateCmd.MarkFlagRequired("forwarding-rule")
+	loadBalancerCreateCmd.MarkFlagRequired("backend")
+	addCreateLabelFlag(loadBalancerCreateCmd)
+
+	addListFlags(loadBalancerLsCmd)
+	addBulkFlags(loadBalancerRmCmd, "loadbalancer")
+
+	loadBalancerCmd.AddCommand(loadBalancerLsCmd, loadBalancerCreateCmd, loadBalancerSetCmd, loadBalancerRmCmd, loadBalancerEventsCmd)
+	rootCmd.AddCommand(loadBalancerCmd)
+}
+
+func toLoadBalancerAlgorithm(val string) (gsclient.LoadbalancerAlgorithm, error) {
+	switch val {
+	case "roundrobin":
+		return gsclient.LoadbalancerRoundrobinAlg, nil
+
+	case "leastconn":
+		return gsclient.LoadbalancerLeastConnAlg, nil
+	}
+	return "", fmt.Errorf("not a valid algorithm: %s", val)
+}
+
+// parseForwardingRules turns rules in the form of LISTEN_PORT:TARGET_PORT[:MODE]
+// into forwarding rules.
+func parseForwardingRules(vals []string) ([]gsclient.ForwardingRule, error) {
+	var rules []gsclient.ForwardingRule
+	for _, val := range vals {
+		parts := strings.Split(val, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("not a valid forwarding rule: %s", val)
+		}
+		listenPort, err := strconv.Atoi(parts[0])
+		if err != nil || listenPort < 1 || listenPort > 65535 {
+			return nil, fmt.Errorf("not a valid listen port: %s", parts[0])
+		}
+		targetPort, err := strconv.Atoi(parts[1])
+		if err != nil || targetPort < 1 || targetPort > 65535 {
+			return nil, fmt.Errorf("not a valid target port: %s", parts[1])
+		}
+		mode := "http"
+		if len(parts) == 3 {
+			mode = parts[2]
+		}
+		switch mode {
+		case "http", "https", "tcp":
+		default:
+			return nil, fmt.Errorf("not a valid mode: %s", mode)
+		}
+		rules = append(rules, gsclient.ForwardingRule{
+			ListenPort: listenPort,
+			TargetPort: targetPort,
+			Mode:       mode,
+		})
+	}
+	return rules, nil
+}
+
+// backendServers resolves IP addresses, server IDs, or server names to
+// backend servers of a load balancer.
+func backendServers(ctx context.Context, vals []string) ([]gsclient.BackendServer, error) {
+	var backends []gsclient.BackendServer
+	var servers []gsclient.Server
+	for _, val := range vals {
+		if net.ParseIP(val) != nil {
+			backends = append(backends, gsclient.BackendServer{Host: val, Weight: 100})
+			continue
+		}
+		if servers == nil {
+			var err error
+			servers, err = rt.ServerOperator().GetServerList(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+		server, err := serverForBackend(servers, val)
+		if err != nil {
+			return nil, err
+		}
+		host := ""
+		for _, addr := range server.Properties.Relations.PublicIPs {
+			if addr.Family == 4 {
+				host = addr.IP
+				break
+			}
+			if host == "" {
+				host = addr.IP
+			}
+		}
+		if host == "" {
+			return nil, fmt.Errorf("server %s has no public IP address assigned", val)
+		}
+		backends = append(backends, gsclient.BackendServer{Host: host, Weight: 100})
+	}
+	return backends, nil
+}
+
+func serverForBackend(servers []gsclient.Server, val string) (gsclient.Server, error) {
+	var objs []namedObject
+	for _, s := range servers {
+		objs = append(objs, namedObject{ID: s.Properties.ObjectUUID, Name: s.Properties.Name})
+	}
+	id := val
+	if _, err := uuid.Parse(val); err != nil {
+		id, err = idForName("server", val, objs)
+		if err != nil {
+			return gsclient.Server{}, err
+		}
+	}
+	for _, s := range servers {
+		if s.Properties.ObjectUUID == id {
+			return s, nil
+		}
+	}
+	return gsclient.Server{}, fmt.Errorf("no such server %s", val)
+}
+
+// listenAddressID returns the ID of an IP address given either as address or ID.
+func listenAddressID(ctx context.Context, val string) (string, error) {
+	addr := net.ParseIP(val)
+	if addr == nil {
+		return val, nil
+	}
+	return idForAddress(ctx, addr, rt.IPOperator())
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseForwardingRules(t *testing.T) {
+	type testCase struct {
+		Rules         []string
+		Expected      []gsclient.ForwardingRule
+		ExpectedError bool
+	}
+	testCases := []testCase{
+		{
+			Rules: []string{"80:8080"},
+			Expected: []gsclient.ForwardingRule{
+				{ListenPort: 80, TargetPort: 8080, Mode: "http"},
+			},
+		},
+		{
+			Rules: []string{"443:8443:https", "22:2222:tcp"},
+			Expected: []gsclient.ForwardingRule{
+				{ListenPort: 443, TargetPort: 8443, Mode: "https"},
+				{ListenPort: 22, TargetPort: 2222, Mode: "tcp"},
+			},
+		},
+		{
+			Rules:         []string{"80"},
+			ExpectedError: true,
+		},
+		{
+			Rules:         []string{"80:70000"},
+			ExpectedError: true,
+		},
+		{
+			Rules:         []string{"80:8080:udp"},
+			ExpectedError: true,
+		},
+	}
+	for _, test := range testCases {
+		rules, err := parseForwardingRules(test.Rules)
+		assert.Equal(t, test.ExpectedError, err != nil)
+		assert.Equal(t, test.Expected, rules)
+	}
+}
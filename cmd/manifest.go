@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// managedByLabel is the key of the label that apply puts on every object it
+// creates or updates. Its value is the name of the manifest, so that --prune
+// only removes objects that belong to the same manifest.
+const managedByLabel = "gscloud/managed-by"
+
+// defaultManifestName is used in the managed-by label of manifests without
+// a name.
+const defaultManifestName = "gscloud"
+
+// manifest describes the objects of a project. Objects are identified by
+// name, and refer to each other by name. A reference that does not match any
+// object in the manifest is looked up by name or ID among the objects in the
+// project.
+type manifest struct {
+	Name     string            `yaml:"name,omitempty" json:"name,omitempty"`
+	SSHKeys  []manifestSSHKey  `yaml:"ssh-keys,omitempty" json:"ssh-keys,omitempty"`
+	Networks []manifestNetwork `yaml:"networks,omitempty" json:"networks,omitempty"`
+	IPs      []manifestIP      `yaml:"ips,omitempty" json:"ips,omitempty"`
+	Storages []manifestStorage `yaml:"storages,omitempty" json:"storages,omitempty"`
+	Servers  []manifestServer  `yaml:"servers,omitempty" json:"servers,omitempty"`
+}
+
+type manifestSSHKey struct {
+	Name   string   `yaml:"name" json:"name"`
+	Key    string   `yaml:"key" json:"key"`
+	Labels []string `yaml:"labels,omitempty" json:"labels,omitempty"`
+}
+
+type manifestNetwork struct {
+	Name       string   `yaml:"name" json:"name"`
+	L2Security bool     `yaml:"l2security,omitempty" json:"l2security,omitempty"`
+	Labels     []string `yaml:"labels,omitempty" json:"labels,omitempty"`
+}
+
+type manifestIP struct {
+	Name       string   `yaml:"name" json:"name"`
+	Family     int      `yaml:"family" json:"family"`
+	Failover   bool     `yaml:"failover,omitempty" json:"failover,omitempty"`
+	ReverseDNS string   `yaml:"reverse-dns,omitempty" json:"reverse-dns,omitempty"`
+	Labels     []string `yaml:"labels,omitempty" json:"labels,omitempty"`
+}
+
+// manifestStorage is a storage. Template, hostname, and SSH keys are only
+// used when the storage is created.
+type manifestStorage struct {
+	Name     string   `yaml:"name" json:"name"`
+	Capacity int      `yaml:"capacity" json:"capacity"`
+	Type     string   `yaml:"type,omitempty" json:"type,omitempty"`
+	Template string   `yaml:"template,omitempty" json:"template,omitempty"`
+	Hostname string   `yaml:"hostname,omitempty" json:"hostname,omitempty"`
+	SSHKeys  []string `yaml:"ssh-keys,omitempty" json:"ssh-keys,omitempty"`
+	Labels   []string `yaml:"labels,omitempty" json:"labels,omitempty"`
+}
+
+// manifestServer is a server. The first of its storages is the boot device.
+// Power is left as it is if not given. Profile is only used when the server
+// is created.
+type manifestServer struct {
+	Name     string   `yaml:"name" json:"name"`
+	Cores    int      `yaml:"cores" json:"cores"`
+	Memory   int      `yaml:"memory" json:"memory"`
+	Profile  string   `yaml:"profile,omitempty" json:"profile,omitempty"`
+	Power    *bool    `yaml:"power,omitempty" json:"power,omitempty"`
+	Storages []string `yaml:"storages,omitempty" json:"storages,omitempty"`
+	Networks []string `yaml:"networks,omitempty" json:"networks,omitempty"`
+	IPs      []string `yaml:"ips,omitempty" json:"ips,omitempty"`
+	Labels   []string `yaml:"labels,omitempty" json:"labels,omitempty"`
+}
+
+// readManifest reads a manifest in YAML or JSON from the file at path, or
+// from stdin if path is "-".
+func readManifest(path string) (manifest, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return manifest{}, err
+	}
+	return parseManifest(data)
+}
+
+// parseManifest parses and validates a manifest. Unknown fields are an
+// error, so that typos do not go unnoticed.
+func parseManifest(data []byte) (manifest, error) {
+	var m manifest
+	err := yaml.UnmarshalStrict(data, &m)
+	if err != nil {
+		return manifest{}, err
+	}
+	return m, m.validate()
+}
+
+// validate checks that all objects have a unique name and sensible
+// properties.
+func (m manifest) validate() error {
+	names := map[string]bool{}
+	checkName := func(kind, name string) error {
+		if name == "" {
+			return fmt.Errorf("%s without name", kind)
+		}
+		if names[kind+"/"+name] {
+			return fmt.Errorf("%s %q is given more than once", kind, name)
+		}
+		names[kind+"/"+name] = true
+		return nil
+	}
+	for _, k := range m.SSHKeys {
+		if err := checkName("ssh-key", k.Name); err != nil {
+			return err
+		}
+		if strings.TrimSpace(k.Key) == "" {
+			return fmt.Errorf("ssh-key %q has no key", k.Name)
+		}
+	}
+	for _, n := range m.Networks {
+		if err := checkName("network", n.Name); err != nil {
+			return err
+		}
+	}
+	for _, ip := range m.IPs {
+		if err := checkName("ip", ip.Name); err != nil {
+			return err
+		}
+		if ip.Family != 4 && ip.Family != 6 {
+			return fmt.Errorf("ip %q: expected family 4 or 6", ip.Name)
+		}
+	}
+	for _, s := range m.Storages {
+		if err := checkName("storage", s.Name); err != nil {
+			return err
+		}
+		if s.Capacity < 1 {
+			return fmt.Errorf("storage %q: expected capacity ≥ 1 GB", s.Name)
+		}
+		if s.Type != "" {
+			if _, err := toStorageType(s.Type); err != nil {
+				return fmt.Errorf("storage %q: %w", s.Name, err)
+			}
+		}
+	}
+	for _, s := range m.Servers {
+		if err := checkName("server", s.Name); err != nil {
+			return err
+		}
+		if s.Cores < 1 || s.Memory < 1 {
+			return fmt.Errorf("server %q: expected at least 1 core and 1 GB memory", s.Name)
+		}
+		if s.Profile != "" {
+			if _, err := toHardwareProfile(s.Profile); err != nil {
+				return fmt.Errorf("server %q: %w", s.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// managedBy returns the managed-by label of objects in the manifest.
+func (m manifest) managedBy() string {
+	name := m.Name
+	if name == "" {
+		name = defaultManifestName
+	}
+	return managedByLabel + "=" + name
+}
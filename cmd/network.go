@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"github.com/gridscale/gsclient-go/v3"
-	"github.com/gridscale/gscloud/render"
 	"github.com/spf13/cobra"
 )
 
@@ -26,6 +25,8 @@ var networkCmd = &cobra.Command{
 	Long:  `List, create, or remove networks.`,
 }
 
+var networkWideColumns = []string{"id", "name", "location", "changed", "status", "network_type", "public_net", "dhcp_active", "labels"}
+
 var networkLsCmd = &cobra.Command{
 	Use:     "ls [flags]",
 	Aliases: []string{"list"},
@@ -40,31 +41,43 @@ var networkLsCmd = &cobra.Command{
 			return NewError(cmd, "Could not get list of networks", err)
 		}
 		var rows [][]string
-		if !rootFlags.json {
-			heading := []string{"id", "name", "location", "changed", "status"}
-			for _, network := range networks {
-				fill := [][]string{
-					{
-						network.Properties.ObjectUUID,
-						network.Properties.Name,
-						network.Properties.LocationName,
-						network.Properties.ChangeTime.Local().Format(time.RFC3339),
-						network.Properties.Status,
-					},
-				}
-				rows = append(rows, fill...)
-
+		var labels [][]string
+		heading := []string{"id", "name", "location", "changed", "status"}
+		for _, network := range networks {
+			fill := [][]string{
+				{
+					network.Properties.ObjectUUID,
+					network.Properties.Name,
+					network.Properties.LocationName,
+					network.Properties.ChangeTime.Local().Format(time.RFC3339),
+					network.Properties.Status,
+				},
 			}
-			render.AsTable(out, heading, rows, renderOpts)
-			if rootFlags.quiet {
-				for _, info := range rows {
-					fmt.Println(info[0])
-				}
-				return nil
+			rows = append(rows, fill...)
+			labels = append(labels, network.Properties.Labels)
+		}
+		order, err := listOrder(heading, rows, labels, networks)
+		if err != nil {
+			return NewError(cmd, "Could not list networks", err)
+		}
+		rows = selectRows(rows, order)
+		selected := make([]gsclient.Network, 0, len(order))
+		for _, i := range order {
+			selected = append(selected, networks[i])
+		}
+		if quietOutput() {
+			for _, info := range rows {
+				fmt.Println(info[0])
 			}
-
-		} else {
-			render.AsJSON(out, networks)
+			return nil
+		}
+		heading, rows, err = listColumns(heading, rows, selected, networkWideColumns)
+		if err != nil {
+			return NewError(cmd, "Could not list networks", err)
+		}
+		err = renderOutput(out, heading, rows, selected)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
 		}
 		fmt.Print(out)
 		return nil
@@ -92,41 +105,87 @@ Create a network:
 		networkOp := rt.NetworkOperator()
 		ctx := context.Background()
 		network, err := networkOp.CreateNetwork(ctx, gsclient.NetworkCreateRequest{
-			Name: networkFlags.networkName,
+			Name:   networkFlags.networkName,
+			Labels: createLabels(),
 		})
 
 		if err != nil {
 			return NewError(cmd, "Could not create network", err)
 		}
-		if !rootFlags.json {
+		err = waitForNetwork(ctx, networkOp, network.ObjectUUID)
+		if err != nil {
+			return NewError(cmd, "Waiting for network failed", err)
+		}
+		if !structuredOutput() {
 			fmt.Println("Network created:", network.ObjectUUID)
 		} else {
-			render.AsJSON(os.Stdout, output{Network: network.ObjectUUID})
+			err = renderOutput(os.Stdout, nil, nil, output{Network: network.ObjectUUID})
+			if err != nil {
+				return NewError(cmd, "Could not render output", err)
+			}
 		}
 		return nil
 	},
 }
 
+// describeNetwork describes a network to be removed.
+func describeNetwork(n gsclient.Network) removal {
+	var servers []string
+	for _, rel := range n.Properties.Relations.Servers {
+		servers = append(servers, rel.ObjectName)
+	}
+	return removal{
+		kind: "network",
+		name: n.Properties.Name,
+		id:   n.Properties.ObjectUUID,
+		details: []string{
+			"servers: " + relatedNames(servers),
+		},
+	}
+}
+
 var networkRmCmd = &cobra.Command{
-	Use:     "rm [flags] [ID]",
+	Use:     "rm [flags] [ID...]",
 	Aliases: []string{"remove"},
 	Short:   "Remove network",
-	Long:    `Remove an existing network.`,
-	Args:    cobra.ExactArgs(1),
+	Long:    `Remove an existing network. Its name and the servers connected to it are shown and you are asked for confirmation first, unless --yes is given. See confirm_destructive in gscloud-config(1) for when confirmation is needed. Protected networks are not removed, see gscloud-protect(1).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
+		id, err := idForNetwork(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find network", err)
+		}
 		networkOps := rt.NetworkOperator()
-		err := networkOps.DeleteNetwork(ctx, args[0])
+		network, err := networkOps.GetNetwork(ctx, id)
+		if err != nil {
+			return NewError(cmd, "Could not get network", err)
+		}
+		err = checkProtected("network", network.Properties.Name, network.Properties.Labels)
+		if err != nil {
+			return NewError(cmd, "Removing network failed", err)
+		}
+		err = confirmRemoval(func() (removal, error) {
+			return describeNetwork(network), nil
+		})
+		if err != nil {
+			return NewError(cmd, "Removing network failed", err)
+		}
+		err = networkOps.DeleteNetwork(ctx, id)
 		if err != nil {
 			return NewError(cmd, "Deleting network failed", err)
 		}
-		fmt.Fprintf(os.Stderr, "Removed %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Removed %s\n", id)
 		return nil
 	},
 }
 
 func init() {
 	networkCreateCmd.Flags().StringVarP(&networkFlags.networkName, "name", "n", "", "Name of the network")
+	addCreateLabelFlag(networkCreateCmd)
+
+	addListFlags(networkLsCmd)
+	addConfirmFlags(networkRmCmd)
+	addBulkFlags(networkRmCmd, "network")
 
 	networkCmd.AddCommand(networkLsCmd, networkRmCmd, networkCreateCmd)
 	rootCmd.AddCommand(networkCmd)
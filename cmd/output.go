@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/gridscale/gscloud/render"
+)
+
+// outputOptions returns the options for rendering output in the format
+// selected by --output. --json is short for --output json.
+func outputOptions() render.Options {
+	opts := renderOpts
+	if rootFlags.json && opts.Output == "" {
+		opts.Output = "json"
+	}
+	return opts
+}
+
+// structuredOutput tells whether objects are printed as they are, e.g. as
+// JSON or through a template, rather than as a table.
+func structuredOutput() bool {
+	return !outputOptions().Tabular()
+}
+
+// quietOutput tells whether only IDs are to be printed instead of a table.
+func quietOutput() bool {
+	return rootFlags.quiet && !structuredOutput()
+}
+
+// renderOutput writes a table made of heading and rows, or objs, to w in the
+// format selected by --output. Every command prints its output this way so
+// that all formats behave the same for all commands. When run for one of
+// several projects, output is passed on to be merged instead.
+func renderOutput(w io.Writer, heading []string, rows [][]string, objs interface{}) error {
+	if path := os.Getenv(fanOutEnv); path != "" {
+		return writeFanOutOutput(path, heading, rows, objs)
+	}
+	return render.Render(w, heading, rows, objs, outputOptions())
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_OutputOptions(t *testing.T) {
+	defer func() {
+		rootFlags.json = false
+		rootFlags.quiet = false
+		renderOpts.Output = ""
+	}()
+
+	assert.False(t, structuredOutput())
+
+	rootFlags.json = true
+	assert.Equal(t, "json", outputOptions().Output)
+	assert.True(t, structuredOutput())
+
+	renderOpts.Output = "yaml"
+	assert.Equal(t, "yaml", outputOptions().Output)
+
+	rootFlags.json = false
+	rootFlags.quiet = true
+	renderOpts.Output = "csv"
+	assert.True(t, quietOutput())
+	renderOpts.Output = "go-template={{.}}"
+	assert.False(t, quietOutput())
+}
@@ -0,0 +1,596 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gridscale/gsclient-go/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+// liveObjects holds the objects found in a project. Firewalls are not part
+// of a manifest and only filled in by project copy.
+type liveObjects struct {
+	SSHKeys   []gsclient.Sshkey
+	Networks  []gsclient.Network
+	IPs       []gsclient.IP
+	Storages  []gsclient.Storage
+	Servers   []gsclient.Server
+	Firewalls []gsclient.Firewall
+}
+
+// getLiveObjects gets all objects of the kinds a manifest describes.
+func getLiveObjects(ctx context.Context) (liveObjects, error) {
+	var live liveObjects
+	var err error
+	live.SSHKeys, err = rt.SSHKeyOperator().GetSshkeyList(ctx)
+	if err != nil {
+		return live, err
+	}
+	live.Networks, err = rt.NetworkOperator().GetNetworkList(ctx)
+	if err != nil {
+		return live, err
+	}
+	live.IPs, err = rt.IPOperator().GetIPList(ctx)
+	if err != nil {
+		return live, err
+	}
+	live.Storages, err = rt.StorageOperator().GetStorageList(ctx)
+	if err != nil {
+		return live, err
+	}
+	live.Servers, err = rt.ServerOperator().GetServerList(ctx)
+	return live, err
+}
+
+// manifestKinds lists the kinds of objects in a manifest in the order they
+// are created. Objects only refer to objects of kinds earlier in the list.
+var manifestKinds = []string{"ssh-key", "network", "ip", "storage", "server"}
+
+// liveObject is the part of an object in a project that is needed to match
+// it with an object in a manifest.
+type liveObject struct {
+	ID     string
+	Name   string
+	Labels []string
+}
+
+func (l liveObjects) byKind(kind string) []liveObject {
+	var objs []liveObject
+	switch kind {
+	case "ssh-key":
+		for _, o := range l.SSHKeys {
+			objs = append(objs, liveObject{ID: o.Properties.ObjectUUID, Name: o.Properties.Name, Labels: o.Properties.Labels})
+		}
+	case "network":
+		for _, o := range l.Networks {
+			objs = append(objs, liveObject{ID: o.Properties.ObjectUUID, Name: o.Properties.Name, Labels: o.Properties.Labels})
+		}
+	case "ip":
+		for _, o := range l.IPs {
+			objs = append(objs, liveObject{ID: o.Properties.ObjectUUID, Name: ipName(o), Labels: o.Properties.Labels})
+		}
+	case "storage":
+		for _, o := range l.Storages {
+			objs = append(objs, liveObject{ID: o.Properties.ObjectUUID, Name: o.Properties.Name, Labels: o.Properties.Labels})
+		}
+	case "server":
+		for _, o := range l.Servers {
+			objs = append(objs, liveObject{ID: o.Properties.ObjectUUID, Name: o.Properties.Name, Labels: o.Properties.Labels})
+		}
+	case "firewall":
+		for _, o := range l.Firewalls {
+			objs = append(objs, liveObject{ID: o.Properties.ObjectUUID, Name: o.Properties.Name, Labels: o.Properties.Labels})
+		}
+	}
+	return objs
+}
+
+// ipName returns the name of an IP address object, or the address itself if
+// the object has no name.
+func ipName(ip gsclient.IP) string {
+	if ip.Properties.Name != "" {
+		return ip.Properties.Name
+	}
+	return ip.Properties.IP
+}
+
+// relation is a storage, network, or IP address a server is connected to.
+// ID is empty if the object is yet to be created.
+type relation struct {
+	Kind string
+	Name string
+	ID   string
+	Boot bool
+}
+
+func (r relation) String() string {
+	return r.Kind + " " + r.Name
+}
+
+// change is a single step of a plan that makes a project match a manifest.
+// Action is one of "create", "update", or "remove".
+type change struct {
+	Action   string   `json:"action"`
+	Kind     string   `json:"type"`
+	Name     string   `json:"name"`
+	ID       string   `json:"id,omitempty"`
+	Changes  []string `json:"changes,omitempty"`
+	Password string   `json:"password,omitempty"`
+
+	// object is the object in the manifest, for create and update.
+	object interface{}
+	// labels are the labels the object is given.
+	labels []string
+	// update tells whether the properties of the object, not just the
+	// relations of a server, are to be updated.
+	update bool
+	attach []relation
+	detach []relation
+	// running tells whether a server to be removed is powered on.
+	running bool
+}
+
+// planner makes a plan from a manifest and the objects in a project.
+type planner struct {
+	m    manifest
+	live liveObjects
+	// referenced holds the IDs of objects in the project that objects in
+	// the manifest refer to.
+	referenced map[string]bool
+}
+
+// makePlan returns the changes needed to make the objects in the project
+// match the manifest, in the order they are to be made. With prune, objects
+// that carry the managed-by label of the manifest but are not in it are
+// removed.
+func makePlan(m manifest, live liveObjects, prune bool) ([]change, error) {
+	p := planner{m: m, live: live, referenced: map[string]bool{}}
+	var plan []change
+	add := func(c *change, err error) error {
+		if err != nil {
+			return err
+		}
+		if c != nil {
+			plan = append(plan, *c)
+		}
+		return nil
+	}
+	for i := range m.SSHKeys {
+		if err := add(p.sshKey(&m.SSHKeys[i])); err != nil {
+			return nil, err
+		}
+	}
+	for i := range m.Networks {
+		if err := add(p.network(&m.Networks[i])); err != nil {
+			return nil, err
+		}
+	}
+	for i := range m.IPs {
+		if err := add(p.ip(&m.IPs[i])); err != nil {
+			return nil, err
+		}
+	}
+	for i := range m.Storages {
+		if err := add(p.storage(&m.Storages[i])); err != nil {
+			return nil, err
+		}
+	}
+	for i := range m.Servers {
+		if err := add(p.server(&m.Servers[i])); err != nil {
+			return nil, err
+		}
+	}
+	if prune {
+		plan = append(plan, p.prune()...)
+	}
+	return plan, nil
+}
+
+// find returns the object of kind in the project with the given name, or
+// nil if there is none.
+func (p *planner) find(kind, name string) (*liveObject, error) {
+	var found []liveObject
+	for _, o := range p.live.byKind(kind) {
+		if o.Name == name {
+			found = append(found, o)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &found[0], nil
+	}
+	var ids []string
+	for _, o := range found {
+		ids = append(ids, o.ID)
+	}
+	sort.Strings(ids)
+	return nil, fmt.Errorf("%s name %q is ambiguous, the project has: %s", kind, name, strings.Join(ids, ", "))
+}
+
+// inManifest tells whether the manifest has an object of kind called name.
+func (p *planner) inManifest(kind, name string) bool {
+	switch kind {
+	case "ssh-key":
+		for _, o := range p.m.SSHKeys {
+			if o.Name == name {
+				return true
+			}
+		}
+	case "network":
+		for _, o := range p.m.Networks {
+			if o.Name == name {
+				return true
+			}
+		}
+	case "ip":
+		for _, o := range p.m.IPs {
+			if o.Name == name {
+				return true
+			}
+		}
+	case "storage":
+		for _, o := range p.m.Storages {
+			if o.Name == name {
+				return true
+			}
+		}
+	case "server":
+		for _, o := range p.m.Servers {
+			if o.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reference returns the relation to the object of kind that ref refers to,
+// which is either the name of an object in the manifest, or the name or ID of
+// an object in the project.
+func (p *planner) reference(from, kind, ref string) (relation, error) {
+	if p.inManifest(kind, ref) {
+		obj, err := p.find(kind, ref)
+		if err != nil {
+			return relation{}, err
+		}
+		r := relation{Kind: kind, Name: ref}
+		if obj != nil {
+			r.ID = obj.ID
+		}
+		return r, nil
+	}
+	var objs []namedObject
+	for _, o := range p.live.byKind(kind) {
+		objs = append(objs, namedObject{ID: o.ID, Name: o.Name})
+	}
+	id, err := idForName(kind, ref, objs)
+	if err != nil {
+		return relation{}, fmt.Errorf("%s refers to unknown object: %w", from, err)
+	}
+	p.referenced[id] = true
+	name := ref
+	for _, o := range objs {
+		if o.ID == id {
+			name = o.Name
+		}
+	}
+	return relation{Kind: kind, Name: name, ID: id}, nil
+}
+
+// labels returns the labels an object is given: the labels in the manifest,
+// the managed-by label, and any other label reserved for gscloud the object
+// already has.
+func (p *planner) labels(want []string, have []string) []string {
+	labels := append([]string{}, want...)
+	labels = append(labels, p.m.managedBy())
+	for _, l := range have {
+		if strings.HasPrefix(l, "gscloud/") && !strings.HasPrefix(l, managedByLabel+"=") {
+			labels = append(labels, l)
+		}
+	}
+	return sortedSet(labels)
+}
+
+func sortedSet(vals []string) []string {
+	seen := map[string]bool{}
+	set := []string{}
+	for _, v := range vals {
+		if !seen[v] {
+			seen[v] = true
+			set = append(set, v)
+		}
+	}
+	sort.Strings(set)
+	return set
+}
+
+// diffLabels adds a description of the labels changed to c, if any.
+func diffLabels(c *change, have []string) {
+	if strings.Join(sortedSet(have), ",") != strings.Join(c.labels, ",") {
+		c.Changes = append(c.Changes, fmt.Sprintf("labels: %s → %s", strings.Join(sortedSet(have), ","), strings.Join(c.labels, ",")))
+		c.update = true
+	}
+}
+
+func diffValue(c *change, what string, have, want interface{}) {
+	if have != want {
+		c.Changes = append(c.Changes, fmt.Sprintf("%s: %v → %v", what, have, want))
+		c.update = true
+	}
+}
+
+// result returns c if there is anything to do.
+func (c *change) result() (*change, error) {
+	if c.Action == "update" && len(c.Changes) == 0 {
+		return nil, nil
+	}
+	return c, nil
+}
+
+func (p *planner) sshKey(k *manifestSSHKey) (*change, error) {
+	obj, err := p.find("ssh-key", k.Name)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return &change{Action: "create", Kind: "ssh-key", Name: k.Name, object: k, labels: p.labels(k.Labels, nil)}, nil
+	}
+	var live gsclient.Sshkey
+	for _, o := range p.live.SSHKeys {
+		if o.Properties.ObjectUUID == obj.ID {
+			live = o
+		}
+	}
+	c := &change{Action: "update", Kind: "ssh-key", Name: k.Name, ID: obj.ID, object: k, labels: p.labels(k.Labels, obj.Labels)}
+	if strings.TrimSpace(live.Properties.Sshkey) != strings.TrimSpace(k.Key) {
+		c.Changes = append(c.Changes, "key")
+		c.update = true
+	}
+	diffLabels(c, obj.Labels)
+	return c.result()
+}
+
+func (p *planner) network(n *manifestNetwork) (*change, error) {
+	obj, err := p.find("network", n.Name)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return &change{Action: "create", Kind: "network", Name: n.Name, object: n, labels: p.labels(n.Labels, nil)}, nil
+	}
+	var live gsclient.Network
+	for _, o := range p.live.Networks {
+		if o.Properties.ObjectUUID == obj.ID {
+			live = o
+		}
+	}
+	c := &change{Action: "update", Kind: "network", Name: n.Name, ID: obj.ID, object: n, labels: p.labels(n.Labels, obj.Labels)}
+	diffValue(c, "l2security", live.Properties.L2Security, n.L2Security)
+	diffLabels(c, obj.Labels)
+	return c.result()
+}
+
+func (p *planner) ip(ip *manifestIP) (*change, error) {
+	obj, err := p.find("ip", ip.Name)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return &change{Action: "create", Kind: "ip", Name: ip.Name, object: ip, labels: p.labels(ip.Labels, nil)}, nil
+	}
+	var live gsclient.IP
+	for _, o := range p.live.IPs {
+		if o.Properties.ObjectUUID == obj.ID {
+			live = o
+		}
+	}
+	if live.Properties.Family != ip.Family {
+		return nil, fmt.Errorf("ip %q: cannot change family from %d to %d", ip.Name, live.Properties.Family, ip.Family)
+	}
+	c := &change{Action: "update", Kind: "ip", Name: ip.Name, ID: obj.ID, object: ip, labels: p.labels(ip.Labels, obj.Labels)}
+	diffValue(c, "failover", live.Properties.Failover, ip.Failover)
+	if ip.ReverseDNS != "" {
+		diffValue(c, "reverse-dns", live.Properties.ReverseDNS, ip.ReverseDNS)
+	}
+	diffLabels(c, obj.Labels)
+	return c.result()
+}
+
+func (p *planner) storage(s *manifestStorage) (*change, error) {
+	for _, k := range s.SSHKeys {
+		if _, err := p.reference("storage "+strconv.Quote(s.Name), "ssh-key", k); err != nil {
+			return nil, err
+		}
+	}
+	obj, err := p.find("storage", s.Name)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return &change{Action: "create", Kind: "storage", Name: s.Name, object: s, labels: p.labels(s.Labels, nil)}, nil
+	}
+	var live gsclient.Storage
+	for _, o := range p.live.Storages {
+		if o.Properties.ObjectUUID == obj.ID {
+			live = o
+		}
+	}
+	if s.Capacity < live.Properties.Capacity {
+		return nil, fmt.Errorf("storage %q: cannot shrink from %d GB to %d GB", s.Name, live.Properties.Capacity, s.Capacity)
+	}
+	// The storage type is only sent if it changes.
+	want := *s
+	if want.Type == live.Properties.StorageType {
+		want.Type = ""
+	}
+	c := &change{Action: "update", Kind: "storage", Name: s.Name, ID: obj.ID, object: &want, labels: p.labels(s.Labels, obj.Labels)}
+	diffValue(c, "capacity", live.Properties.Capacity, s.Capacity)
+	if want.Type != "" {
+		diffValue(c, "type", live.Properties.StorageType, want.Type)
+	}
+	diffLabels(c, obj.Labels)
+	return c.result()
+}
+
+func (p *planner) server(s *manifestServer) (*change, error) {
+	from := "server " + strconv.Quote(s.Name)
+	var want []relation
+	for i, ref := range s.Storages {
+		r, err := p.reference(from, "storage", ref)
+		if err != nil {
+			return nil, err
+		}
+		r.Boot = i == 0
+		want = append(want, r)
+	}
+	for _, ref := range s.Networks {
+		r, err := p.reference(from, "network", ref)
+		if err != nil {
+			return nil, err
+		}
+		want = append(want, r)
+	}
+	for _, ref := range s.IPs {
+		r, err := p.reference(from, "ip", ref)
+		if err != nil {
+			return nil, err
+		}
+		want = append(want, r)
+	}
+
+	obj, err := p.find("server", s.Name)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		c := &change{Action: "create", Kind: "server", Name: s.Name, object: s, labels: p.labels(s.Labels, nil), attach: want}
+		for _, r := range want {
+			c.Changes = append(c.Changes, "attach "+r.String())
+		}
+		if s.Power != nil && *s.Power {
+			c.Changes = append(c.Changes, "power: on")
+		}
+		return c, nil
+	}
+	var live gsclient.Server
+	for _, o := range p.live.Servers {
+		if o.Properties.ObjectUUID == obj.ID {
+			live = o
+		}
+	}
+	c := &change{Action: "update", Kind: "server", Name: s.Name, ID: obj.ID, object: s, labels: p.labels(s.Labels, obj.Labels)}
+	diffValue(c, "cores", live.Properties.Cores, s.Cores)
+	diffValue(c, "memory", live.Properties.Memory, s.Memory)
+	diffLabels(c, obj.Labels)
+
+	var have []relation
+	for _, r := range live.Properties.Relations.Storages {
+		have = append(have, relation{Kind: "storage", Name: r.ObjectName, ID: r.ObjectUUID})
+	}
+	for _, r := range live.Properties.Relations.Networks {
+		have = append(have, relation{Kind: "network", Name: r.ObjectName, ID: r.ObjectUUID})
+	}
+	for _, r := range live.Properties.Relations.PublicIPs {
+		name := r.IP
+		for _, ip := range p.live.IPs {
+			if ip.Properties.ObjectUUID == r.ObjectUUID {
+				name = ipName(ip)
+			}
+		}
+		have = append(have, relation{Kind: "ip", Name: name, ID: r.ObjectUUID})
+	}
+	wanted := map[string]bool{}
+	for _, r := range want {
+		wanted[r.ID] = true
+	}
+	for _, r := range have {
+		if !wanted[r.ID] {
+			c.detach = append(c.detach, r)
+			c.Changes = append(c.Changes, "detach "+r.String())
+		}
+	}
+	for _, r := range want {
+		attached := false
+		for _, h := range have {
+			if r.ID != "" && h.ID == r.ID {
+				attached = true
+			}
+		}
+		if !attached {
+			c.attach = append(c.attach, r)
+			c.Changes = append(c.Changes, "attach "+r.String())
+		}
+	}
+	if s.Power != nil && *s.Power != live.Properties.Power {
+		c.Changes = append(c.Changes, fmt.Sprintf("power: %s → %s", onOff(live.Properties.Power), onOff(*s.Power)))
+	}
+	return c.result()
+}
+
+// prune returns the changes removing objects that carry the managed-by label
+// of the manifest but are not in it, in the reverse order of creation.
+func (p *planner) prune() []change {
+	var plan []change
+	for i := len(manifestKinds) - 1; i >= 0; i-- {
+		kind := manifestKinds[i]
+		for _, o := range p.live.byKind(kind) {
+			if p.inManifest(kind, o.Name) || p.referenced[o.ID] || !hasLabel(o.Labels, p.m.managedBy()) {
+				continue
+			}
+			if isProtected(o.Labels) {
+				log.Warnf("Not removing %s %s, it is protected by the label %s", kind, o.Name, protectLabel())
+				continue
+			}
+			c := change{Action: "remove", Kind: kind, Name: o.Name, ID: o.ID}
+			if kind == "server" {
+				for _, s := range p.live.Servers {
+					if s.Properties.ObjectUUID == o.ID {
+						c.running = s.Properties.Power
+					}
+				}
+			}
+			plan = append(plan, c)
+		}
+	}
+	return plan
+}
+
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+func onOff(power bool) string {
+	if power {
+		return "on"
+	}
+	return "off"
+}
+
+// writePlan writes the changes of a plan to w, one object per line followed
+// by the changes made to it. Objects to create are marked by "+", objects to
+// update by "~", and objects to remove by "-".
+func writePlan(w io.Writer, plan []change) {
+	marks := map[string]string{"create": "+", "update": "~", "remove": "-"}
+	for _, c := range plan {
+		fmt.Fprintf(w, "%s %s %s", marks[c.Action], c.Kind, c.Name)
+		if c.Action == "remove" {
+			fmt.Fprintf(w, " (%s)", c.ID)
+		}
+		fmt.Fprintln(w)
+		for _, d := range c.Changes {
+			fmt.Fprintf(w, "    %s\n", d)
+		}
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"sort"
 
-	"github.com/gridscale/gscloud/render"
 	"github.com/gridscale/gscloud/utils"
 	"github.com/spf13/cobra"
 )
@@ -50,22 +49,20 @@ var postgresReleasesCmd = &cobra.Command{
 		}
 		releases = unique(releases)
 		sort.Sort(sort.Reverse(utils.StringSorter(releases)))
-		if !rootFlags.json {
-			heading := []string{"releases"}
-			var rows [][]string
+		heading := []string{"releases"}
+		var rows [][]string
+		for _, rel := range releases {
+			rows = append(rows, []string{rel})
+		}
+		if quietOutput() {
 			for _, rel := range releases {
-				rows = append(rows, []string{rel})
-			}
-			render.AsTable(out, heading, rows, renderOpts)
-			if rootFlags.quiet {
-				for _, rel := range releases {
-					fmt.Println(rel)
-				}
-				return nil
+				fmt.Println(rel)
 			}
-
-		} else {
-			render.AsJSON(out, releases)
+			return nil
+		}
+		err = renderOutput(out, heading, rows, releases)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
 		}
 		fmt.Print(out)
 		return nil
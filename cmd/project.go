@@ -0,0 +1,461 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/gridscale/gscloud/runtime"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type projectCmdFlags struct {
+	from      string
+	to        string
+	s3Host    string
+	bucket    string
+	accessKey string
+	secretKey string
+}
+
+var (
+	projectFlags projectCmdFlags
+)
+
+var projectCmd = &cobra.Command{
+	Use:   "project",
+	Short: "Operations on projects",
+	Long:  `Operations on whole projects, as configured in the configuration file.`,
+}
+
+var projectCopyCmd = &cobra.Command{
+	Use:     "copy --from PROJECT --to PROJECT",
+	Example: `gscloud project copy --from staging --to prod --dry-run`,
+	Short:   "Copy objects of one project to another",
+	Long: `Recreate the SSH keys, networks, IP addresses, storages, servers, and firewalls of one project in another one. Both projects are taken from the configuration file.
+
+Objects are matched by name, as with gscloud-apply(1): objects missing in the target project are created, objects already there are updated to match the source project. Nothing is removed from either project. Every object copied is labeled gscloud/managed-by=gscloud. Objects of the same type must have distinct names in the source project.
+
+With **--dry-run**, the changes are printed like gscloud-diff(1) does, but not made.
+
+After copying, a table maps the objects of the source project to those in the target project. IP addresses cannot be copied themselves: the target project gets new addresses, named like the addresses in the source project unless they have a name.
+
+Storages are created from the template they were last initialized from, if the target project has a template of the same name, and are empty otherwise. Their contents are not copied: the storages in the target project stay empty or as initialized from the template. With **--bucket**, a snapshot of each storage in the source project is exported to an S3-compatible object storage, as PROJECT/STORAGE.gz, and removed again once exported. These exports are not imported into the target project; restore them into the target storages by hand. Access and secret key are taken from **--access-key** and **--secret-key**, or from the environment variables AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+
+# EXAMPLES
+
+Look at the changes, then make them:
+
+	$ gscloud project copy --from staging --to prod --dry-run
+	$ gscloud project copy --from staging --to prod
+
+Also export the contents of all storages, to be restored by hand:
+
+	$ gscloud project copy --from staging --to prod --s3-host gos3.io --bucket backups
+
+# ENVIRONMENT
+
+AWS_ACCESS_KEY_ID
+	Access key used if --access-key is not given
+
+AWS_SECRET_ACCESS_KEY
+	Secret key used if --secret-key is not given
+`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if projectFlags.from == projectFlags.to {
+			return errors.New("source and target project are the same")
+		}
+		if projectFlags.bucket == "" {
+			return nil
+		}
+		if projectFlags.s3Host == "" {
+			return errors.New("no S3 host given")
+		}
+		if projectFlags.accessKey == "" {
+			projectFlags.accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		}
+		if projectFlags.secretKey == "" {
+			projectFlags.secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		}
+		if projectFlags.accessKey == "" || projectFlags.secretKey == "" {
+			return errors.New("no access key or secret key given")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		type output struct {
+			Changes []change      `json:"changes"`
+			Mapping []copyMapping `json:"mapping"`
+		}
+
+		conf, err := runtime.ParseConfig()
+		if err != nil {
+			return NewError(cmd, "Could not read configuration", err)
+		}
+		src, err := runtime.NewRuntime(*conf, projectFlags.from, false)
+		if err != nil {
+			return NewError(cmd, "Could not use source project", err)
+		}
+		dst, err := runtime.NewRuntime(*conf, projectFlags.to, false)
+		if err != nil {
+			return NewError(cmd, "Could not use target project", err)
+		}
+		if sameProject(src.Project(), dst.Project()) {
+			return NewError(cmd, "Could not copy project", errors.New("source and target project have the same credentials"))
+		}
+
+		// The helpers shared with apply and export work on rt.
+		defer func(saved *runtime.Runtime) { rt = saved }(rt)
+		ctx := context.Background()
+
+		rt = src
+		srcLive, srcTemplates, err := getCopiedObjects(ctx)
+		if err != nil {
+			return NewError(cmd, "Could not get objects of source project", err)
+		}
+		types, _ := exportTypes(nil)
+		m, err := exportManifest(srcLive, srcTemplates, types)
+		if err != nil {
+			return NewError(cmd, "Could not copy project", err)
+		}
+
+		rt = dst
+		dstLive, dstTemplates, err := getCopiedObjects(ctx)
+		if err != nil {
+			return NewError(cmd, "Could not get objects of target project", err)
+		}
+		for _, name := range dropMissingTemplates(&m, dstTemplates) {
+			log.Warnf("Template of storage %s not found in project %s, storage is created empty", name, projectFlags.to)
+		}
+		plan, err := copyPlan(m, srcLive, dstLive)
+		if err != nil {
+			return NewError(cmd, "Could not plan changes", err)
+		}
+
+		if rootFlags.dryRun {
+			mapping := copyMappings(m, srcLive, dstLive, plan)
+			if structuredOutput() {
+				err = renderOutput(os.Stdout, nil, nil, output{Changes: plan, Mapping: mapping})
+				if err != nil {
+					return NewError(cmd, "Could not render output", err)
+				}
+				return nil
+			}
+			writePlan(os.Stdout, plan)
+			fmt.Println()
+			return writeMappings(cmd, mapping)
+		}
+
+		done := make([]change, 0, len(plan))
+		for _, c := range plan {
+			err = applyCopyChange(ctx, &c, plan)
+			if err != nil {
+				return NewError(cmd, fmt.Sprintf("Could not %s %s %s", c.Action, c.Kind, c.Name), err)
+			}
+			done = append(done, c)
+			if !structuredOutput() && !rootFlags.quiet {
+				writeApplied(c)
+			}
+		}
+		mapping := copyMappings(m, srcLive, dstLive, done)
+
+		if projectFlags.bucket != "" {
+			rt = src
+			for _, r := range mapping {
+				if r.Kind != "storage" {
+					continue
+				}
+				err = exportStorageContents(ctx, r.From, r.Export)
+				if err != nil {
+					return NewError(cmd, fmt.Sprintf("Could not export storage %s", r.Name), err)
+				}
+				if !rootFlags.quiet {
+					fmt.Fprintf(os.Stderr, "Exported storage %s to %s/%s. Its contents are not copied to %s; restore them by hand\n", r.Name, projectFlags.bucket, r.Export, projectFlags.to)
+				}
+			}
+		}
+
+		if structuredOutput() {
+			err = renderOutput(os.Stdout, nil, nil, output{Changes: done, Mapping: mapping})
+			if err != nil {
+				return NewError(cmd, "Could not render output", err)
+			}
+			return nil
+		}
+		if !rootFlags.quiet {
+			fmt.Println()
+			return writeMappings(cmd, mapping)
+		}
+		return nil
+	},
+}
+
+// copyKinds lists the kinds of objects project copy copies, in the order
+// they are created.
+var copyKinds = append([]string{"firewall"}, manifestKinds...)
+
+// copyMapping maps an object in the source project to the object in the
+// target project. To is empty if the object is yet to be created. Export is
+// the name of the file the contents of a storage are exported to.
+type copyMapping struct {
+	Kind   string `json:"type"`
+	Name   string `json:"name"`
+	From   string `json:"from"`
+	To     string `json:"to,omitempty"`
+	Export string `json:"export,omitempty"`
+}
+
+// copiedFirewall is a firewall to create or update in the target project.
+type copiedFirewall struct {
+	Name  string
+	Rules gsclient.FirewallRules
+}
+
+// sameProject tells whether two projects are the same, which is the case if
+// environment variables override the credentials of both.
+func sameProject(a, b runtime.ProjectEntry) bool {
+	return a.UserID == b.UserID && a.Token == b.Token && a.URL == b.URL
+}
+
+// getCopiedObjects gets the objects project copy copies, and the names of
+// templates by ID.
+func getCopiedObjects(ctx context.Context) (liveObjects, map[string]string, error) {
+	live, err := getLiveObjects(ctx)
+	if err != nil {
+		return live, nil, err
+	}
+	firewalls, err := rt.FirewallOperator().GetFirewallList(ctx)
+	if err != nil {
+		return live, nil, err
+	}
+	// Public firewall templates are the same in every project.
+	for _, fw := range firewalls {
+		if fw.Properties.Private {
+			live.Firewalls = append(live.Firewalls, fw)
+		}
+	}
+	list, err := rt.TemplateOperator().GetTemplateList(ctx)
+	if err != nil {
+		return live, nil, err
+	}
+	templates := map[string]string{}
+	for _, t := range list {
+		templates[t.Properties.ObjectUUID] = t.Properties.Name
+	}
+	return live, templates, nil
+}
+
+// dropMissingTemplates removes templates not among templates from the
+// storages in m, and returns the names of these storages.
+func dropMissingTemplates(m *manifest, templates map[string]string) []string {
+	names := map[string]bool{}
+	for _, name := range templates {
+		names[name] = true
+	}
+	var dropped []string
+	for i, s := range m.Storages {
+		if s.Template != "" && !names[s.Template] {
+			m.Storages[i].Template = ""
+			dropped = append(dropped, s.Name)
+		}
+	}
+	return dropped
+}
+
+// copyPlan returns the changes needed to copy the firewalls in src and the
+// objects in m to the project holding dst.
+func copyPlan(m manifest, src, dst liveObjects) ([]change, error) {
+	p := planner{m: m, live: dst, referenced: map[string]bool{}}
+	var plan []change
+	seen := map[string]bool{}
+	for _, fw := range src.Firewalls {
+		name := fw.Properties.Name
+		if seen[name] {
+			return nil, fmt.Errorf("more than one firewall is named %q, rename all but one to copy them", name)
+		}
+		seen[name] = true
+		obj, err := p.find("firewall", name)
+		if err != nil {
+			return nil, err
+		}
+		want := &copiedFirewall{Name: name, Rules: fw.Properties.Rules}
+		if obj == nil {
+			plan = append(plan, change{Action: "create", Kind: "firewall", Name: name, object: want, labels: p.labels(exportLabels(fw.Properties.Labels), nil)})
+			continue
+		}
+		c := &change{Action: "update", Kind: "firewall", Name: name, ID: obj.ID, object: want, labels: p.labels(exportLabels(fw.Properties.Labels), obj.Labels)}
+		for _, live := range dst.Firewalls {
+			if live.Properties.ObjectUUID == obj.ID && !sameFirewallRules(live.Properties.Rules, want.Rules) {
+				c.Changes = append(c.Changes, "rules")
+				c.update = true
+			}
+		}
+		diffLabels(c, obj.Labels)
+		if len(c.Changes) > 0 {
+			plan = append(plan, *c)
+		}
+	}
+	rest, err := makePlan(m, dst, false)
+	if err != nil {
+		return nil, err
+	}
+	return append(plan, rest...), nil
+}
+
+func sameFirewallRules(a, b gsclient.FirewallRules) bool {
+	sorted := func(r gsclient.FirewallRules) gsclient.FirewallRules {
+		return gsclient.FirewallRules{
+			RulesV4In:  sortedFirewallRules(r.RulesV4In),
+			RulesV4Out: sortedFirewallRules(r.RulesV4Out),
+			RulesV6In:  sortedFirewallRules(r.RulesV6In),
+			RulesV6Out: sortedFirewallRules(r.RulesV6Out),
+		}
+	}
+	return reflect.DeepEqual(sorted(a), sorted(b))
+}
+
+// copyMappings maps the objects copied from src to the objects in the target
+// project: those created or updated by plan, or else those in dst of the same
+// name.
+func copyMappings(m manifest, src, dst liveObjects, plan []change) []copyMapping {
+	p := planner{m: m}
+	var mapping []copyMapping
+	for _, kind := range copyKinds {
+		objs := src.byKind(kind)
+		sort.SliceStable(objs, func(i, j int) bool { return objs[i].Name < objs[j].Name })
+		for _, o := range objs {
+			if kind != "firewall" && !p.inManifest(kind, o.Name) {
+				continue
+			}
+			r := copyMapping{Kind: kind, Name: o.Name, From: o.ID}
+			for _, c := range plan {
+				if c.Kind == kind && c.Name == o.Name {
+					r.To = c.ID
+				}
+			}
+			if r.To == "" {
+				for _, d := range dst.byKind(kind) {
+					if d.Name == o.Name {
+						r.To = d.ID
+					}
+				}
+			}
+			if kind == "storage" && projectFlags.bucket != "" {
+				r.Export = projectFlags.from + "/" + o.Name + ".gz"
+			}
+			mapping = append(mapping, r)
+		}
+	}
+	return mapping
+}
+
+// writeMappings prints the mapping of objects as a table.
+func writeMappings(cmd *cobra.Command, mapping []copyMapping) error {
+	heading := []string{"type", "name", "source", "target"}
+	if projectFlags.bucket != "" {
+		heading = append(heading, "export")
+	}
+	var rows [][]string
+	for _, r := range mapping {
+		to := r.To
+		if to == "" {
+			to = "(new)"
+		}
+		row := []string{r.Kind, r.Name, r.From, to}
+		if projectFlags.bucket != "" {
+			row = append(row, r.Export)
+		}
+		rows = append(rows, row)
+	}
+	err := renderOutput(os.Stdout, heading, rows, nil)
+	if err != nil {
+		return NewError(cmd, "Could not render output", err)
+	}
+	return nil
+}
+
+// applyCopyChange makes a change of a copy plan.
+func applyCopyChange(ctx context.Context, c *change, plan []change) error {
+	fw, ok := c.object.(*copiedFirewall)
+	if !ok {
+		return applyChange(ctx, c, plan)
+	}
+	firewallOp := rt.FirewallOperator()
+	if c.Action == "create" {
+		resp, err := firewallOp.CreateFirewall(ctx, gsclient.FirewallCreateRequest{
+			Name:   fw.Name,
+			Labels: c.labels,
+			Rules:  fw.Rules,
+		})
+		c.ID = resp.ObjectUUID
+		return err
+	}
+	req := gsclient.FirewallUpdateRequest{Labels: &c.labels}
+	if c.update {
+		req.Rules = &fw.Rules
+	}
+	return firewallOp.UpdateFirewall(ctx, c.ID, req)
+}
+
+// exportStorageContents takes a snapshot of a storage and exports it to the
+// file called name in the bucket given by --bucket. The snapshot is removed
+// afterwards. Nothing is imported into the target project.
+func exportStorageContents(ctx context.Context, storageID, name string) error {
+	snapshotOp := rt.StorageSnapshotOperator()
+	snapshot, err := snapshotOp.CreateStorageSnapshot(ctx, storageID, gsclient.StorageSnapshotCreateRequest{
+		Name: "project-copy",
+	})
+	if err != nil {
+		return err
+	}
+	err = waitForSnapshotActive(ctx, snapshotOp, storageID, snapshot.ObjectUUID)
+	if err == nil {
+		err = exportSnapshot(ctx, snapshotOp, storageID, snapshot.ObjectUUID, name)
+	}
+	deleteErr := snapshotOp.DeleteStorageSnapshot(ctx, storageID, snapshot.ObjectUUID)
+	if err != nil {
+		return err
+	}
+	if deleteErr != nil {
+		return fmt.Errorf("could not remove snapshot %s: %w", snapshot.ObjectUUID, deleteErr)
+	}
+	return nil
+}
+
+// exportSnapshot exports a snapshot to the file called name in the bucket
+// given by --bucket.
+func exportSnapshot(ctx context.Context, snapshotOp gsclient.StorageSnapshotOperator, storageID, snapshotID, name string) error {
+	return snapshotOp.ExportStorageSnapshotToS3(ctx, storageID, snapshotID, gsclient.StorageSnapshotExportToS3Request{
+		S3auth: gsclient.S3auth{
+			Host:      projectFlags.s3Host,
+			AccessKey: projectFlags.accessKey,
+			SecretKey: projectFlags.secretKey,
+		},
+		S3data: gsclient.S3data{
+			Host:     projectFlags.s3Host,
+			Bucket:   projectFlags.bucket,
+			Filename: name,
+			Private:  true,
+		},
+	})
+}
+
+func init() {
+	projectCopyCmd.Flags().StringVar(&projectFlags.from, "from", "", "Name of the project to copy from")
+	projectCopyCmd.MarkFlagRequired("from")
+	projectCopyCmd.Flags().StringVar(&projectFlags.to, "to", "", "Name of the project to copy to")
+	projectCopyCmd.MarkFlagRequired("to")
+	projectCopyCmd.Flags().StringVar(&projectFlags.s3Host, "s3-host", "", "Host name of the S3-compatible object storage storages are exported to")
+	projectCopyCmd.Flags().StringVar(&projectFlags.bucket, "bucket", "", "Name of the bucket storages are exported to")
+	projectCopyCmd.Flags().StringVar(&projectFlags.accessKey, "access-key", "", "Access key of the object storage")
+	projectCopyCmd.Flags().StringVar(&projectFlags.secretKey, "secret-key", "", "Secret key of the object storage")
+
+	projectCmd.AddCommand(projectCopyCmd)
+	rootCmd.AddCommand(projectCmd)
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/gridscale/gscloud/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CopyPlan(t *testing.T) {
+	ssh := gsclient.FirewallRules{RulesV4In: []gsclient.FirewallRuleProperties{{Order: 0, Protocol: gsclient.TCPTransport, DstPort: "22", Action: "accept"}}}
+	web := gsclient.FirewallRules{RulesV4In: []gsclient.FirewallRuleProperties{{Order: 0, Protocol: gsclient.TCPTransport, DstPort: "443", Action: "accept"}}}
+	src := testExportLive
+	src.Firewalls = []gsclient.Firewall{
+		{Properties: gsclient.FirewallProperties{ObjectUUID: "f1", Name: "ssh", Rules: ssh, Private: true}},
+		{Properties: gsclient.FirewallProperties{ObjectUUID: "f2", Name: "web", Rules: web, Private: true}},
+	}
+	dst := liveObjects{
+		Networks: []gsclient.Network{
+			{Properties: gsclient.NetworkProperties{ObjectUUID: "n9", Name: "Public Network", PublicNet: true}},
+			{Properties: gsclient.NetworkProperties{ObjectUUID: "n8", Name: "backend", Labels: []string{"gscloud/managed-by=gscloud"}}},
+		},
+		Firewalls: []gsclient.Firewall{
+			{Properties: gsclient.FirewallProperties{ObjectUUID: "f9", Name: "ssh", Rules: web, Labels: []string{"gscloud/managed-by=gscloud"}, Private: true}},
+		},
+	}
+
+	types, _ := exportTypes(nil)
+	m, err := exportManifest(src, map[string]string{"t1": "Ubuntu"}, types)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"web-root"}, dropMissingTemplates(&m, map[string]string{"t2": "Debian"}))
+	assert.Equal(t, "", m.Storages[1].Template)
+
+	plan, err := copyPlan(m, src, dst)
+	assert.Nil(t, err)
+	var steps []string
+	for _, c := range plan {
+		steps = append(steps, c.Action+" "+c.Kind+" "+c.Name)
+	}
+	assert.Equal(t, []string{
+		"update firewall ssh",
+		"create firewall web",
+		"create ssh-key admin",
+		"create ip 192.0.2.1",
+		"create storage web-data",
+		"create storage web-root",
+		"create server web",
+	}, steps)
+	assert.Equal(t, []string{"rules"}, plan[0].Changes)
+
+	plan[1].ID = "f8"
+	mapping := copyMappings(m, src, dst, plan)
+	assert.Equal(t, []copyMapping{
+		{Kind: "firewall", Name: "ssh", From: "f1", To: "f9"},
+		{Kind: "firewall", Name: "web", From: "f2", To: "f8"},
+		{Kind: "ssh-key", Name: "admin", From: "k1"},
+		{Kind: "network", Name: "backend", From: "n1", To: "n8"},
+		{Kind: "ip", Name: "192.0.2.1", From: "i1"},
+		{Kind: "storage", Name: "web-data", From: "s2"},
+		{Kind: "storage", Name: "web-root", From: "s1"},
+		{Kind: "server", Name: "web", From: "v1"},
+	}, mapping)
+
+	src.Firewalls = append(src.Firewalls, gsclient.Firewall{Properties: gsclient.FirewallProperties{ObjectUUID: "f3", Name: "web", Private: true}})
+	_, err = copyPlan(m, src, dst)
+	assert.NotNil(t, err)
+}
+
+func Test_SameProject(t *testing.T) {
+	a := runtime.ProjectEntry{Name: "staging", UserID: "u1", Token: "t1"}
+	b := runtime.ProjectEntry{Name: "prod", UserID: "u2", Token: "t2"}
+	assert.False(t, sameProject(a, b))
+	b.UserID, b.Token = "u1", "t1"
+	assert.True(t, sameProject(a, b))
+}
+
+type copySnapshotOp struct {
+	gsclient.StorageSnapshotOperator
+	calls []string
+}
+
+func (o *copySnapshotOp) CreateStorageSnapshot(ctx context.Context, storageID string, body gsclient.StorageSnapshotCreateRequest) (gsclient.StorageSnapshotCreateResponse, error) {
+	o.calls = append(o.calls, "create")
+	return gsclient.StorageSnapshotCreateResponse{ObjectUUID: "snap"}, nil
+}
+
+func (o *copySnapshotOp) GetStorageSnapshot(ctx context.Context, storageID, snapshotID string) (gsclient.StorageSnapshot, error) {
+	o.calls = append(o.calls, "get")
+	var snapshot gsclient.StorageSnapshot
+	snapshot.Properties.Status = "active"
+	return snapshot, nil
+}
+
+func (o *copySnapshotOp) ExportStorageSnapshotToS3(ctx context.Context, storageID, snapshotID string, body gsclient.StorageSnapshotExportToS3Request) error {
+	o.calls = append(o.calls, "export "+body.S3data.Filename)
+	return nil
+}
+
+func (o *copySnapshotOp) DeleteStorageSnapshot(ctx context.Context, storageID, snapshotID string) error {
+	o.calls = append(o.calls, "delete "+snapshotID)
+	return nil
+}
+
+func Test_ExportStorageContents(t *testing.T) {
+	// The snapshot is waited for even without --wait.
+	rootFlags.wait = false
+	rt, _ = runtime.NewTestRuntime()
+	op := &copySnapshotOp{}
+	rt.SetStorageSnapshotOperator(op)
+	err := exportStorageContents(context.Background(), "disk", "staging/disk.gz")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"create", "get", "export staging/disk.gz", "delete snap"}, op.calls)
+}
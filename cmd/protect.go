@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gridscale/gscloud/runtime"
+	"github.com/spf13/cobra"
+)
+
+var protectCmd = &cobra.Command{
+	Use:     "protect TYPE ID",
+	Example: `gscloud protect storage db-data`,
+	Short:   "Protect an object from being removed",
+	Long: fmt.Sprintf(`Protect an object from being removed by gscloud, by giving it the protection label, %s unless protect_label in the configuration file says otherwise.
+
+gscloud refuses to remove protected objects, or to shrink protected storages. This applies to the rm commands, also to objects removed with **server rm --include-related**, and to objects removed by **apply --prune**. To remove a protected object, remove the label with gscloud-unprotect(1) first.
+
+TYPE is one of %s. Objects are given by ID or name, IP addresses also by address.
+
+# EXAMPLES
+
+Protect a storage and check that it carries the label:
+
+	$ gscloud protect storage db-data
+	$ gscloud storage ls --label %s
+`, runtime.DefaultProtectLabel, strings.Join(labeledKindNames(), ", "), runtime.DefaultProtectLabel),
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setProtection(cmd, args[0], args[1], true)
+	},
+}
+
+var unprotectCmd = &cobra.Command{
+	Use:     "unprotect TYPE ID",
+	Example: `gscloud unprotect storage db-data`,
+	Short:   "Allow removing a protected object",
+	Long: fmt.Sprintf(`Remove the protection label from an object, so that it can be removed again. See gscloud-protect(1).
+
+TYPE is one of %s.`, strings.Join(labeledKindNames(), ", ")),
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setProtection(cmd, args[0], args[1], false)
+	},
+}
+
+// setProtection adds the protection label to an object, or removes it.
+func setProtection(cmd *cobra.Command, kindName, val string, protect bool) error {
+	kind, err := lookupLabeledKind(kindName)
+	if err != nil {
+		return NewError(cmd, "Invalid object type", err)
+	}
+	ctx := context.Background()
+	id, err := kind.id(ctx, val)
+	if err != nil {
+		return NewError(cmd, "Could not find "+kindName, err)
+	}
+	obj, err := kind.get(ctx, id)
+	if err != nil {
+		return NewError(cmd, "Could not get "+kindName, err)
+	}
+	var labels []string
+	var changed bool
+	if protect {
+		labels, changed = addLabel(obj.Labels, protectLabel())
+	} else {
+		labels, changed = removeLabel(obj.Labels, protectLabel())
+	}
+	if changed {
+		err = kind.set(ctx, id, labels)
+		if err != nil {
+			return NewError(cmd, "Could not change labels", err)
+		}
+	}
+	if protect {
+		fmt.Fprintf(os.Stderr, "Protected %s\n", id)
+	} else {
+		fmt.Fprintf(os.Stderr, "Unprotected %s\n", id)
+	}
+	return nil
+}
+
+// protectLabel returns the label protecting objects from being removed.
+func protectLabel() string {
+	if rt == nil {
+		return runtime.DefaultProtectLabel
+	}
+	return rt.ProtectLabel()
+}
+
+// isProtected tells whether an object with labels is protected from being
+// removed.
+func isProtected(labels []string) bool {
+	return hasLabel(labels, protectLabel())
+}
+
+// checkProtected returns an error if an object with labels is protected from
+// being removed.
+func checkProtected(kind, name string, labels []string) error {
+	if isProtected(labels) {
+		return fmt.Errorf("%s %s is protected by the label %s. Remove the label with gscloud unprotect first", kind, name, protectLabel())
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(protectCmd, unprotectCmd)
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/gridscale/gscloud/runtime"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ProtectLabel(t *testing.T) {
+	labels, changed := addLabel([]string{"env=prod"}, "gscloud/protect=true")
+	assert.True(t, changed)
+	assert.Equal(t, []string{"env=prod", "gscloud/protect=true"}, labels)
+	assert.True(t, isProtected(labels))
+	assert.NotNil(t, checkProtected("storage", "db-data", labels))
+
+	_, changed = addLabel(labels, "gscloud/protect=true")
+	assert.False(t, changed)
+
+	labels, changed = removeLabel(labels, "gscloud/protect=true")
+	assert.True(t, changed)
+	assert.Equal(t, []string{"env=prod"}, labels)
+	assert.False(t, isProtected(labels))
+	assert.Nil(t, checkProtected("storage", "db-data", labels))
+
+	_, changed = removeLabel(labels, "gscloud/protect=true")
+	assert.False(t, changed)
+}
+
+func Test_MakePlanProtected(t *testing.T) {
+	m, _ := parseManifest([]byte("name: shop\n"))
+	live := liveObjects{
+		Storages: []gsclient.Storage{
+			{Properties: gsclient.StorageProperties{ObjectUUID: "s1", Name: "db-data", Labels: []string{"gscloud/managed-by=shop", "gscloud/protect=true"}}},
+			{Properties: gsclient.StorageProperties{ObjectUUID: "s2", Name: "cache", Labels: []string{"gscloud/managed-by=shop"}}},
+		},
+	}
+	plan, err := makePlan(m, live, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []change{{Action: "remove", Kind: "storage", Name: "cache", ID: "s2"}}, plan)
+}
+
+type protectedSSHKeyOp struct {
+	gsclient.SSHKeyOperator
+	deleted bool
+}
+
+func (o *protectedSSHKeyOp) GetSshkey(ctx context.Context, id string) (gsclient.Sshkey, error) {
+	var key gsclient.Sshkey
+	key.Properties.ObjectUUID = id
+	key.Properties.Name = "deploy"
+	key.Properties.Labels = []string{"gscloud/protect=true"}
+	return key, nil
+}
+
+func (o *protectedSSHKeyOp) DeleteSshkey(ctx context.Context, id string) error {
+	o.deleted = true
+	return nil
+}
+
+func Test_SSHKeyRmProtected(t *testing.T) {
+	rt, _ = runtime.NewTestRuntime()
+	op := &protectedSSHKeyOp{}
+	rt.SetSSHKeyOperator(op)
+	err := sshKeyRmCmd.RunE(new(cobra.Command), []string{"4a6b2c1d-8e9f-4a3b-9c7d-1e2f3a4b5c6d"})
+	assert.NotNil(t, err)
+	assert.False(t, op.deleted)
+}
@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// namedObject is the ID and name of an object, as needed to look up the ID
+// of an object given by name.
+type namedObject struct {
+	ID   string
+	Name string
+}
+
+// resolveID returns the ID of the object given by val, which is either an ID
+// or the name of an object. IDs are returned as they are. Names are looked up
+// in the objects returned by list; kind names the type of object in errors.
+func resolveID(kind, val string, list func() ([]namedObject, error)) (string, error) {
+	if _, err := uuid.Parse(val); err == nil {
+		return val, nil
+	}
+	objs, err := list()
+	if err != nil {
+		return "", err
+	}
+	return idForName(kind, val, objs)
+}
+
+// idForName returns the ID of the only object in objs named name, or of the
+// object whose ID is name. An error listing all candidates is returned if
+// more than one object has that name.
+func idForName(kind, name string, objs []namedObject) (string, error) {
+	var candidates []string
+	for _, obj := range objs {
+		if obj.ID == name {
+			return obj.ID, nil
+		}
+		if obj.Name == name {
+			candidates = append(candidates, obj.ID)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("no %s named %q", kind, name)
+	case 1:
+		return candidates[0], nil
+	}
+	sort.Strings(candidates)
+	return "", fmt.Errorf("%s name %q is ambiguous, use one of the IDs: %s", kind, name, strings.Join(candidates, ", "))
+}
+
+// idForServer returns the ID of a server given by ID or name.
+func idForServer(ctx context.Context, val string) (string, error) {
+	return resolveID("server", val, func() ([]namedObject, error) {
+		servers, err := rt.ServerOperator().GetServerList(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var objs []namedObject
+		for _, s := range servers {
+			objs = append(objs, namedObject{ID: s.Properties.ObjectUUID, Name: s.Properties.Name})
+		}
+		return objs, nil
+	})
+}
+
+// idForStorage returns the ID of a storage given by ID or name.
+func idForStorage(ctx context.Context, val string) (string, error) {
+	return resolveID("storage", val, func() ([]namedObject, error) {
+		storages, err := rt.StorageOperator().GetStorageList(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var objs []namedObject
+		for _, s := range storages {
+			objs = append(objs, namedObject{ID: s.Properties.ObjectUUID, Name: s.Properties.Name})
+		}
+		return objs, nil
+	})
+}
+
+// idForSnapshot returns the ID of a snapshot of the given storage given by
+// ID or name.
+func idForSnapshot(ctx context.Context, storageID, val string) (string, error) {
+	return resolveID("snapshot", val, func() ([]namedObject, error) {
+		snapshots, err := rt.StorageSnapshotOperator().GetStorageSnapshotList(ctx, storageID)
+		if err != nil {
+			return nil, err
+		}
+		var objs []namedObject
+		for _, s := range snapshots {
+			objs = append(objs, namedObject{ID: s.Properties.ObjectUUID, Name: s.Properties.Name})
+		}
+		return objs, nil
+	})
+}
+
+// idForSnapshotSchedule returns the ID of a snapshot schedule of the given
+// storage given by ID or name.
+func idForSnapshotSchedule(ctx context.Context, storageID, val string) (string, error) {
+	return resolveID("snapshot schedule", val, func() ([]namedObject, error) {
+		schedules, err := rt.StorageSnapshotScheduleOperator().GetStorageSnapshotScheduleList(ctx, storageID)
+		if err != nil {
+			return nil, err
+		}
+		var objs []namedObject
+		for _, s := range schedules {
+			objs = append(objs, namedObject{ID: s.Properties.ObjectUUID, Name: s.Properties.Name})
+		}
+		return objs, nil
+	})
+}
+
+// idForTemplate returns the ID of a template given by ID or name.
+func idForTemplate(ctx context.Context, val string) (string, error) {
+	return resolveID("template", val, func() ([]namedObject, error) {
+		templates, err := rt.TemplateOperator().GetTemplateList(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var objs []namedObject
+		for _, t := range templates {
+			objs = append(objs, namedObject{ID: t.Properties.ObjectUUID, Name: t.Properties.Name})
+		}
+		return objs, nil
+	})
+}
+
+// idForNetwork returns the ID of a network given by ID or name.
+func idForNetwork(ctx context.Context, val string) (string, error) {
+	return resolveID("network", val, func() ([]namedObject, error) {
+		networks, err := rt.NetworkOperator().GetNetworkList(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var objs []namedObject
+		for _, n := range networks {
+			objs = append(objs, namedObject{ID: n.Properties.ObjectUUID, Name: n.Properties.Name})
+		}
+		return objs, nil
+	})
+}
+
+// idForISOImage returns the ID of an ISO image given by ID or name.
+func idForISOImage(ctx context.Context, val string) (string, error) {
+	return resolveID("ISO image", val, func() ([]namedObject, error) {
+		images, err := rt.ISOImageOperator().GetISOImageList(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var objs []namedObject
+		for _, i := range images {
+			objs = append(objs, namedObject{ID: i.Properties.ObjectUUID, Name: i.Properties.Name})
+		}
+		return objs, nil
+	})
+}
+
+// idForSSHKey returns the ID of an SSH key given by ID or name.
+func idForSSHKey(ctx context.Context, val string) (string, error) {
+	return resolveID("SSH key", val, func() ([]namedObject, error) {
+		keys, err := rt.SSHKeyOperator().GetSshkeyList(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var objs []namedObject
+		for _, k := range keys {
+			objs = append(objs, namedObject{ID: k.Properties.ObjectUUID, Name: k.Properties.Name})
+		}
+		return objs, nil
+	})
+}
+
+// idForLoadBalancer returns the ID of a load balancer given by ID or name.
+func idForLoadBalancer(ctx context.Context, val string) (string, error) {
+	return resolveID("load balancer", val, func() ([]namedObject, error) {
+		loadBalancers, err := rt.LoadBalancerOperator().GetLoadBalancerList(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var objs []namedObject
+		for _, lb := range loadBalancers {
+			objs = append(objs, namedObject{ID: lb.Properties.ObjectUUID, Name: lb.Properties.Name})
+		}
+		return objs, nil
+	})
+}
+
+// idForFirewall returns the ID of a firewall given by ID or name.
+func idForFirewall(ctx context.Context, val string) (string, error) {
+	return resolveID("firewall", val, func() ([]namedObject, error) {
+		firewalls, err := rt.FirewallOperator().GetFirewallList(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var objs []namedObject
+		for _, fw := range firewalls {
+			objs = append(objs, namedObject{ID: fw.Properties.ObjectUUID, Name: fw.Properties.Name})
+		}
+		return objs, nil
+	})
+}
+
+// idForIP returns the ID of an IP address given by ID, address, or name.
+func idForIP(ctx context.Context, val string) (string, error) {
+	if addr := net.ParseIP(val); addr != nil {
+		return idForAddress(ctx, addr, rt.IPOperator())
+	}
+	return resolveID("IP address", val, func() ([]namedObject, error) {
+		addrs, err := rt.IPOperator().GetIPList(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var objs []namedObject
+		for _, a := range addrs {
+			objs = append(objs, namedObject{ID: a.Properties.ObjectUUID, Name: ipName(a)})
+		}
+		return objs, nil
+	})
+}
+
+// idForPaaSService returns the ID of a platform service, such as a
+// Kubernetes cluster, given by ID or name.
+func idForPaaSService(ctx context.Context, val string) (string, error) {
+	return resolveID("platform service", val, func() ([]namedObject, error) {
+		services, err := rt.PaaSOperator().GetPaaSServiceList(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var objs []namedObject
+		for _, s := range services {
+			objs = append(objs, namedObject{ID: s.Properties.ObjectUUID, Name: s.Properties.Name})
+		}
+		return objs, nil
+	})
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/gridscale/gscloud/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+var testNamedObjects = []namedObject{
+	{ID: "37d53278-8e5f-47e1-a63f-54513e4b4d53", Name: "web-1"},
+	{ID: "b0dd8d71-8f8d-46c1-8985-ce4b6dc37ecc", Name: "web"},
+	{ID: "690de890-13c0-4e76-8a01-e10ba8786e53", Name: "web"},
+}
+
+func Test_IDForName(t *testing.T) {
+	id, err := idForName("server", "web-1", testNamedObjects)
+	assert.Nil(t, err)
+	assert.Equal(t, "37d53278-8e5f-47e1-a63f-54513e4b4d53", id)
+
+	id, err = idForName("server", "690de890-13c0-4e76-8a01-e10ba8786e53", testNamedObjects)
+	assert.Nil(t, err)
+	assert.Equal(t, "690de890-13c0-4e76-8a01-e10ba8786e53", id)
+
+	_, err = idForName("server", "db", testNamedObjects)
+	assert.EqualError(t, err, `no server named "db"`)
+
+	_, err = idForName("server", "web", testNamedObjects)
+	assert.EqualError(t, err, `server name "web" is ambiguous, use one of the IDs: 690de890-13c0-4e76-8a01-e10ba8786e53, b0dd8d71-8f8d-46c1-8985-ce4b6dc37ecc`)
+}
+
+func Test_ResolveIDSkipsLookupForUUID(t *testing.T) {
+	list := func() ([]namedObject, error) {
+		return nil, errors.New("unexpected lookup")
+	}
+	id, err := resolveID("server", "37d53278-8e5f-47e1-a63f-54513e4b4d53", list)
+	assert.Nil(t, err)
+	assert.Equal(t, "37d53278-8e5f-47e1-a63f-54513e4b4d53", id)
+
+	_, err = resolveID("server", "web-1", list)
+	assert.EqualError(t, err, "unexpected lookup")
+}
+
+type resolveIPOp struct {
+	gsclient.IPOperator
+}
+
+func (o *resolveIPOp) GetIPList(ctx context.Context) ([]gsclient.IP, error) {
+	return []gsclient.IP{
+		{Properties: gsclient.IPProperties{ObjectUUID: "c1a4a1a2-4d5e-4b8a-9f3c-1d2e3f4a5b6c", Name: "web-1", IP: "185.201.147.176"}},
+		{Properties: gsclient.IPProperties{ObjectUUID: "6f1c8a8e-93b5-4d0f-8b7e-2a3b4c5d6e7f", IP: "2a06:2380:0:1::6"}},
+	}, nil
+}
+
+func Test_IDForIP(t *testing.T) {
+	ctx := context.Background()
+	rt, _ = runtime.NewTestRuntime()
+	rt.SetIPOperator(&resolveIPOp{})
+
+	for _, val := range []string{"web-1", "185.201.147.176"} {
+		id, err := idForIP(ctx, val)
+		assert.Nil(t, err)
+		assert.Equal(t, "c1a4a1a2-4d5e-4b8a-9f3c-1d2e3f4a5b6c", id)
+	}
+	id, err := idForIP(ctx, "2a06:2380:0:1:0:0:0:6")
+	assert.Nil(t, err)
+	assert.Equal(t, "6f1c8a8e-93b5-4d0f-8b7e-2a3b4c5d6e7f", id)
+
+	_, err = idForIP(ctx, "db-1")
+	assert.EqualError(t, err, `no IP address named "db-1"`)
+}
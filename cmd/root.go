@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/gridscale/gscloud/render"
 	"github.com/gridscale/gscloud/runtime"
@@ -23,18 +25,25 @@ type Error struct {
 
 func (e *Error) Error() string { return e.What + ": " + e.Err.Error() }
 
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error { return e.Err }
+
 // NewError constructs a new error.
 func NewError(cmd *cobra.Command, what string, err error) *Error {
 	return &Error{Command: cmd, What: what, Err: err}
 }
 
 type rootCmdFlags struct {
-	configFile string
-	project    string
-	account    string // Deprecated
-	json       bool
-	quiet      bool
-	debug      bool
+	configFile  string
+	project     string
+	account     string // Deprecated
+	allProjects bool
+	dryRun      bool
+	json        bool
+	quiet       bool
+	debug       bool
+	wait        bool
+	timeout     time.Duration
 }
 
 var (
@@ -53,9 +62,21 @@ var rootCmd = &cobra.Command{
 
 Commands are given usually in the form of 'gscloud object verb'. For example, to list all servers you would do 'gscloud server ls'. Likewise, to list all storages you would do 'gscloud storage ls'.
 
-Output, if any, is usually in the form of a table. You can pass --json to print output as JSON if you wish to do so.
+Objects are referred to by ID or by name. A name has to be unique among the objects of its type, otherwise the IDs of all objects with that name are listed and the command fails.
+
+Output, if any, is usually in the form of a table. Use --output to choose a different format: "json", "yaml", "csv", "tsv", "go-template=TEMPLATE" to format output with a Go template, or "jsonpath=EXPRESSION" to print parts of the output selected by a JSONPath expression. Templates and JSONPath expressions see objects the way they appear in JSON output. --json is short for --output json.
+
+All ls commands accept --filter COLUMN=PATTERN, --label PATTERN, --sort-by COLUMN, and --reverse to select and order the objects listed. Patterns may contain the wildcards '*', '?', and '[...]'. These options apply to table, --quiet, and --json output alike.
+
+Tables, CSV, and TSV output of ls commands can be given different columns with --columns. Besides the columns shown by default, any property of the objects can be chosen by its name in JSON output, e.g. "location_uuid". Properties ending in "_name" or "_uuid" can be given without that suffix, e.g. "location". --output wide, or the column "wide", adds commonly used columns.
+
+Commands that turn servers on or off, or remove objects, accept several IDs or names, --all for all objects, or select objects by --label PATTERN and --name PATTERN. They act on up to --parallel objects at a time, 1 by default, and report failures per object. The command fails if it failed for any object.
+
+Commands run for several projects at once when --project is given a list of projects separated by commas, or with --all-projects. The command runs for all of them concurrently, and the results are merged: tables get a leading PROJECT column, JSON objects a "project" key, and other output lines are prefixed by the project. The command fails if it failed for any project.
 
-To configure access to your projects via the API a YAML configuration file is used. See gscloud-make-config(1) and --config for more.
+With --dry-run, nothing is changed. Commands that would create, change, or remove objects print the API requests they would send instead: a table of HTTP methods, paths, and request bodies, or a list of these with --json. Requests that only read objects are sent as usual, and --wait is ignored. Objects that would be created are given made-up IDs, such as 00000000-0000-4000-8000-000000000001, to show how later requests refer to them. Commands that do not use the API, such as the config commands, fail with --dry-run.
+
+To configure access to your projects via the API a YAML configuration file is used. See gscloud-make-config(1), gscloud-config(1), and --config for more. API tokens can be kept out of that file, in the keyring of the operating system, an environment variable, a separate file, or a password manager. See gscloud-config-migrate-secrets(1).
 
 # FILES
 
@@ -69,6 +90,7 @@ gscloud returns zero exit code on success, non-zero on failure. Following exit c
     2. Reading the configuration file failed.
     3. The configuration could not be parsed.
     4. The account specified does not exist in the configuration file.
+    5. Waiting for an object timed out (see --wait and --timeout).
 
 # EXAMPLES
 
@@ -80,11 +102,55 @@ List all servers available:
     37d53278-8e5f-47e1-a63f-54513e4b4d53  test-1  1     1    2020-11-17T08:48:22+01:00  off
     b0dd8d71-8f8d-46c1-8985-ce4b6dc37ecc  test-2  1     1    2020-11-20T11:44:58+01:00  off
 
-Power all servers on:
+List servers that are powered on, largest first:
+
+    $ gscloud server ls --filter power=on --sort-by mem --reverse
+
+Power all servers on, four at a time:
+
+    $ gscloud server on --all --parallel 4
+
+Remove the storages of the dev environment:
+
+    $ gscloud storage rm --label env=dev
+
+Power a server on by name:
+
+    $ gscloud server on test-1
+
+Power a server on and wait until it is running:
+
+    $ gscloud --wait server on 37d53278-8e5f-47e1-a63f-54513e4b4d53
+
+List servers with their location and labels:
+
+    $ gscloud server ls --columns name,power,location,labels
+
+See what removing a server with its storages and IP addresses would do:
+
+    $ gscloud --dry-run server rm --include-related --force test-1
+
+    METHOD  PATH                                                         BODY
+    PATCH   /objects/servers/37d53278-8e5f-47e1-a63f-54513e4b4d53/power  {"power":false}
+    DELETE  /objects/servers/37d53278-8e5f-47e1-a63f-54513e4b4d53
+    DELETE  /objects/storages/479b7973-376a-4b23-98fc-50e94131a6e3
+    DELETE  /objects/ips/8e0c2ba1-4e4c-43d8-a8ba-3f5c2a7b8d60
 
-    $ gscloud server ls --quiet | while read s; do
-        gscloud server on $s
-    done
+List the servers of two projects:
+
+    $ gscloud --project staging,prod server ls
+
+Export the list of storages for a spreadsheet:
+
+    $ gscloud storage ls -o csv > storages.csv
+
+Print the names of all servers:
+
+    $ gscloud server ls -o 'jsonpath={[*].server.name}'
+
+Print name and capacity of all storages:
+
+    $ gscloud storage ls -o 'go-template={{range .}}{{.storage.name}}: {{.storage.capacity}} GB{{"\n"}}{{end}}'
 
 Get the list of storages as JSON:
 
@@ -145,6 +211,38 @@ GRIDSCALE_URL
 
 `, runtime.ConfigPathWithoutUser()),
 	DisableAutoGenTag: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if rt != nil {
+			err := applyProjectDefaults(cmd, rt.Project().Defaults)
+			if err != nil {
+				return err
+			}
+		}
+		// Reject unknown output formats before anything is changed.
+		err := outputOptions().Validate()
+		if err != nil {
+			return err
+		}
+		if len(fanOutProjects) > 0 {
+			// Run the command once for each project instead.
+			cmd.Run = nil
+			cmd.RunE = func(cmd *cobra.Command, args []string) error {
+				return fanOut(cmd, fanOutProjects)
+			}
+		} else if rootFlags.dryRun && cmd.RunE != nil {
+			// Commands without a runtime, such as the config commands,
+			// change local files. Those changes cannot be previewed.
+			if rt == nil {
+				return fmt.Errorf("%s does not support --dry-run", cmd.CommandPath())
+			}
+			run := cmd.RunE
+			rt.EnableDryRun()
+			cmd.RunE = func(cmd *cobra.Command, args []string) error {
+				return dryRun(cmd, args, run)
+			}
+		}
+		return nil
+	},
 }
 
 // Execute runs the subcommand. Execute adds all child commands to the root
@@ -153,10 +251,19 @@ GRIDSCALE_URL
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
+// exitCode returns the exit code for an error returned by a command.
+func exitCode(err error) int {
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return exitCodeTimeout
+	}
+	return 1
+}
+
 func init() {
 	// Register following initializers only when we are not running tests.
 	if !utils.UnderTest() {
@@ -174,13 +281,18 @@ func init() {
 	}
 
 	rootCmd.PersistentFlags().StringVar(&rootFlags.configFile, "config", "", "Path to configuration file")
-	rootCmd.PersistentFlags().StringVar(&rootFlags.project, "project", "", "Specify the project used. Overrides GRIDSCALE_PROJECT environment variable")
+	rootCmd.PersistentFlags().StringVar(&rootFlags.project, "project", "", "Specify the project used, or several projects separated by commas. Overrides GRIDSCALE_PROJECT environment variable")
+	rootCmd.PersistentFlags().BoolVar(&rootFlags.allProjects, "all-projects", false, "Run the command for all projects in the configuration file")
 	rootCmd.PersistentFlags().StringVar(&rootFlags.account, "account", project, "Specify the project used. Is overriden by --project. Deprecated")
 	rootCmd.PersistentFlags().MarkDeprecated("account", "it will be removed in a future update. Use --project instead")
-	rootCmd.PersistentFlags().BoolVarP(&rootFlags.json, "json", "j", false, "Print JSON to stdout instead of a table")
+	rootCmd.PersistentFlags().BoolVarP(&rootFlags.json, "json", "j", false, "Print JSON to stdout instead of a table. Short for --output json")
+	rootCmd.PersistentFlags().StringVarP(&renderOpts.Output, "output", "o", "", "Output format. One of "+strings.Join(render.Formats, ", "))
 	rootCmd.PersistentFlags().BoolVar(&renderOpts.NoHeader, "noheading", false, "Do not print column headings")
 	rootCmd.PersistentFlags().BoolVarP(&rootFlags.quiet, "quiet", "q", false, "Print only object IDs")
 	rootCmd.PersistentFlags().BoolVar(&rootFlags.debug, "debug", false, "Debug mode")
+	rootCmd.PersistentFlags().BoolVar(&rootFlags.dryRun, "dry-run", false, "Print the API requests that would change objects instead of sending them")
+	rootCmd.PersistentFlags().BoolVar(&rootFlags.wait, "wait", false, "Wait until asynchronous operations are finished")
+	rootCmd.PersistentFlags().DurationVar(&rootFlags.timeout, "timeout", 10*time.Minute, "Maximum time to wait with --wait")
 	rootCmd.PersistentFlags().BoolP("help", "h", false, "Print usage")
 }
 
@@ -221,7 +333,7 @@ func initConfig() {
 
 // initRuntime initializes the client for a given account.
 func initRuntime() {
-	if CommandWithoutConfig(os.Args) {
+	if CommandWithoutConfig(os.Args) || commandWithoutRuntime(os.Args) {
 		return
 	}
 	conf, err := runtime.ParseConfig()
@@ -230,8 +342,17 @@ func initRuntime() {
 		os.Exit(3)
 	}
 
-	if rootFlags.project == "" && rootFlags.account != "" {
-		rootFlags.project = rootFlags.account
+	if rootFlags.project == "" {
+		rootFlags.project = defaultProject(conf)
+	}
+
+	fanOutProjects, err = selectProjects(*conf, rootFlags.project, rootFlags.allProjects)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(4)
+	}
+	if fanOutProjects != nil {
+		return
 	}
 
 	theRuntime, err := runtime.NewRuntime(*conf, rootFlags.project, CommandWithoutConfig(os.Args))
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -33,3 +35,21 @@ func Test_CommandWithoutConfig(t *testing.T) {
 		assert.Equal(t, test.Expected, CommandWithoutConfig(test.Args))
 	}
 }
+
+func Test_DryRunWithoutRuntime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := []byte("projects:\n- name: prod\n  userId: u\n  token: t\n")
+	assert.Nil(t, ioutil.WriteFile(path, data, 0600))
+
+	savedRt, savedFlags := rt, rootFlags
+	defer func() {
+		rt, rootFlags = savedRt, savedFlags
+		rootCmd.SetArgs(nil)
+	}()
+	rt = nil
+	rootCmd.SetArgs([]string{"--config", path, "--dry-run", "config", "rm", "prod"})
+	err := rootCmd.Execute()
+	assert.EqualError(t, err, "gscloud config rm does not support --dry-run")
+	written, _ := ioutil.ReadFile(path)
+	assert.Equal(t, data, written)
+}
@@ -9,9 +9,7 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/gridscale/gsclient-go/v3"
-	"github.com/gridscale/gscloud/render"
 	"github.com/sethvargo/go-password/password"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -43,6 +41,8 @@ var serverCmd = &cobra.Command{
 	Long:  `List, create, or remove servers.`,
 }
 
+var serverWideColumns = []string{"id", "name", "core", "mem", "changed", "power", "status", "location", "availability_zone", "labels"}
+
 func serverLsCmdRun(cmd *cobra.Command, args []string) error {
 	serverOp := rt.ServerOperator()
 	ctx := context.Background()
@@ -52,34 +52,48 @@ func serverLsCmdRun(cmd *cobra.Command, args []string) error {
 		return NewError(cmd, "Could not get list of servers", err)
 	}
 	var rows [][]string
-	if !rootFlags.json {
-		heading := []string{"id", "name", "core", "mem", "changed", "power"}
-		for _, server := range servers {
-			power := "off"
-			if server.Properties.Power {
-				power = "on"
-			}
-			fill := [][]string{
-				{
-					server.Properties.ObjectUUID,
-					server.Properties.Name,
-					strconv.FormatInt(int64(server.Properties.Cores), 10),
-					strconv.FormatInt(int64(server.Properties.Memory), 10),
-					server.Properties.ChangeTime.Local().Format(time.RFC3339),
-					power,
-				},
-			}
-			rows = append(rows, fill...)
+	var labels [][]string
+	heading := []string{"id", "name", "core", "mem", "changed", "power"}
+	for _, server := range servers {
+		power := "off"
+		if server.Properties.Power {
+			power = "on"
 		}
-		if rootFlags.quiet {
-			for _, info := range rows {
-				fmt.Println(info[0])
-			}
-		} else {
-			render.AsTable(out, heading, rows, renderOpts)
+		fill := [][]string{
+			{
+				server.Properties.ObjectUUID,
+				server.Properties.Name,
+				strconv.FormatInt(int64(server.Properties.Cores), 10),
+				strconv.FormatInt(int64(server.Properties.Memory), 10),
+				server.Properties.ChangeTime.Local().Format(time.RFC3339),
+				power,
+			},
 		}
-	} else {
-		render.AsJSON(out, servers)
+		rows = append(rows, fill...)
+		labels = append(labels, server.Properties.Labels)
+	}
+	order, err := listOrder(heading, rows, labels, servers)
+	if err != nil {
+		return NewError(cmd, "Could not list servers", err)
+	}
+	rows = selectRows(rows, order)
+	selected := make([]gsclient.Server, 0, len(order))
+	for _, i := range order {
+		selected = append(selected, servers[i])
+	}
+	if quietOutput() {
+		for _, info := range rows {
+			fmt.Println(info[0])
+		}
+		return nil
+	}
+	heading, rows, err = listColumns(heading, rows, selected, serverWideColumns)
+	if err != nil {
+		return NewError(cmd, "Could not list servers", err)
+	}
+	err = renderOutput(out, heading, rows, selected)
+	if err != nil {
+		return NewError(cmd, "Could not render output", err)
 	}
 	fmt.Print(out)
 	return nil
@@ -96,61 +110,73 @@ var serverLsCmd = &cobra.Command{
 func serverOnCmdRun(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 	serverOp := rt.ServerOperator()
-	err := serverOp.StartServer(ctx, args[0])
+	id, err := idForServer(ctx, args[0])
+	if err != nil {
+		return NewError(cmd, "Could not find server", err)
+	}
+	err = serverOp.StartServer(ctx, id)
 	if err != nil {
 		return NewError(cmd, "Failed starting server", err)
 	}
+	err = waitForServerPower(ctx, serverOp, id, true)
+	if err != nil {
+		return NewError(cmd, "Waiting for server failed", err)
+	}
+	fmt.Fprintf(os.Stderr, "Turned on %s\n", id)
 	return nil
 }
 
 var serverOnCmd = &cobra.Command{
-	Use:   "on ID",
-	Short: "Turn server on",
-	Args:  cobra.ExactArgs(1),
-	RunE:  serverOnCmdRun,
+	Use:     "on [flags] [ID...]",
+	Example: `gscloud server on --label env=dev --parallel 4`,
+	Short:   "Turn server on",
+	RunE:    serverOnCmdRun,
 }
 
 func serverOffCmdRun(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 	serverOp := rt.ServerOperator()
+	id, err := idForServer(ctx, args[0])
+	if err != nil {
+		return NewError(cmd, "Could not find server", err)
+	}
 	if serverFlags.forceShutdown {
-		err := serverOp.StopServer(ctx, args[0])
+		err = serverOp.StopServer(ctx, id)
 		if err != nil {
 			return NewError(cmd, "Failed stopping server", err)
 		}
 	} else {
-		err := serverOp.ShutdownServer(ctx, args[0])
+		err = serverOp.ShutdownServer(ctx, id)
 		if err != nil {
 			return NewError(cmd, "Failed shutting down server", err)
 		}
 	}
+	err = waitForServerPower(ctx, serverOp, id, false)
+	if err != nil {
+		return NewError(cmd, "Waiting for server failed", err)
+	}
+	fmt.Fprintf(os.Stderr, "Turned off %s\n", id)
 	return nil
 }
 
 var serverOffCmd = &cobra.Command{
-	Use:   "off [flags] ID",
-	Short: "Turn server off via ACPI",
-	Args:  cobra.ExactArgs(1),
-	RunE:  serverOffCmdRun,
+	Use:     "off [flags] [ID...]",
+	Example: `gscloud server off test-1 test-2`,
+	Short:   "Turn server off via ACPI",
+	RunE:    serverOffCmdRun,
 }
 
 func serverRmCmdRun(cmd *cobra.Command, args []string) error {
 	serverOp := rt.ServerOperator()
 	ctx := context.Background()
-	id := args[0]
+	id, err := idForServer(ctx, args[0])
+	if err != nil {
+		return NewError(cmd, "Could not find server", err)
+	}
 	s, err := serverOp.GetServer(ctx, id)
 	if err != nil {
 		return NewError(cmd, "Look up server failed", err)
 	}
-	if serverFlags.force {
-		if s.Properties.Power {
-			err := serverOp.StopServer(ctx, args[0])
-			if err != nil {
-				return NewError(cmd, "Failed stopping server", err)
-			}
-		}
-	}
-
 	var storages []gsclient.ServerStorageRelationProperties
 	var ipAddrs []gsclient.ServerIPRelationProperties
 	if serverFlags.includeRelated {
@@ -164,7 +190,7 @@ func serverRmCmdRun(cmd *cobra.Command, args []string) error {
 			return NewError(cmd, "Could not get assigned IP addresses", err)
 		}
 
-		if !rootFlags.quiet {
+		if !rootFlags.quiet && !structuredOutput() {
 			var rows [][]string
 			heading := []string{"id", "type", "name"}
 			rows = append(rows, []string{
@@ -193,7 +219,10 @@ func serverRmCmdRun(cmd *cobra.Command, args []string) error {
 				rows = append(rows, fill...)
 			}
 
-			render.AsTable(out, heading, rows, renderOpts)
+			err = renderOutput(out, heading, rows, nil)
+			if err != nil {
+				return NewError(cmd, "Could not render output", err)
+			}
 			fmt.Print(out)
 		}
 
@@ -208,6 +237,24 @@ func serverRmCmdRun(cmd *cobra.Command, args []string) error {
 			return nil
 		}
 	}
+	err = checkServerProtected(ctx, s, storages, ipAddrs)
+	if err != nil {
+		return NewError(cmd, "Removing server failed", err)
+	}
+	err = confirmRemoval(func() (removal, error) {
+		return describeServer(s, serverFlags.includeRelated), nil
+	})
+	if err != nil {
+		return NewError(cmd, "Removing server failed", err)
+	}
+	if serverFlags.force {
+		if s.Properties.Power {
+			err := serverOp.StopServer(ctx, id)
+			if err != nil {
+				return NewError(cmd, "Failed stopping server", err)
+			}
+		}
+	}
 	err = serverOp.DeleteServer(ctx, id)
 	if err != nil {
 		return NewError(cmd, "Deleting server failed", err)
@@ -236,21 +283,89 @@ func serverRmCmdRun(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// describeServer describes a server to be removed. With related, its
+// storages and IP addresses are removed as well.
+func describeServer(s gsclient.Server, related bool) removal {
+	power := "off"
+	if s.Properties.Power {
+		power = "on"
+	}
+	var storages, networks, addrs []string
+	for _, rel := range s.Properties.Relations.Storages {
+		storages = append(storages, fmt.Sprintf("%s (%d GB)", rel.ObjectName, rel.Capacity))
+	}
+	for _, rel := range s.Properties.Relations.Networks {
+		networks = append(networks, rel.ObjectName)
+	}
+	for _, rel := range s.Properties.Relations.PublicIPs {
+		addrs = append(addrs, rel.IP)
+	}
+	r := removal{
+		kind: "server",
+		name: s.Properties.Name,
+		id:   s.Properties.ObjectUUID,
+		details: []string{
+			fmt.Sprintf("size: %d cores, %d GB memory", s.Properties.Cores, s.Properties.Memory),
+			"power: " + power,
+			"storages: " + relatedNames(storages),
+			"networks: " + relatedNames(networks),
+			"IP addresses: " + relatedNames(addrs),
+		},
+	}
+	if related {
+		r.details = append(r.details, "Storages and IP addresses are removed as well")
+	}
+	return r
+}
+
+// checkServerProtected returns an error if a server or any of the storages
+// and IP addresses to be removed with it is protected.
+func checkServerProtected(ctx context.Context, s gsclient.Server, storages []gsclient.ServerStorageRelationProperties, ipAddrs []gsclient.ServerIPRelationProperties) error {
+	err := checkProtected("server", s.Properties.Name, s.Properties.Labels)
+	if err != nil {
+		return err
+	}
+	for _, rel := range storages {
+		storage, err := rt.StorageOperator().GetStorage(ctx, rel.ObjectUUID)
+		if err != nil {
+			return err
+		}
+		err = checkProtected("storage", storage.Properties.Name, storage.Properties.Labels)
+		if err != nil {
+			return err
+		}
+	}
+	for _, rel := range ipAddrs {
+		addr, err := rt.IPOperator().GetIP(ctx, rel.ObjectUUID)
+		if err != nil {
+			return err
+		}
+		err = checkProtected("IP address", addr.Properties.IP, addr.Properties.Labels)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 var serverRmCmd = &cobra.Command{
-	Use:     "rm [flags] ID",
+	Use:     "rm [flags] [ID...]",
 	Aliases: []string{"remove"},
 	Short:   "Remove server",
 	Long: `**gscloud server rm** removes an existing server from a project.
 
 With the **--include-related** option, you can delete all referenced storages and assigned IP addresses, if any. By default, storages and IP addresses are not removed to prevent important data from being deleted.
 
+Before the server is removed, its name, size, and relations are shown and you are asked for confirmation. **--yes** removes the server without asking. See confirm_destructive in gscloud-config(1) for when confirmation is needed.
+
+Protected servers are not removed, and neither are servers with protected storages or IP addresses with **--include-related**. See gscloud-protect(1).
+
 # EXAMPLES
 
 Remove a server including storages and IP addresses:
 
 	$ gscloud server rm --include-related --force 37d53278-8e5f-47e1-a63f-54513e4b4d53
 `,
-	Args: cobra.ExactArgs(1),
 	RunE: serverRmCmdRun,
 }
 
@@ -293,17 +408,9 @@ To create a server without any storage just omit --with-template flag:
 		}
 
 		if serverFlags.template != "" {
-			// Might be an ID or a name
-			id, err := uuid.Parse(serverFlags.template)
-			if err == nil {
-				templateID = id.String()
-			} else {
-				templateOp := rt.TemplateOperator()
-				template, err := templateOp.GetTemplateByName(ctx, serverFlags.template)
-				if err != nil {
-					return NewError(cmd, "Cannot create server", err)
-				}
-				templateID = template.Properties.ObjectUUID
+			templateID, err = idForTemplate(ctx, serverFlags.template)
+			if err != nil {
+				return NewError(cmd, "Cannot create server", err)
 			}
 		}
 
@@ -315,6 +422,7 @@ To create a server without any storage just omit --with-template flag:
 			HardwareProfile: profile,
 			AvailablityZone: serverFlags.availabilityZone,
 			AutoRecovery:    &serverFlags.autoRecovery,
+			Labels:          createLabels(),
 		}
 		if serverFlags.userDataBase64 != "" {
 			serverCreateRequest.UserData = &serverFlags.userDataBase64
@@ -347,6 +455,7 @@ To create a server without any storage just omit --with-template flag:
 					PasswordType: gsclient.PlainPasswordType,
 					Hostname:     serverFlags.hostName,
 				},
+				Labels: createLabels(),
 			})
 			if err != nil {
 				return NewError(cmd, "Creating storage failed", err)
@@ -365,7 +474,9 @@ To create a server without any storage just omit --with-template flag:
 			}
 			cleanupServer = false
 
-			if !rootFlags.json {
+			// Print the password before waiting, so it is not lost if
+			// waiting fails.
+			if !structuredOutput() {
 				fmt.Println("Server created:", server.ObjectUUID)
 				fmt.Println("Storage created:", storage.ObjectUUID)
 				fmt.Println("Password:", password)
@@ -375,11 +486,23 @@ To create a server without any storage just omit --with-template flag:
 					Storage:  storage.ObjectUUID,
 					Password: password,
 				}
-				render.AsJSON(os.Stdout, jsonOutput)
+				err = renderOutput(os.Stdout, nil, nil, jsonOutput)
+				if err != nil {
+					return NewError(cmd, "Could not render output", err)
+				}
+			}
+
+			err = waitForStorage(ctx, storageOp, storage.ObjectUUID)
+			if err != nil {
+				return NewError(cmd, "Waiting for storage failed", err)
 			}
 		}
 
 		cleanupServer = false
+		err = waitForServer(ctx, serverOp, server.ObjectUUID)
+		if err != nil {
+			return NewError(cmd, "Waiting for server failed", err)
+		}
 		return nil
 	},
 }
@@ -401,14 +524,22 @@ var serverSetCmd = &cobra.Command{
 		if serverFlags.userDataBase64 != "" {
 			serverUpdateRequest.UserData = &serverFlags.userDataBase64
 		}
-		err := serverOp.UpdateServer(
+		id, err := idForServer(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find server", err)
+		}
+		err = serverOp.UpdateServer(
 			ctx,
-			args[0],
+			id,
 			serverUpdateRequest,
 		)
 		if err != nil {
 			return NewError(cmd, "Failed setting property", err)
 		}
+		err = waitForServer(ctx, serverOp, id)
+		if err != nil {
+			return NewError(cmd, "Waiting for server failed", err)
+		}
 		return nil
 	},
 }
@@ -424,8 +555,11 @@ var serverAssignCmd = &cobra.Command{
 		var addrID string
 		var err error
 
-		serverID = args[0]
 		ctx := context.Background()
+		serverID, err = idForServer(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find server", err)
+		}
 		ipOp := rt.IPOperator()
 		addr := net.ParseIP(args[1])
 		if addr != nil {
@@ -436,7 +570,7 @@ var serverAssignCmd = &cobra.Command{
 		} else {
 			addrID = args[1]
 		}
-		err = rt.Client().LinkIP(ctx, serverID, addrID)
+		err = rt.ServerIPRelationOperator().LinkIP(ctx, serverID, addrID)
 		if err != nil {
 			return NewError(cmd, "Could not assign IP address", err)
 		}
@@ -462,8 +596,11 @@ Only list request IDs of a server (in case you need to tell suport what happened
 `,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		serverID := args[0]
 		ctx := context.Background()
+		serverID, err := idForServer(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find server", err)
+		}
 		serverOp := rt.ServerOperator()
 		events, err := serverOp.GetServerEventList(ctx, serverID)
 		if err != nil {
@@ -471,33 +608,31 @@ Only list request IDs of a server (in case you need to tell suport what happened
 		}
 
 		out := new(bytes.Buffer)
-		if rootFlags.json {
-			render.AsJSON(out, events)
-
-		} else {
-			if rootFlags.quiet {
-				for _, event := range events {
-					fmt.Println(event.Properties.RequestUUID)
-				}
-			} else {
-				var rows [][]string
-				heading := []string{
-					"time", "request id", "request type", "details", "initiator",
-				}
-				for _, event := range events {
-					fill := [][]string{
-						{
-							event.Properties.Timestamp.Local().Format(time.RFC3339),
-							event.Properties.RequestUUID,
-							event.Properties.RequestType,
-							event.Properties.Change,
-							event.Properties.Initiator,
-						},
-					}
-					rows = append(rows, fill...)
-				}
-				render.AsTable(out, heading, rows, renderOpts)
+		if quietOutput() {
+			for _, event := range events {
+				fmt.Println(event.Properties.RequestUUID)
+			}
+			return nil
+		}
+		var rows [][]string
+		heading := []string{
+			"time", "request id", "request type", "details", "initiator",
+		}
+		for _, event := range events {
+			fill := [][]string{
+				{
+					event.Properties.Timestamp.Local().Format(time.RFC3339),
+					event.Properties.RequestUUID,
+					event.Properties.RequestType,
+					event.Properties.Change,
+					event.Properties.Initiator,
+				},
 			}
+			rows = append(rows, fill...)
+		}
+		err = renderOutput(out, heading, rows, events)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
 		}
 		fmt.Print(out)
 
@@ -507,6 +642,8 @@ Only list request IDs of a server (in case you need to tell suport what happened
 
 func init() {
 	serverOffCmd.Flags().BoolVarP(&serverFlags.forceShutdown, "force", "f", false, "Force shutdown (no ACPI)")
+	addBulkFlags(serverOnCmd, "server")
+	addBulkFlags(serverOffCmd, "server")
 
 	serverCreateCmd.Flags().IntVar(&serverFlags.memory, "mem", 1, "Memory (GB)")
 	serverCreateCmd.Flags().IntVar(&serverFlags.cores, "cores", 1, "No. of cores")
@@ -518,6 +655,7 @@ func init() {
 	serverCreateCmd.Flags().StringVar(&serverFlags.availabilityZone, "availability-zone", "", "Availability zone. One of \"a\", \"b\", \"c\" (default \"\")")
 	serverCreateCmd.Flags().BoolVar(&serverFlags.autoRecovery, "auto-recovery", true, "Whether to restart in case of errors")
 	serverCreateCmd.Flags().StringVar(&serverFlags.userDataBase64, "user-data-base64", "", "For system configuration on first boot. May contain cloud-config data or shell scripting, encoded as base64 string. Supported tools are cloud-init, Cloudbase-init, and Ignition.")
+	addCreateLabelFlag(serverCreateCmd)
 
 	serverSetCmd.Flags().IntVar(&serverFlags.memory, "mem", 0, "Memory (GB)")
 	serverSetCmd.Flags().IntVar(&serverFlags.cores, "cores", 0, "No. of cores")
@@ -526,6 +664,10 @@ func init() {
 
 	serverRmCmd.Flags().BoolVarP(&serverFlags.includeRelated, "include-related", "i", false, "Remove all objects currently related to this server, not just the server")
 	serverRmCmd.Flags().BoolVarP(&serverFlags.force, "force", "f", false, "Force a destructive operation")
+	addConfirmFlags(serverRmCmd)
+	addBulkFlags(serverRmCmd, "server")
+
+	addListFlags(serverLsCmd)
 
 	serverCmd.AddCommand(serverLsCmd, serverOnCmd, serverOffCmd, serverRmCmd, serverCreateCmd, serverSetCmd, serverAssignCmd, serverEventsCmd)
 	rootCmd.AddCommand(serverCmd)
@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/spf13/cobra"
+)
+
+type serverRelationCmdFlags struct {
+	bootDevice       bool
+	ordering         int
+	l3security       []string
+	firewallTemplate string
+	rulesFile        string
+}
+
+var (
+	serverRelationFlags serverRelationCmdFlags
+)
+
+var serverAttachStorageCmd = &cobra.Command{
+	Use:     "attach-storage [flags] SERVER STORAGE",
+	Example: `gscloud server attach-storage --boot 37d53278-8e5f-47e1-a63f-54513e4b4d53 b3ec341c-1732-45b3-bc45-9a7fcebb363e`,
+	Short:   "Attach a storage",
+	Long:    `Attach an existing storage to a server. With --boot the server boots from the storage.`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		serverID, err := idForServer(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find server", err)
+		}
+		storageID, err := idForStorage(ctx, args[1])
+		if err != nil {
+			return NewError(cmd, "Could not find storage", err)
+		}
+		op := rt.ServerStorageRelationOperator()
+		err = op.CreateServerStorage(ctx, serverID, gsclient.ServerStorageRelationCreateRequest{
+			ObjectUUID: storageID,
+			BootDevice: serverRelationFlags.bootDevice,
+		})
+		if err != nil {
+			return NewError(cmd, powerOffHint(ctx, "Attaching storage failed", serverID), err)
+		}
+		return nil
+	},
+}
+
+var serverDetachStorageCmd = &cobra.Command{
+	Use:     "detach-storage SERVER STORAGE",
+	Example: `gscloud server detach-storage 37d53278-8e5f-47e1-a63f-54513e4b4d53 b3ec341c-1732-45b3-bc45-9a7fcebb363e`,
+	Short:   "Detach a storage",
+	Long:    `Detach a storage from a server. The storage itself is not removed.`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		serverID, err := idForServer(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find server", err)
+		}
+		storageID, err := idForStorage(ctx, args[1])
+		if err != nil {
+			return NewError(cmd, "Could not find storage", err)
+		}
+		op := rt.ServerStorageRelationOperator()
+		err = op.DeleteServerStorage(ctx, serverID, storageID)
+		if err != nil {
+			return NewError(cmd, powerOffHint(ctx, "Detaching storage failed", serverID), err)
+		}
+		return nil
+	},
+}
+
+var serverAttachISOCmd = &cobra.Command{
+	Use:     "attach-iso SERVER ISO_IMAGE",
+	Example: `gscloud server attach-iso 37d53278-8e5f-47e1-a63f-54513e4b4d53 4b3a7c2e-1a8e-4bde-9c61-3f5ad1e0e8a7`,
+	Short:   "Attach an ISO image",
+	Long:    `Insert an existing ISO image into the virtual CD-ROM drive of a server.`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		serverID, err := idForServer(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find server", err)
+		}
+		imageID, err := idForISOImage(ctx, args[1])
+		if err != nil {
+			return NewError(cmd, "Could not find ISO image", err)
+		}
+		op := rt.ServerIsoImageRelationOperator()
+		err = op.CreateServerIsoImage(ctx, serverID, gsclient.ServerIsoImageRelationCreateRequest{
+			ObjectUUID: imageID,
+		})
+		if err != nil {
+			return NewError(cmd, powerOffHint(ctx, "Attaching ISO image failed", serverID), err)
+		}
+		return nil
+	},
+}
+
+var serverDetachISOCmd = &cobra.Command{
+	Use:     "detach-iso SERVER ISO_IMAGE",
+	Example: `gscloud server detach-iso 37d53278-8e5f-47e1-a63f-54513e4b4d53 4b3a7c2e-1a8e-4bde-9c61-3f5ad1e0e8a7`,
+	Short:   "Detach an ISO image",
+	Long:    `Eject an ISO image from a server. The ISO image itself is not removed.`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		serverID, err := idForServer(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find server", err)
+		}
+		imageID, err := idForISOImage(ctx, args[1])
+		if err != nil {
+			return NewError(cmd, "Could not find ISO image", err)
+		}
+		op := rt.ServerIsoImageRelationOperator()
+		err = op.DeleteServerIsoImage(ctx, serverID, imageID)
+		if err != nil {
+			return NewError(cmd, powerOffHint(ctx, "Detaching ISO image failed", serverID), err)
+		}
+		return nil
+	},
+}
+
+var serverAttachNetworkCmd = &cobra.Command{
+	Use:     "attach-network [flags] SERVER NETWORK",
+	Example: `gscloud server attach-network --ordering 1 37d53278-8e5f-47e1-a63f-54513e4b4d53 9c5c1a9e-5b57-4f36-a6c8-2ed1e5e8c4f2`,
+	Short:   "Attach a network",
+	Long: `Connect a server to a network. Each attached network shows up as a network interface within the server.
+
+The order of network interfaces is given by **--ordering**; lower numbers get lower PCI IDs. With **--l3security** only traffic from the given IP addresses is allowed on the interface. A firewall is applied either from a firewall template given with **--firewall-template** or from rules given with **--rules-file** (see gscloud-firewall-export(1) for the format).
+
+# EXAMPLES
+
+Attach a private network as second interface, allowing only one source address:
+
+	$ gscloud server attach-network \
+		--ordering 1 \
+		--l3security 10.0.0.5 \
+		37d53278-8e5f-47e1-a63f-54513e4b4d53 9c5c1a9e-5b57-4f36-a6c8-2ed1e5e8c4f2
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		serverID, err := idForServer(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find server", err)
+		}
+		networkID, err := idForNetwork(ctx, args[1])
+		if err != nil {
+			return NewError(cmd, "Could not find network", err)
+		}
+		createReq := gsclient.ServerNetworkRelationCreateRequest{
+			ObjectUUID: networkID,
+			Ordering:   serverRelationFlags.ordering,
+			BootDevice: serverRelationFlags.bootDevice,
+			L3security: serverRelationFlags.l3security,
+		}
+		if serverRelationFlags.firewallTemplate != "" {
+			createReq.FirewallTemplateUUID, err = idForFirewall(ctx, serverRelationFlags.firewallTemplate)
+			if err != nil {
+				return NewError(cmd, "Could not find firewall", err)
+			}
+		}
+		if serverRelationFlags.rulesFile != "" {
+			rules, err := readFirewallRules(serverRelationFlags.rulesFile)
+			if err != nil {
+				return NewError(cmd, "Could not read rules", err)
+			}
+			createReq.Firewall = &rules
+		}
+		op := rt.ServerNetworkRelationOperator()
+		err = op.CreateServerNetwork(ctx, serverID, createReq)
+		if err != nil {
+			return NewError(cmd, powerOffHint(ctx, "Attaching network failed", serverID), err)
+		}
+		return nil
+	},
+}
+
+var serverDetachNetworkCmd = &cobra.Command{
+	Use:     "detach-network SERVER NETWORK",
+	Example: `gscloud server detach-network 37d53278-8e5f-47e1-a63f-54513e4b4d53 9c5c1a9e-5b57-4f36-a6c8-2ed1e5e8c4f2`,
+	Short:   "Detach a network",
+	Long:    `Disconnect a server from a network. The network itself is not removed.`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		serverID, err := idForServer(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find server", err)
+		}
+		networkID, err := idForNetwork(ctx, args[1])
+		if err != nil {
+			return NewError(cmd, "Could not find network", err)
+		}
+		op := rt.ServerNetworkRelationOperator()
+		err = op.DeleteServerNetwork(ctx, serverID, networkID)
+		if err != nil {
+			return NewError(cmd, powerOffHint(ctx, "Detaching network failed", serverID), err)
+		}
+		return nil
+	},
+}
+
+var serverNetworksCmd = &cobra.Command{
+	Use:     "networks [flags] ID",
+	Example: `gscloud server networks 37d53278-8e5f-47e1-a63f-54513e4b4d53`,
+	Short:   "List attached networks",
+	Long:    `List the networks attached to a server, i.e. its network interfaces.`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		serverID, err := idForServer(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find server", err)
+		}
+		out := new(bytes.Buffer)
+		op := rt.ServerNetworkRelationOperator()
+		networks, err := op.GetServerNetworkList(ctx, serverID)
+		if err != nil {
+			return NewError(cmd, "Could not get list of networks", err)
+		}
+		var rows [][]string
+		heading := []string{"id", "name", "ordering", "mac", "public", "boot", "l3security", "firewall template"}
+		for _, network := range networks {
+			public := "no"
+			if network.PublicNet {
+				public = "yes"
+			}
+			boot := "no"
+			if network.BootDevice {
+				boot = "yes"
+			}
+			fill := [][]string{
+				{
+					network.NetworkUUID,
+					network.ObjectName,
+					strconv.Itoa(network.Ordering),
+					network.Mac,
+					public,
+					boot,
+					strings.Join(network.L3security, ","),
+					network.FirewallTemplateUUID,
+				},
+			}
+			rows = append(rows, fill...)
+		}
+		if quietOutput() {
+			for _, info := range rows {
+				fmt.Println(info[0])
+			}
+			return nil
+		}
+		err = renderOutput(out, heading, rows, networks)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
+		}
+		fmt.Print(out)
+		return nil
+	},
+}
+
+func init() {
+	serverAttachStorageCmd.Flags().BoolVar(&serverRelationFlags.bootDevice, "boot", false, "Use storage as boot device")
+
+	serverAttachNetworkCmd.Flags().IntVar(&serverRelationFlags.ordering, "ordering", 0, "Position of the network interface")
+	serverAttachNetworkCmd.Flags().BoolVar(&serverRelationFlags.bootDevice, "boot", false, "Boot from this network (PXE)")
+	serverAttachNetworkCmd.Flags().StringSliceVar(&serverRelationFlags.l3security, "l3security", nil, "IP addresses allowed to send traffic on this interface")
+	serverAttachNetworkCmd.Flags().StringVar(&serverRelationFlags.firewallTemplate, "firewall-template", "", "Name or ID of firewall template to apply")
+	serverAttachNetworkCmd.Flags().StringVar(&serverRelationFlags.rulesFile, "rules-file", "", "Path to a YAML or JSON file containing firewall rules to apply")
+
+	serverCmd.AddCommand(serverAttachStorageCmd, serverDetachStorageCmd, serverAttachISOCmd, serverDetachISOCmd, serverAttachNetworkCmd, serverDetachNetworkCmd, serverNetworksCmd)
+}
+
+// powerOffHint extends the description of a failed operation with a hint to
+// power off the server first, if the server is running. Most changes to the
+// hardware of a server are only possible while it is powered off.
+func powerOffHint(ctx context.Context, what, serverID string) string {
+	on, err := rt.ServerOperator().IsServerOn(ctx, serverID)
+	if err != nil || !on {
+		return what
+	}
+	return fmt.Sprintf("%s. The server is powered on, try again after 'gscloud server off %s'", what, serverID)
+}
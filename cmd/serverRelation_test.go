@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/gridscale/gscloud/runtime"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockServerRelationOp is a running server that refuses any change to its
+// storages.
+type mockServerRelationOp struct {
+	mockServerOp
+}
+
+func (o *mockServerRelationOp) IsServerOn(ctx context.Context, id string) (bool, error) {
+	return true, nil
+}
+
+func (o *mockServerRelationOp) GetServerStorageList(ctx context.Context, id string) ([]gsclient.ServerStorageRelationProperties, error) {
+	return []gsclient.ServerStorageRelationProperties{}, nil
+}
+
+func (o *mockServerRelationOp) GetServerStorage(ctx context.Context, serverID, storageID string) (gsclient.ServerStorageRelationProperties, error) {
+	return gsclient.ServerStorageRelationProperties{}, nil
+}
+
+func (o *mockServerRelationOp) CreateServerStorage(ctx context.Context, id string, body gsclient.ServerStorageRelationCreateRequest) error {
+	return errors.New("test")
+}
+
+func (o *mockServerRelationOp) UpdateServerStorage(ctx context.Context, serverID, storageID string, body gsclient.ServerStorageRelationUpdateRequest) error {
+	return errors.New("test")
+}
+
+func (o *mockServerRelationOp) DeleteServerStorage(ctx context.Context, serverID, storageID string) error {
+	return errors.New("test")
+}
+
+func (o *mockServerRelationOp) LinkStorage(ctx context.Context, serverID string, storageID string, bootdevice bool) error {
+	return errors.New("test")
+}
+
+func (o *mockServerRelationOp) UnlinkStorage(ctx context.Context, serverID string, storageID string) error {
+	return errors.New("test")
+}
+
+func Test_ServerAttachStoragePowerOffHint(t *testing.T) {
+	rt, _ = runtime.NewTestRuntime()
+	rt.SetServerStorageRelationOperator(&mockServerRelationOp{})
+
+	for _, c := range []*cobra.Command{serverAttachStorageCmd, serverDetachStorageCmd} {
+		err := c.RunE(new(cobra.Command), []string{"37d53278-8e5f-47e1-a63f-54513e4b4d53", "b3ec341c-1732-45b3-bc45-9a7fcebb363e"})
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "gscloud server off 37d53278-8e5f-47e1-a63f-54513e4b4d53")
+	}
+}
@@ -87,6 +87,9 @@ func (o mockServerOp) IsServerOn(ctx context.Context, id string) (bool, error) {
 }
 
 func Test_ServerCommmandDelete(t *testing.T) {
+	confirmFlags.yes = true
+	defer func() { confirmFlags.yes = false }()
+
 	type testCase struct {
 		isSuccessful   bool
 		expectedFatal  bool
@@ -108,6 +111,7 @@ func Test_ServerCommmandDelete(t *testing.T) {
 	for _, tc := range testCases {
 		var fatal bool
 		op := mockServerOp{}
+		op.On("GetServerList").Return([]gsclient.Server{mockServer}, nil)
 		if tc.isSuccessful {
 			op.On("DeleteServer", mock.Anything).Return(nil)
 		} else {
@@ -118,7 +122,7 @@ func Test_ServerCommmandDelete(t *testing.T) {
 		r, w, _ := os.Pipe()
 		os.Stdout = w
 		cmd := serverRmCmd.RunE
-		cmd(new(cobra.Command), []string{"rm", mockServer.Properties.ObjectUUID})
+		cmd(new(cobra.Command), []string{mockServer.Properties.ObjectUUID})
 		w.Close()
 		out, _ := ioutil.ReadAll(r)
 		assert.Equal(t, tc.expectedFatal, fatal)
@@ -206,6 +210,7 @@ func Test_ServerCommmandOn(t *testing.T) {
 	for _, tc := range testCases {
 		var fatal bool
 		op := mockServerOp{}
+		op.On("GetServerList").Return([]gsclient.Server{mockServer}, nil)
 		if tc.isSuccessful {
 			op.On("StartServer", mock.Anything).Return(nil)
 		} else {
@@ -216,7 +221,7 @@ func Test_ServerCommmandOn(t *testing.T) {
 		r, w, _ := os.Pipe()
 		os.Stdout = w
 		cmd := serverOnCmd.RunE
-		cmd(new(cobra.Command), []string{"on", mockServer.Properties.ObjectUUID})
+		cmd(new(cobra.Command), []string{mockServer.Properties.ObjectUUID})
 		w.Close()
 		out, _ := ioutil.ReadAll(r)
 		assert.Equal(t, tc.expectedFatal, fatal)
@@ -258,6 +263,7 @@ func Test_ServerCommmandOff(t *testing.T) {
 		serverFlags.forceShutdown = tc.isForceShutdown
 
 		op := mockServerOp{}
+		op.On("GetServerList").Return([]gsclient.Server{mockServer}, nil)
 		if tc.isForceShutdown {
 			if tc.isSuccessful {
 				op.On("StopServer", mock.Anything).Return(nil)
@@ -278,7 +284,7 @@ func Test_ServerCommmandOff(t *testing.T) {
 		r, w, _ := os.Pipe()
 		os.Stdout = w
 		cmd := serverOffCmd.RunE
-		cmd(new(cobra.Command), []string{"off", mockServer.Properties.ObjectUUID})
+		cmd(new(cobra.Command), []string{mockServer.Properties.ObjectUUID})
 		w.Close()
 		out, _ := ioutil.ReadAll(r)
 		assert.Equal(t, tc.expectedFatal, fatal)
@@ -8,7 +8,6 @@ import (
 	"os"
 
 	"github.com/gridscale/gsclient-go/v3"
-	"github.com/gridscale/gscloud/render"
 	"github.com/spf13/cobra"
 )
 
@@ -27,6 +26,8 @@ var sshKeyCmd = &cobra.Command{
 	Long:  `List, create, or remove SSH keys.`,
 }
 
+var sshKeyWideColumns = []string{"id", "name", "key", "user", "created", "labels"}
+
 var sshKeyLsCmd = &cobra.Command{
 	Use:     "ls [flags]",
 	Aliases: []string{"list"},
@@ -41,30 +42,43 @@ var sshKeyLsCmd = &cobra.Command{
 			return NewError(cmd, "Could not get SSH key list", err)
 		}
 		var rows [][]string
-		if !rootFlags.json {
-			heading := []string{"id", "name", "key", "user", "created"}
-			for _, key := range sshkeys {
-				fill := [][]string{
-					{
-						key.Properties.ObjectUUID,
-						key.Properties.Name,
-						key.Properties.Sshkey[:10] + "..." + key.Properties.Sshkey[len(key.Properties.Sshkey)-30:],
-						key.Properties.UserUUID[:8],
-						key.Properties.CreateTime.String()[:19],
-					},
-				}
-				rows = append(rows, fill...)
+		var labels [][]string
+		heading := []string{"id", "name", "key", "user", "created"}
+		for _, key := range sshkeys {
+			fill := [][]string{
+				{
+					key.Properties.ObjectUUID,
+					key.Properties.Name,
+					key.Properties.Sshkey[:10] + "..." + key.Properties.Sshkey[len(key.Properties.Sshkey)-30:],
+					key.Properties.UserUUID[:8],
+					key.Properties.CreateTime.String()[:19],
+				},
 			}
-
-			if rootFlags.quiet {
-				for _, info := range rows {
-					fmt.Println(info[0])
-				}
-				return nil
+			rows = append(rows, fill...)
+			labels = append(labels, key.Properties.Labels)
+		}
+		order, err := listOrder(heading, rows, labels, sshkeys)
+		if err != nil {
+			return NewError(cmd, "Could not list SSH keys", err)
+		}
+		rows = selectRows(rows, order)
+		selected := make([]gsclient.Sshkey, 0, len(order))
+		for _, i := range order {
+			selected = append(selected, sshkeys[i])
+		}
+		if quietOutput() {
+			for _, info := range rows {
+				fmt.Println(info[0])
 			}
-			render.AsTable(out, heading, rows, renderOpts)
-		} else {
-			render.AsJSON(out, sshkeys)
+			return nil
+		}
+		heading, rows, err = listColumns(heading, rows, selected, sshKeyWideColumns)
+		if err != nil {
+			return NewError(cmd, "Could not list SSH keys", err)
+		}
+		err = renderOutput(out, heading, rows, selected)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
 		}
 		fmt.Print(out)
 		return nil
@@ -85,6 +99,7 @@ var sshKeyAddCmd = &cobra.Command{
 		_, err = op.CreateSshkey(ctx, gsclient.SshkeyCreateRequest{
 			Name:   sshKeyFlags.name,
 			Sshkey: string(publicKey),
+			Labels: createLabels(),
 		})
 		if err != nil {
 			return NewError(cmd, "Creating SSH key failed", err)
@@ -94,19 +109,30 @@ var sshKeyAddCmd = &cobra.Command{
 }
 
 var sshKeyRmCmd = &cobra.Command{
-	Use:     "rm [flags] [ID]",
+	Use:     "rm [flags] [ID...]",
 	Aliases: []string{"remove"},
 	Short:   "Remove SSH key",
-	Long:    `Remove an existing SSH key.`,
-	Args:    cobra.ExactArgs(1),
+	Long:    `Remove an existing SSH key. Protected SSH keys are not removed, see gscloud-protect(1).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
+		id, err := idForSSHKey(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find SSH key", err)
+		}
 		op := rt.SSHKeyOperator()
-		err := op.DeleteSshkey(ctx, args[0])
+		key, err := op.GetSshkey(ctx, id)
+		if err != nil {
+			return NewError(cmd, "Could not get SSH key", err)
+		}
+		err = checkProtected("SSH key", key.Properties.Name, key.Properties.Labels)
 		if err != nil {
 			return NewError(cmd, "Removing SSH key failed", err)
 		}
-		fmt.Fprintf(os.Stderr, "Removed %s\n", args[0])
+		err = op.DeleteSshkey(ctx, id)
+		if err != nil {
+			return NewError(cmd, "Removing SSH key failed", err)
+		}
+		fmt.Fprintf(os.Stderr, "Removed %s\n", id)
 		return nil
 	},
 }
@@ -116,6 +142,10 @@ func init() {
 	sshKeyAddCmd.MarkFlagRequired("name")
 	sshKeyAddCmd.PersistentFlags().StringVarP(&sshKeyFlags.pubKeyFile, "file", "f", "", "Path to public key file")
 	sshKeyAddCmd.MarkFlagRequired("file")
+	addCreateLabelFlag(sshKeyAddCmd)
+
+	addListFlags(sshKeyLsCmd)
+	addBulkFlags(sshKeyRmCmd, "ssh-key")
 
 	sshKeyCmd.AddCommand(sshKeyLsCmd, sshKeyAddCmd, sshKeyRmCmd)
 	rootCmd.AddCommand(sshKeyCmd)
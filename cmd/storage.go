@@ -10,16 +10,20 @@ import (
 	"time"
 
 	"github.com/gridscale/gsclient-go/v3"
-	"github.com/gridscale/gscloud/render"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
 
 type storageCmdFlags struct {
-	name     string
-	capacity int
-	force    bool
+	name           string
+	capacity       int
+	createCapacity int
+	storageType    string
+	template       string
+	hostName       string
+	force          bool
+	withSchedules  bool
 }
 
 var (
@@ -32,6 +36,8 @@ var storageCmd = &cobra.Command{
 	Long:  `List, create, or remove storages.`,
 }
 
+var storageWideColumns = []string{"id", "name", "capacity", "changed", "status", "storage_type", "location", "labels"}
+
 var storageLsCmd = &cobra.Command{
 	Use:     "ls [flags]",
 	Aliases: []string{"list"},
@@ -46,29 +52,47 @@ var storageLsCmd = &cobra.Command{
 			return NewError(cmd, "Could not get storage list", err)
 		}
 		var rows [][]string
-		if !rootFlags.json {
-			heading := []string{"id", "name", "capacity", "changed", "status"}
-			for _, storage := range storages {
-				fill := [][]string{
-					{
-						storage.Properties.ObjectUUID,
-						storage.Properties.Name,
-						strconv.FormatInt(int64(storage.Properties.Capacity), 10),
-						storage.Properties.ChangeTime.Local().Format(time.RFC3339),
-						storage.Properties.Status,
-					},
-				}
-				rows = append(rows, fill...)
+		var labels [][]string
+		heading := []string{"id", "name", "capacity", "changed", "status"}
+		if storageFlags.withSchedules {
+			heading = append(heading, "schedules")
+		}
+		for _, storage := range storages {
+			fill := []string{
+				storage.Properties.ObjectUUID,
+				storage.Properties.Name,
+				strconv.FormatInt(int64(storage.Properties.Capacity), 10),
+				storage.Properties.ChangeTime.Local().Format(time.RFC3339),
+				storage.Properties.Status,
 			}
-			if rootFlags.quiet {
-				for _, info := range rows {
-					fmt.Println(info[0])
-				}
-				return nil
+			if storageFlags.withSchedules {
+				fill = append(fill, strconv.Itoa(len(storage.Properties.Relations.SnapshotSchedules)))
 			}
-			render.AsTable(out, heading, rows, renderOpts)
-		} else {
-			render.AsJSON(out, storages)
+			rows = append(rows, fill)
+			labels = append(labels, storage.Properties.Labels)
+		}
+		order, err := listOrder(heading, rows, labels, storages)
+		if err != nil {
+			return NewError(cmd, "Could not list storages", err)
+		}
+		rows = selectRows(rows, order)
+		selected := make([]gsclient.Storage, 0, len(order))
+		for _, i := range order {
+			selected = append(selected, storages[i])
+		}
+		if quietOutput() {
+			for _, info := range rows {
+				fmt.Println(info[0])
+			}
+			return nil
+		}
+		heading, rows, err = listColumns(heading, rows, selected, storageWideColumns)
+		if err != nil {
+			return NewError(cmd, "Could not list storages", err)
+		}
+		err = renderOutput(out, heading, rows, selected)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
 		}
 		fmt.Print(out)
 		return nil
@@ -107,17 +131,25 @@ Shrink a storage:
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
 		storageOp := rt.StorageOperator()
+		id, err := idForStorage(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find storage", err)
+		}
 		updateReq := gsclient.StorageUpdateRequest{}
 		if len(storageFlags.name) > 0 {
 			updateReq.Name = storageFlags.name
 		}
 		if storageFlags.capacity > 0 {
-			storage, err := storageOp.GetStorage(ctx, args[0])
+			storage, err := storageOp.GetStorage(ctx, id)
 			if err != nil {
 				return NewError(cmd, "Could not set new capacity", err)
 			}
 			currentSize := storage.Properties.Capacity
 			if storageFlags.capacity < currentSize {
+				err = checkProtected("storage", storage.Properties.Name, storage.Properties.Labels)
+				if err != nil {
+					return NewError(cmd, "Could not set new capacity", err)
+				}
 				if !storageFlags.force {
 					log.Printf("Downsizing can destroy your data. Re-run with --force to reduce storage size from %d GB to %d GB\n", currentSize, storageFlags.capacity)
 					return nil
@@ -125,40 +157,249 @@ Shrink a storage:
 			}
 			updateReq.Capacity = storageFlags.capacity
 		}
-		err := storageOp.UpdateStorage(
+		err = storageOp.UpdateStorage(
 			ctx,
-			args[0],
+			id,
 			updateReq)
 		if err != nil {
 			return NewError(cmd, "Could not set property", err)
 		}
+		err = waitForStorage(ctx, storageOp, id)
+		if err != nil {
+			return NewError(cmd, "Waiting for storage failed", err)
+		}
 		return nil
 	},
 }
 
+// describeStorage describes a storage to be removed.
+func describeStorage(s gsclient.Storage) removal {
+	var servers []string
+	for _, rel := range s.Properties.Relations.Servers {
+		servers = append(servers, rel.ObjectName)
+	}
+	return removal{
+		kind: "storage",
+		name: s.Properties.Name,
+		id:   s.Properties.ObjectUUID,
+		details: []string{
+			fmt.Sprintf("size: %d GB, %s", s.Properties.Capacity, s.Properties.StorageType),
+			"servers: " + relatedNames(servers),
+			fmt.Sprintf("snapshots: %d", len(s.Properties.Snapshots)),
+		},
+	}
+}
+
 var storageRmCmd = &cobra.Command{
-	Use:     "rm [flags] [ID]",
+	Use:     "rm [flags] [ID...]",
 	Aliases: []string{"remove"},
 	Short:   "Remove storage",
-	Long:    `Remove an existing storage.`,
-	Args:    cobra.ExactArgs(1),
+	Long:    `Remove an existing storage. Its name, size, and the servers it is attached to are shown and you are asked for confirmation first, unless --yes is given. See confirm_destructive in gscloud-config(1) for when confirmation is needed. Protected storages are not removed, see gscloud-protect(1).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		storageOp := rt.StorageOperator()
 		ctx := context.Background()
-		err := storageOp.DeleteStorage(ctx, args[0])
+		id, err := idForStorage(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find storage", err)
+		}
+		storage, err := storageOp.GetStorage(ctx, id)
+		if err != nil {
+			return NewError(cmd, "Could not get storage", err)
+		}
+		err = checkProtected("storage", storage.Properties.Name, storage.Properties.Labels)
+		if err != nil {
+			return NewError(cmd, "Removing storage failed", err)
+		}
+		err = confirmRemoval(func() (removal, error) {
+			return describeStorage(storage), nil
+		})
+		if err != nil {
+			return NewError(cmd, "Removing storage failed", err)
+		}
+		err = storageOp.DeleteStorage(ctx, id)
 		if err != nil {
 			return NewError(cmd, "Deleting storage failed", err)
 		}
-		fmt.Fprintf(os.Stderr, "Removed %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Removed %s\n", id)
+		return nil
+	},
+}
+
+var storageCreateCmd = &cobra.Command{
+	Use:     "create [flags]",
+	Example: `gscloud storage create --name data-1 --capacity 50 --type storage_high`,
+	Short:   "Create storage",
+	Long: `Create a new storage. The storage is created in the location of the project.
+
+Storage type is one of "storage", "storage_high", or "storage_insane". If a template is given, the storage is initialized from it and a generated root password is printed.
+
+# EXAMPLES
+
+Create an empty storage:
+
+	$ gscloud storage create --name data-1 --capacity 50
+
+Create a storage from the Ubuntu 20.04 template:
+
+	$ gscloud storage create \
+		--name root-1 \
+		--capacity 20 \
+		--type storage_insane \
+		--with-template "Ubuntu 20.04 LTS" \
+		--hostname worker-1
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		type output struct {
+			Storage  string `json:"storage"`
+			Password string `json:"password,omitempty"`
+		}
+
+		ctx := context.Background()
+		storageType, err := toStorageType(storageFlags.storageType)
+		if err != nil {
+			return NewError(cmd, "Cannot create storage", err)
+		}
+		createReq := gsclient.StorageCreateRequest{
+			Name:        storageFlags.name,
+			Capacity:    storageFlags.createCapacity,
+			StorageType: storageType,
+			Labels:      createLabels(),
+		}
+		var password string
+		if storageFlags.template != "" {
+			templateID, err := idForTemplate(ctx, storageFlags.template)
+			if err != nil {
+				return NewError(cmd, "Cannot create storage", err)
+			}
+			password = generatePassword()
+			createReq.Template = &gsclient.StorageTemplate{
+				TemplateUUID: templateID,
+				Password:     password,
+				PasswordType: gsclient.PlainPasswordType,
+				Hostname:     storageFlags.hostName,
+			}
+		}
+		storageOp := rt.StorageOperator()
+		storage, err := storageOp.CreateStorage(ctx, createReq)
+		if err != nil {
+			return NewError(cmd, "Creating storage failed", err)
+		}
+		// Print the password before waiting, so it is not lost if waiting
+		// fails.
+		if !structuredOutput() {
+			fmt.Println("Storage created:", storage.ObjectUUID)
+			if password != "" {
+				fmt.Println("Password:", password)
+			}
+		} else {
+			err = renderOutput(os.Stdout, nil, nil, output{Storage: storage.ObjectUUID, Password: password})
+			if err != nil {
+				return NewError(cmd, "Could not render output", err)
+			}
+		}
+		err = waitForStorage(ctx, storageOp, storage.ObjectUUID)
+		if err != nil {
+			return NewError(cmd, "Waiting for storage failed", err)
+		}
+		return nil
+	},
+}
+
+var storageCloneCmd = &cobra.Command{
+	Use:     "clone [flags] ID",
+	Example: `gscloud storage clone --wait b3ec341c-1732-45b3-bc45-9a7fcebb363e`,
+	Short:   "Clone storage",
+	Long: `Create a copy of an existing storage. The copy is given the labels of the original, as well as the default labels of the project and the ones given by --label.
+
+With **--wait**, gscloud waits until the clone is ready to be used.
+
+# EXAMPLES
+
+Clone a storage and wait for at most five minutes until the clone is active:
+
+	$ gscloud --wait --timeout 5m storage clone b3ec341c-1732-45b3-bc45-9a7fcebb363e
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		type output struct {
+			Storage string `json:"storage"`
+		}
+
+		ctx := context.Background()
+		storageOp := rt.StorageOperator()
+		id, err := idForStorage(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find storage", err)
+		}
+		clone, err := storageOp.CloneStorage(ctx, id)
+		if err != nil {
+			return NewError(cmd, "Cloning storage failed", err)
+		}
+		// The API does not take labels when cloning, so they are added to
+		// the labels of the original afterwards.
+		if labels := createLabels(); len(labels) > 0 {
+			storage, err := storageOp.GetStorage(ctx, id)
+			if err != nil {
+				return NewError(cmd, "Could not get storage", err)
+			}
+			cloneLabels := storage.Properties.Labels
+			for _, label := range labels {
+				cloneLabels, _ = addLabel(cloneLabels, label)
+			}
+			err = storageOp.UpdateStorage(ctx, clone.ObjectUUID, gsclient.StorageUpdateRequest{Labels: &cloneLabels})
+			if err != nil {
+				return NewError(cmd, "Could not label clone", err)
+			}
+		}
+		err = waitForStorage(ctx, storageOp, clone.ObjectUUID)
+		if err != nil {
+			return NewError(cmd, "Waiting for clone failed", err)
+		}
+		if !structuredOutput() {
+			fmt.Println("Storage created:", clone.ObjectUUID)
+		} else {
+			err = renderOutput(os.Stdout, nil, nil, output{Storage: clone.ObjectUUID})
+			if err != nil {
+				return NewError(cmd, "Could not render output", err)
+			}
+		}
 		return nil
 	},
 }
 
 func init() {
+	storageCreateCmd.Flags().StringVarP(&storageFlags.name, "name", "n", "", "Name of the storage")
+	storageCreateCmd.MarkFlagRequired("name")
+	storageCreateCmd.Flags().IntVar(&storageFlags.createCapacity, "capacity", 10, "Storage capacity (GB)")
+	storageCreateCmd.Flags().StringVar(&storageFlags.storageType, "type", "storage", "Storage type. One of \"storage\", \"storage_high\", \"storage_insane\"")
+	storageCreateCmd.Flags().StringVar(&storageFlags.template, "with-template", "", "Name or ID of template to use")
+	storageCreateCmd.Flags().StringVar(&storageFlags.hostName, "hostname", "", "Hostname")
+	addCreateLabelFlag(storageCreateCmd)
+	addCreateLabelFlag(storageCloneCmd)
+
+	addListFlags(storageLsCmd)
+	addConfirmFlags(storageRmCmd)
+	addBulkFlags(storageRmCmd, "storage")
+	storageLsCmd.Flags().BoolVar(&storageFlags.withSchedules, "schedules", false, "Show number of snapshot schedules per storage")
+
 	storageSetCmd.PersistentFlags().StringVarP(&storageFlags.name, "name", "n", "", "Change name")
 	storageSetCmd.PersistentFlags().IntVar(&storageFlags.capacity, "capacity", 0, "Change size (GB)")
 	storageSetCmd.PersistentFlags().BoolVarP(&storageFlags.force, "force", "f", false, "Force a potential destructive operation")
 
-	storageCmd.AddCommand(storageLsCmd, storageSetCmd, storageRmCmd)
+	storageCmd.AddCommand(storageLsCmd, storageSetCmd, storageRmCmd, storageCreateCmd, storageCloneCmd)
 	rootCmd.AddCommand(storageCmd)
 }
+
+func toStorageType(val string) (gsclient.StorageType, error) {
+	switch val {
+	case "storage":
+		return gsclient.DefaultStorageType, nil
+
+	case "storage_high":
+		return gsclient.HighStorageType, nil
+
+	case "storage_insane":
+		return gsclient.InsaneStorageType, nil
+	}
+	return "", fmt.Errorf("not a valid storage type: %s", val)
+}
@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gridscale/gsclient-go/v3"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type storageSnapshotCmdFlags struct {
+	name      string
+	force     bool
+	s3Host    string
+	bucket    string
+	fileName  string
+	accessKey string
+	secretKey string
+	private   bool
+}
+
+var (
+	storageSnapshotFlags storageSnapshotCmdFlags
+)
+
+var storageSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Operations on storage snapshots",
+	Long:  `List, create, roll back to, export, or remove snapshots of a storage.`,
+}
+
+var storageSnapshotWideColumns = []string{"id", "name", "capacity", "created", "status", "change_time", "location", "labels"}
+
+var storageSnapshotLsCmd = &cobra.Command{
+	Use:     "ls [flags] STORAGE",
+	Aliases: []string{"list"},
+	Short:   "List snapshots",
+	Long:    `List snapshots of a storage.`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snapshotOp := rt.StorageSnapshotOperator()
+		ctx := context.Background()
+		storageID, err := idForStorage(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find storage", err)
+		}
+		out := new(bytes.Buffer)
+		snapshots, err := snapshotOp.GetStorageSnapshotList(ctx, storageID)
+		if err != nil {
+			return NewError(cmd, "Could not get list of snapshots", err)
+		}
+		var rows [][]string
+		heading := []string{"id", "name", "capacity", "created", "status"}
+		for _, snapshot := range snapshots {
+			fill := [][]string{
+				{
+					snapshot.Properties.ObjectUUID,
+					snapshot.Properties.Name,
+					strconv.FormatInt(int64(snapshot.Properties.Capacity), 10),
+					snapshot.Properties.CreateTime.Local().Format(time.RFC3339),
+					snapshot.Properties.Status,
+				},
+			}
+			rows = append(rows, fill...)
+		}
+		if quietOutput() {
+			for _, info := range rows {
+				fmt.Println(info[0])
+			}
+			return nil
+		}
+		heading, rows, err = listColumns(heading, rows, snapshots, storageSnapshotWideColumns)
+		if err != nil {
+			return NewError(cmd, "Could not list snapshots", err)
+		}
+		err = renderOutput(out, heading, rows, snapshots)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
+		}
+		fmt.Print(out)
+		return nil
+	},
+}
+
+var storageSnapshotCreateCmd = &cobra.Command{
+	Use:     "create [flags] STORAGE",
+	Example: `gscloud storage snapshot create --name before-upgrade b3ec341c-1732-45b3-bc45-9a7fcebb363e`,
+	Short:   "Create snapshot",
+	Long:    `Create a new snapshot of a storage.`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		type output struct {
+			Snapshot string `json:"snapshot"`
+		}
+
+		snapshotOp := rt.StorageSnapshotOperator()
+		ctx := context.Background()
+		storageID, err := idForStorage(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find storage", err)
+		}
+		snapshot, err := snapshotOp.CreateStorageSnapshot(ctx, storageID, gsclient.StorageSnapshotCreateRequest{
+			Name:   storageSnapshotFlags.name,
+			Labels: createLabels(),
+		})
+		if err != nil {
+			return NewError(cmd, "Creating snapshot failed", err)
+		}
+		err = waitForSnapshot(ctx, snapshotOp, storageID, snapshot.ObjectUUID)
+		if err != nil {
+			return NewError(cmd, "Waiting for snapshot failed", err)
+		}
+		if !structuredOutput() {
+			fmt.Println("Snapshot created:", snapshot.ObjectUUID)
+		} else {
+			err = renderOutput(os.Stdout, nil, nil, output{Snapshot: snapshot.ObjectUUID})
+			if err != nil {
+				return NewError(cmd, "Could not render output", err)
+			}
+		}
+		return nil
+	},
+}
+
+var storageSnapshotRmCmd = &cobra.Command{
+	Use:     "rm [flags] STORAGE SNAPSHOT",
+	Aliases: []string{"remove"},
+	Short:   "Remove snapshot",
+	Long:    `Remove an existing snapshot of a storage.`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snapshotOp := rt.StorageSnapshotOperator()
+		ctx := context.Background()
+		storageID, err := idForStorage(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find storage", err)
+		}
+		snapshotID, err := idForSnapshot(ctx, storageID, args[1])
+		if err != nil {
+			return NewError(cmd, "Could not find snapshot", err)
+		}
+		err = snapshotOp.DeleteStorageSnapshot(ctx, storageID, snapshotID)
+		if err != nil {
+			return NewError(cmd, "Deleting snapshot failed", err)
+		}
+		fmt.Fprintf(os.Stderr, "Removed %s\n", snapshotID)
+		return nil
+	},
+}
+
+var storageSnapshotRollbackCmd = &cobra.Command{
+	Use:     "rollback [flags] STORAGE SNAPSHOT",
+	Example: `gscloud storage snapshot rollback --force b3ec341c-1732-45b3-bc45-9a7fcebb363e 6c4bd6b4-8a7e-4f0e-9c5c-0b1f1c8e2f3a`,
+	Short:   "Roll back storage to snapshot",
+	Long: `Roll back a storage to the state of a snapshot. All changes made to the storage after the snapshot was taken are lost.
+
+# EXAMPLES
+
+Roll back a storage:
+
+	$ gscloud storage snapshot rollback --force b3ec341c-1732-45b3-bc45-9a7fcebb363e 6c4bd6b4-8a7e-4f0e-9c5c-0b1f1c8e2f3a
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !storageSnapshotFlags.force {
+			log.Println("Rolling back can destroy your data. Re-run with --force to roll back storage to the snapshot")
+			return nil
+		}
+		snapshotOp := rt.StorageSnapshotOperator()
+		ctx := context.Background()
+		storageID, err := idForStorage(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find storage", err)
+		}
+		snapshotID, err := idForSnapshot(ctx, storageID, args[1])
+		if err != nil {
+			return NewError(cmd, "Could not find snapshot", err)
+		}
+		err = snapshotOp.RollbackStorage(ctx, storageID, snapshotID, gsclient.StorageRollbackRequest{
+			Rollback: true,
+		})
+		if err != nil {
+			return NewError(cmd, "Rolling back storage failed", err)
+		}
+		err = waitForStorage(ctx, rt.StorageOperator(), storageID)
+		if err != nil {
+			return NewError(cmd, "Waiting for storage failed", err)
+		}
+		return nil
+	},
+}
+
+var storageSnapshotExportCmd = &cobra.Command{
+	Use:     "export [flags] STORAGE SNAPSHOT",
+	Example: `gscloud storage snapshot export --s3-host gos3.io --bucket backups --file db.gz b3ec341c-1732-45b3-bc45-9a7fcebb363e 6c4bd6b4-8a7e-4f0e-9c5c-0b1f1c8e2f3a`,
+	Short:   "Export snapshot to S3",
+	Long: `Export a snapshot to a bucket of an S3-compatible object storage.
+
+Access and secret key are taken from **--access-key** and **--secret-key**. If not given, the environment variables AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are used.
+
+# EXAMPLES
+
+Export a snapshot to the gridscale object storage:
+
+	$ export AWS_ACCESS_KEY_ID=… AWS_SECRET_ACCESS_KEY=…
+	$ gscloud storage snapshot export \
+		--s3-host gos3.io \
+		--bucket backups \
+		--file db-2020-11-17.gz \
+		b3ec341c-1732-45b3-bc45-9a7fcebb363e 6c4bd6b4-8a7e-4f0e-9c5c-0b1f1c8e2f3a
+
+# ENVIRONMENT
+
+AWS_ACCESS_KEY_ID
+	Access key used if --access-key is not given
+
+AWS_SECRET_ACCESS_KEY
+	Secret key used if --secret-key is not given
+`,
+	Args: cobra.ExactArgs(2),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if storageSnapshotFlags.accessKey == "" {
+			storageSnapshotFlags.accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		}
+		if storageSnapshotFlags.secretKey == "" {
+			storageSnapshotFlags.secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		}
+		if storageSnapshotFlags.accessKey == "" || storageSnapshotFlags.secretKey == "" {
+			return errors.New("no access key or secret key given")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snapshotOp := rt.StorageSnapshotOperator()
+		ctx := context.Background()
+		storageID, err := idForStorage(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find storage", err)
+		}
+		snapshotID, err := idForSnapshot(ctx, storageID, args[1])
+		if err != nil {
+			return NewError(cmd, "Could not find snapshot", err)
+		}
+		err = snapshotOp.ExportStorageSnapshotToS3(ctx, storageID, snapshotID, gsclient.StorageSnapshotExportToS3Request{
+			S3auth: gsclient.S3auth{
+				Host:      storageSnapshotFlags.s3Host,
+				AccessKey: storageSnapshotFlags.accessKey,
+				SecretKey: storageSnapshotFlags.secretKey,
+			},
+			S3data: gsclient.S3data{
+				Host:     storageSnapshotFlags.s3Host,
+				Bucket:   storageSnapshotFlags.bucket,
+				Filename: storageSnapshotFlags.fileName,
+				Private:  storageSnapshotFlags.private,
+			},
+		})
+		if err != nil {
+			return NewError(cmd, "Exporting snapshot failed", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	storageSnapshotCreateCmd.Flags().StringVarP(&storageSnapshotFlags.name, "name", "n", "", "Name of the snapshot")
+	addCreateLabelFlag(storageSnapshotCreateCmd)
+
+	storageSnapshotRollbackCmd.Flags().BoolVarP(&storageSnapshotFlags.force, "force", "f", false, "Force a destructive operation")
+
+	storageSnapshotExportCmd.Flags().StringVar(&storageSnapshotFlags.s3Host, "s3-host", "", "Host name of the S3-compatible object storage")
+	storageSnapshotExportCmd.MarkFlagRequired("s3-host")
+	storageSnapshotExportCmd.Flags().StringVar(&storageSnapshotFlags.bucket, "bucket", "", "Name of the bucket")
+	storageSnapshotExportCmd.MarkFlagRequired("bucket")
+	storageSnapshotExportCmd.Flags().StringVar(&storageSnapshotFlags.fileName, "file", "", "Name of the exported file in the bucket")
+	storageSnapshotExportCmd.MarkFlagRequired("file")
+	storageSnapshotExportCmd.Flags().StringVar(&storageSnapshotFlags.accessKey, "access-key", "", "Access key of the object storage")
+	storageSnapshotExportCmd.Flags().StringVar(&storageSnapshotFlags.secretKey, "secret-key", "", "Secret key of the object storage")
+	storageSnapshotExportCmd.Flags().BoolVar(&storageSnapshotFlags.private, "private", true, "Whether the exported file is private")
+
+	addColumnsFlag(storageSnapshotLsCmd)
+
+	storageSnapshotCmd.AddCommand(storageSnapshotLsCmd, storageSnapshotCreateCmd, storageSnapshotRmCmd, storageSnapshotRollbackCmd, storageSnapshotExportCmd)
+	storageCmd.AddCommand(storageSnapshotCmd)
+}
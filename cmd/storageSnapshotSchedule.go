@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/spf13/cobra"
+)
+
+type snapshotScheduleCmdFlags struct {
+	name          string
+	interval      time.Duration
+	keepSnapshots int
+	nextRuntime   string
+}
+
+var (
+	snapshotScheduleFlags snapshotScheduleCmdFlags
+)
+
+var snapshotScheduleCmd = &cobra.Command{
+	Use:   "snapshot-schedule",
+	Short: "Operations on snapshot schedules",
+	Long:  `List, create, or remove schedules that take snapshots of a storage periodically.`,
+}
+
+var snapshotScheduleWideColumns = []string{"id", "name", "interval", "keep", "next run", "status", "create_time", "labels"}
+
+var snapshotScheduleLsCmd = &cobra.Command{
+	Use:     "ls [flags] STORAGE",
+	Aliases: []string{"list"},
+	Short:   "List snapshot schedules",
+	Long:    `List snapshot schedules of a storage.`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scheduleOp := rt.StorageSnapshotScheduleOperator()
+		ctx := context.Background()
+		storageID, err := idForStorage(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find storage", err)
+		}
+		out := new(bytes.Buffer)
+		schedules, err := scheduleOp.GetStorageSnapshotScheduleList(ctx, storageID)
+		if err != nil {
+			return NewError(cmd, "Could not get list of snapshot schedules", err)
+		}
+		var rows [][]string
+		heading := []string{"id", "name", "interval", "keep", "next run", "status"}
+		for _, schedule := range schedules {
+			interval := time.Duration(schedule.Properties.RunInterval) * time.Minute
+			fill := [][]string{
+				{
+					schedule.Properties.ObjectUUID,
+					schedule.Properties.Name,
+					interval.String(),
+					strconv.Itoa(schedule.Properties.KeepSnapshots),
+					schedule.Properties.NextRuntime.Local().Format(time.RFC3339),
+					schedule.Properties.Status,
+				},
+			}
+			rows = append(rows, fill...)
+		}
+		if quietOutput() {
+			for _, info := range rows {
+				fmt.Println(info[0])
+			}
+			return nil
+		}
+		heading, rows, err = listColumns(heading, rows, schedules, snapshotScheduleWideColumns)
+		if err != nil {
+			return NewError(cmd, "Could not list snapshot schedules", err)
+		}
+		err = renderOutput(out, heading, rows, schedules)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
+		}
+		fmt.Print(out)
+		return nil
+	},
+}
+
+var snapshotScheduleCreateCmd = &cobra.Command{
+	Use:     "create [flags] STORAGE",
+	Example: `gscloud storage snapshot-schedule create --name nightly --interval 24h --keep 7 b3ec341c-1732-45b3-bc45-9a7fcebb363e`,
+	Short:   "Create snapshot schedule",
+	Long: `Create a new snapshot schedule for a storage.
+
+The interval is given as duration, e.g. "90m" or "24h", and must be at least one hour. The next runtime is given in RFC 3339 format. If omitted, the first snapshot is taken right away.
+
+# EXAMPLES
+
+Take a snapshot every night at 2 AM and keep the last seven:
+
+	$ gscloud storage snapshot-schedule create \
+		--name nightly \
+		--interval 24h \
+		--keep 7 \
+		--next-runtime 2020-11-18T02:00:00+01:00 \
+		b3ec341c-1732-45b3-bc45-9a7fcebb363e
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		type output struct {
+			Schedule string `json:"snapshot_schedule"`
+		}
+
+		interval, err := toRunInterval(snapshotScheduleFlags.interval)
+		if err != nil {
+			return NewError(cmd, "Cannot create snapshot schedule", err)
+		}
+		createReq := gsclient.StorageSnapshotScheduleCreateRequest{
+			Name:          snapshotScheduleFlags.name,
+			RunInterval:   interval,
+			KeepSnapshots: snapshotScheduleFlags.keepSnapshots,
+			Labels:        createLabels(),
+		}
+		if snapshotScheduleFlags.nextRuntime != "" {
+			createReq.NextRuntime, err = toGSTime(snapshotScheduleFlags.nextRuntime)
+			if err != nil {
+				return NewError(cmd, "Cannot create snapshot schedule", err)
+			}
+		}
+		scheduleOp := rt.StorageSnapshotScheduleOperator()
+		ctx := context.Background()
+		storageID, err := idForStorage(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find storage", err)
+		}
+		schedule, err := scheduleOp.CreateStorageSnapshotSchedule(ctx, storageID, createReq)
+		if err != nil {
+			return NewError(cmd, "Creating snapshot schedule failed", err)
+		}
+		if !structuredOutput() {
+			fmt.Println("Snapshot schedule created:", schedule.ObjectUUID)
+		} else {
+			err = renderOutput(os.Stdout, nil, nil, output{Schedule: schedule.ObjectUUID})
+			if err != nil {
+				return NewError(cmd, "Could not render output", err)
+			}
+		}
+		return nil
+	},
+}
+
+var snapshotScheduleSetCmd = &cobra.Command{
+	Use:     "set [flags] STORAGE SCHEDULE",
+	Example: `gscloud storage snapshot-schedule set --keep 14 b3ec341c-1732-45b3-bc45-9a7fcebb363e 2d6a2f5e-3c1b-4b7e-8f0e-6f1a0c9d7e21`,
+	Short:   "Update snapshot schedule",
+	Long:    `Update properties of an existing snapshot schedule.`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		updateReq := gsclient.StorageSnapshotScheduleUpdateRequest{
+			Name:          snapshotScheduleFlags.name,
+			KeepSnapshots: snapshotScheduleFlags.keepSnapshots,
+		}
+		if cmd.Flags().Changed("interval") {
+			updateReq.RunInterval, err = toRunInterval(snapshotScheduleFlags.interval)
+			if err != nil {
+				return NewError(cmd, "Could not update snapshot schedule", err)
+			}
+		}
+		if snapshotScheduleFlags.nextRuntime != "" {
+			updateReq.NextRuntime, err = toGSTime(snapshotScheduleFlags.nextRuntime)
+			if err != nil {
+				return NewError(cmd, "Could not update snapshot schedule", err)
+			}
+		}
+		scheduleOp := rt.StorageSnapshotScheduleOperator()
+		ctx := context.Background()
+		storageID, err := idForStorage(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find storage", err)
+		}
+		scheduleID, err := idForSnapshotSchedule(ctx, storageID, args[1])
+		if err != nil {
+			return NewError(cmd, "Could not find snapshot schedule", err)
+		}
+		err = scheduleOp.UpdateStorageSnapshotSchedule(ctx, storageID, scheduleID, updateReq)
+		if err != nil {
+			return NewError(cmd, "Could not update snapshot schedule", err)
+		}
+		return nil
+	},
+}
+
+var snapshotScheduleRmCmd = &cobra.Command{
+	Use:     "rm [flags] STORAGE SCHEDULE",
+	Aliases: []string{"remove"},
+	Short:   "Remove snapshot schedule",
+	Long:    `Remove an existing snapshot schedule. Snapshots taken by the schedule are kept.`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scheduleOp := rt.StorageSnapshotScheduleOperator()
+		ctx := context.Background()
+		storageID, err := idForStorage(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find storage", err)
+		}
+		scheduleID, err := idForSnapshotSchedule(ctx, storageID, args[1])
+		if err != nil {
+			return NewError(cmd, "Could not find snapshot schedule", err)
+		}
+		err = scheduleOp.DeleteStorageSnapshotSchedule(ctx, storageID, scheduleID)
+		if err != nil {
+			return NewError(cmd, "Deleting snapshot schedule failed", err)
+		}
+		fmt.Fprintf(os.Stderr, "Removed %s\n", scheduleID)
+		return nil
+	},
+}
+
+func init() {
+	snapshotScheduleCreateCmd.Flags().StringVarP(&snapshotScheduleFlags.name, "name", "n", "", "Name of the snapshot schedule")
+	snapshotScheduleCreateCmd.MarkFlagRequired("name")
+	snapshotScheduleCreateCmd.Flags().DurationVar(&snapshotScheduleFlags.interval, "interval", 24*time.Hour, "Time between two snapshots")
+	snapshotScheduleCreateCmd.Flags().IntVar(&snapshotScheduleFlags.keepSnapshots, "keep", 7, "No. of snapshots to keep")
+	snapshotScheduleCreateCmd.Flags().StringVar(&snapshotScheduleFlags.nextRuntime, "next-runtime", "", "Time of the next snapshot (RFC 3339)")
+	addCreateLabelFlag(snapshotScheduleCreateCmd)
+
+	snapshotScheduleSetCmd.Flags().StringVarP(&snapshotScheduleFlags.name, "name", "n", "", "New name of the snapshot schedule")
+	snapshotScheduleSetCmd.Flags().DurationVar(&snapshotScheduleFlags.interval, "interval", 0, "Time between two snapshots")
+	snapshotScheduleSetCmd.Flags().IntVar(&snapshotScheduleFlags.keepSnapshots, "keep", 0, "No. of snapshots to keep")
+	snapshotScheduleSetCmd.Flags().StringVar(&snapshotScheduleFlags.nextRuntime, "next-runtime", "", "Time of the next snapshot (RFC 3339)")
+
+	addColumnsFlag(snapshotScheduleLsCmd)
+
+	snapshotScheduleCmd.AddCommand(snapshotScheduleLsCmd, snapshotScheduleCreateCmd, snapshotScheduleSetCmd, snapshotScheduleRmCmd)
+	storageCmd.AddCommand(snapshotScheduleCmd)
+}
+
+// toRunInterval converts a duration to a run interval in minutes as expected
+// by the API.
+func toRunInterval(d time.Duration) (int, error) {
+	if d < time.Hour {
+		return 0, errors.New("expected interval ≥ 1h")
+	}
+	return int(d / time.Minute), nil
+}
+
+func toGSTime(val string) (*gsclient.GSTime, error) {
+	t, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return nil, err
+	}
+	return &gsclient.GSTime{Time: t}, nil
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/gridscale/gscloud/runtime"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockStorageSnapshotOp struct {
+	mock.Mock
+	// Rolling back passes the storage operator to waitForStorage, which
+	// does not use it without --wait.
+	gsclient.StorageOperator
+}
+
+func (o *mockStorageSnapshotOp) GetStorageSnapshotList(ctx context.Context, id string) ([]gsclient.StorageSnapshot, error) {
+	args := o.Called(id)
+	return args.Get(0).([]gsclient.StorageSnapshot), args.Error(1)
+}
+
+func (o *mockStorageSnapshotOp) GetSnapshotsByLocation(ctx context.Context, id string) ([]gsclient.StorageSnapshot, error) {
+	return []gsclient.StorageSnapshot{}, nil
+}
+
+func (o *mockStorageSnapshotOp) GetStorageSnapshot(ctx context.Context, storageID, snapshotID string) (gsclient.StorageSnapshot, error) {
+	return gsclient.StorageSnapshot{}, nil
+}
+
+func (o *mockStorageSnapshotOp) CreateStorageSnapshot(ctx context.Context, id string, body gsclient.StorageSnapshotCreateRequest) (gsclient.StorageSnapshotCreateResponse, error) {
+	return gsclient.StorageSnapshotCreateResponse{}, nil
+}
+
+func (o *mockStorageSnapshotOp) UpdateStorageSnapshot(ctx context.Context, storageID, snapshotID string, body gsclient.StorageSnapshotUpdateRequest) error {
+	return nil
+}
+
+func (o *mockStorageSnapshotOp) DeleteStorageSnapshot(ctx context.Context, storageID, snapshotID string) error {
+	return nil
+}
+
+func (o *mockStorageSnapshotOp) GetDeletedSnapshots(ctx context.Context) ([]gsclient.StorageSnapshot, error) {
+	return []gsclient.StorageSnapshot{}, nil
+}
+
+func (o *mockStorageSnapshotOp) RollbackStorage(ctx context.Context, storageID, snapshotID string, body gsclient.StorageRollbackRequest) error {
+	args := o.Called(storageID, snapshotID)
+	return args.Error(0)
+}
+
+func (o *mockStorageSnapshotOp) ExportStorageSnapshotToS3(ctx context.Context, storageID, snapshotID string, body gsclient.StorageSnapshotExportToS3Request) error {
+	return nil
+}
+
+func Test_StorageSnapshotRollback(t *testing.T) {
+	storageID := "b3ec341c-1732-45b3-bc45-9a7fcebb363e"
+	snapshotID := "6c4bd6b4-8a7e-4f0e-9c5c-0b1f1c8e2f3a"
+	for _, force := range []bool{false, true} {
+		op := &mockStorageSnapshotOp{}
+		if force {
+			op.On("RollbackStorage", storageID, snapshotID).Return(nil)
+		}
+		rt, _ = runtime.NewTestRuntime()
+		rt.SetStorageSnapshotOperator(op)
+
+		storageSnapshotFlags.force = force
+		err := storageSnapshotRollbackCmd.RunE(new(cobra.Command), []string{storageID, snapshotID})
+		storageSnapshotFlags.force = false
+
+		assert.Nil(t, err)
+		op.AssertExpectations(t)
+		if !force {
+			op.AssertNotCalled(t, "RollbackStorage", mock.Anything, mock.Anything)
+		}
+	}
+}
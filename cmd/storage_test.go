@@ -130,6 +130,9 @@ func Test_StorageListCmd(t *testing.T) {
 }
 
 func Test_StorageCmdDelete(t *testing.T) {
+	confirmFlags.yes = true
+	defer func() { confirmFlags.yes = false }()
+
 	type testCase struct {
 		expectedOutput string
 	}
@@ -141,9 +144,94 @@ func Test_StorageCmdDelete(t *testing.T) {
 	rt.SetStorageOperator(mockClient)
 
 	cmd := storageRmCmd.RunE
-	cmd(new(cobra.Command), []string{"rm", mockStorage.Properties.ObjectUUID})
+	cmd(new(cobra.Command), []string{mockStorage.Properties.ObjectUUID})
 
 	w.Close()
 	out, _ := ioutil.ReadAll(r)
 	assert.Equal(t, err.expectedOutput, string(out))
 }
+
+func Test_StorageListCmdWithSchedules(t *testing.T) {
+	buf := new(bytes.Buffer)
+	headers := []string{"id", "name", "capacity", "changed", "status", "schedules"}
+	rows := [][]string{
+		{
+			"xxx-xxx-xxx",
+			"test",
+			"10",
+			changeTime.Local().Format(time.RFC3339),
+			"active",
+			"0",
+		},
+	}
+	render.AsTable(buf, headers, rows, render.Options{})
+
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	storageFlags.withSchedules = true
+	rt, _ = runtime.NewTestRuntime()
+	rt.SetStorageOperator(mockClient{})
+
+	cmd := storageLsCmd.RunE
+	cmd(new(cobra.Command), []string{})
+
+	storageFlags.withSchedules = false
+
+	w.Close()
+	out, _ := ioutil.ReadAll(r)
+	assert.Equal(t, buf.String(), string(out))
+}
+
+func Test_ToStorageType(t *testing.T) {
+	testCases := []struct {
+		Value         string
+		Expected      gsclient.StorageType
+		ExpectedError bool
+	}{
+		{Value: "storage", Expected: gsclient.DefaultStorageType},
+		{Value: "storage_high", Expected: gsclient.HighStorageType},
+		{Value: "storage_insane", Expected: gsclient.InsaneStorageType},
+		{Value: "ssd", ExpectedError: true},
+	}
+	for _, test := range testCases {
+		storageType, err := toStorageType(test.Value)
+		assert.Equal(t, test.ExpectedError, err != nil)
+		assert.Equal(t, test.Expected, storageType)
+	}
+}
+
+type cloneStorageOp struct {
+	mockClient
+	labels []string
+}
+
+func (o *cloneStorageOp) GetStorage(ctx context.Context, id string) (gsclient.Storage, error) {
+	var storage gsclient.Storage
+	storage.Properties.ObjectUUID = id
+	storage.Properties.Labels = []string{"env=staging", "team=shop"}
+	return storage, nil
+}
+
+func (o *cloneStorageOp) CloneStorage(ctx context.Context, id string) (gsclient.CreateResponse, error) {
+	return gsclient.CreateResponse{ObjectUUID: "c2e5a0b4-7f3d-4e1a-9b6c-8d2f0e4a1b3c"}, nil
+}
+
+func (o *cloneStorageOp) UpdateStorage(ctx context.Context, id string, body gsclient.StorageUpdateRequest) error {
+	o.labels = *body.Labels
+	return nil
+}
+
+func Test_StorageCloneLabels(t *testing.T) {
+	defer func() { labelFlags = labelCmdFlags{} }()
+	labelFlags.create = []string{"env=test"}
+	rt, _ = runtime.NewTestRuntime()
+	op := &cloneStorageOp{}
+	rt.SetStorageOperator(op)
+	stdout := os.Stdout
+	defer func() { os.Stdout = stdout }()
+	os.Stdout, _ = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	err := storageCloneCmd.RunE(new(cobra.Command), []string{"479b7973-376a-4b23-98fc-50e94131a6e3"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"team=shop", "env=test"}, op.labels)
+}
@@ -6,9 +6,10 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/gridscale/gscloud/render"
+	"github.com/gridscale/gsclient-go/v3"
 	"github.com/spf13/cobra"
 )
 
@@ -18,6 +19,8 @@ var templateCmd = &cobra.Command{
 	Long:  `List templates.`,
 }
 
+var templateWideColumns = []string{"id", "name", "capacity", "changed", "description", "distro", "version", "private", "location", "labels"}
+
 var templateLsCmd = &cobra.Command{
 	Use:     "ls [flags]",
 	Aliases: []string{"list"},
@@ -32,54 +35,102 @@ var templateLsCmd = &cobra.Command{
 			return NewError(cmd, "Could not get templates", err)
 		}
 		var rows [][]string
-		if rootFlags.json {
-			render.AsJSON(out, templates)
-		} else {
-			heading := []string{"id", "name", "capacity", "changed", "description"}
-			for _, template := range templates {
-				fill := [][]string{
-					{
-						template.Properties.ObjectUUID,
-						template.Properties.Name,
-						strconv.FormatInt(int64(template.Properties.Capacity), 10),
-						template.Properties.ChangeTime.Local().Format(time.RFC3339),
-						template.Properties.Description,
-					},
-				}
-				rows = append(rows, fill...)
+		var labels [][]string
+		heading := []string{"id", "name", "capacity", "changed", "description"}
+		for _, template := range templates {
+			fill := [][]string{
+				{
+					template.Properties.ObjectUUID,
+					template.Properties.Name,
+					strconv.FormatInt(int64(template.Properties.Capacity), 10),
+					template.Properties.ChangeTime.Local().Format(time.RFC3339),
+					template.Properties.Description,
+				},
 			}
-			if rootFlags.quiet {
-				for _, info := range rows {
-					fmt.Println(info[0])
-				}
-				return nil
+			rows = append(rows, fill...)
+			labels = append(labels, template.Properties.Labels)
+		}
+		order, err := listOrder(heading, rows, labels, templates)
+		if err != nil {
+			return NewError(cmd, "Could not list templates", err)
+		}
+		rows = selectRows(rows, order)
+		selected := make([]gsclient.Template, 0, len(order))
+		for _, i := range order {
+			selected = append(selected, templates[i])
+		}
+		if quietOutput() {
+			for _, info := range rows {
+				fmt.Println(info[0])
 			}
-			render.AsTable(out, heading, rows, renderOpts)
+			return nil
+		}
+		heading, rows, err = listColumns(heading, rows, selected, templateWideColumns)
+		if err != nil {
+			return NewError(cmd, "Could not list templates", err)
+		}
+		err = renderOutput(out, heading, rows, selected)
+		if err != nil {
+			return NewError(cmd, "Could not render output", err)
 		}
 		fmt.Print(out)
 		return nil
 	},
 }
 
+// describeTemplate describes a template to be removed.
+func describeTemplate(t gsclient.Template) removal {
+	return removal{
+		kind: "template",
+		name: t.Properties.Name,
+		id:   t.Properties.ObjectUUID,
+		details: []string{
+			fmt.Sprintf("size: %d GB", t.Properties.Capacity),
+			"OS: " + strings.TrimSpace(t.Properties.Ostype+" "+t.Properties.Version),
+		},
+	}
+}
+
 var templateRmCmd = &cobra.Command{
-	Use:     "rm [flags] [ID]",
+	Use:     "rm [flags] [ID...]",
 	Aliases: []string{"remove"},
 	Short:   "Remove templates",
-	Long:    `Remove a template by ID.`,
-	Args:    cobra.ExactArgs(1),
+	Long:    `Remove a template by ID. Its name and size are shown and you are asked for confirmation first, unless --yes is given. See confirm_destructive in gscloud-config(1) for when confirmation is needed. Protected templates are not removed, see gscloud-protect(1).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		storageOp := rt.TemplateOperator()
 		ctx := context.Background()
-		err := storageOp.DeleteTemplate(ctx, args[0])
+		id, err := idForTemplate(ctx, args[0])
+		if err != nil {
+			return NewError(cmd, "Could not find template", err)
+		}
+		template, err := storageOp.GetTemplate(ctx, id)
+		if err != nil {
+			return NewError(cmd, "Could not get template", err)
+		}
+		err = checkProtected("template", template.Properties.Name, template.Properties.Labels)
+		if err != nil {
+			return NewError(cmd, "Removing template failed", err)
+		}
+		err = confirmRemoval(func() (removal, error) {
+			return describeTemplate(template), nil
+		})
+		if err != nil {
+			return NewError(cmd, "Removing template failed", err)
+		}
+		err = storageOp.DeleteTemplate(ctx, id)
 		if err != nil {
 			return NewError(cmd, "Deleting template failed", err)
 		}
-		fmt.Fprintf(os.Stderr, "Removed %s\n", args[0])
+		fmt.Fprintf(os.Stderr, "Removed %s\n", id)
 		return nil
 	},
 }
 
 func init() {
+	addListFlags(templateLsCmd)
+	addConfirmFlags(templateRmCmd)
+	addBulkFlags(templateRmCmd, "template")
+
 	templateCmd.AddCommand(templateLsCmd, templateRmCmd)
 	rootCmd.AddCommand(templateCmd)
 }
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/gridscale/gscloud/runtime"
+)
+
+// exitCodeTimeout is the exit code used when waiting for an object timed out.
+const exitCodeTimeout = 5
+
+// TimeoutError is returned when an object did not reach the expected state
+// within the time given by --timeout.
+type TimeoutError struct {
+	What    string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for %s", e.Timeout, e.What)
+}
+
+// pollInterval is the time between two status requests while waiting for an
+// object to become ready.
+var pollInterval = 2 * time.Second
+
+// progressWriter receives the progress indicator while waiting.
+var progressWriter io.Writer = os.Stderr
+
+// waitFor calls done repeatedly until it reports true, returns an error, or
+// the timeout given by --timeout expires. A progress indicator is written to
+// stderr meanwhile.
+func waitFor(ctx context.Context, what string, done func(ctx context.Context) (bool, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, rootFlags.timeout)
+	defer cancel()
+
+	if !rootFlags.quiet {
+		fmt.Fprintf(progressWriter, "Waiting for %s", what)
+		defer fmt.Fprintln(progressWriter)
+	}
+	for {
+		ok, err := done(ctx)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return &TimeoutError{What: what, Timeout: rootFlags.timeout}
+		}
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if !rootFlags.quiet {
+			fmt.Fprint(progressWriter, ".")
+		}
+		select {
+		case <-ctx.Done():
+			return &TimeoutError{What: what, Timeout: rootFlags.timeout}
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// waitForServerPower blocks until the server with given ID has the given
+// power state. Does nothing unless --wait is given.
+func waitForServerPower(ctx context.Context, op gsclient.ServerOperator, id string, power bool) error {
+	if !rootFlags.wait {
+		return nil
+	}
+	state := "off"
+	if power {
+		state = "on"
+	}
+	return waitFor(ctx, fmt.Sprintf("server %s to power %s", id, state), func(ctx context.Context) (bool, error) {
+		server, err := op.GetServer(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		return server.Properties.Power == power, nil
+	})
+}
+
+// waitForServer blocks until the server with given ID is active. Does
+// nothing unless --wait is given.
+func waitForServer(ctx context.Context, op gsclient.ServerOperator, id string) error {
+	if !rootFlags.wait {
+		return nil
+	}
+	return waitFor(ctx, fmt.Sprintf("server %s", id), func(ctx context.Context) (bool, error) {
+		server, err := op.GetServer(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		return server.Properties.Status == "active", nil
+	})
+}
+
+// waitForStorage blocks until the storage with given ID is active. Does
+// nothing unless --wait is given.
+func waitForStorage(ctx context.Context, op gsclient.StorageOperator, id string) error {
+	if !rootFlags.wait {
+		return nil
+	}
+	return waitFor(ctx, fmt.Sprintf("storage %s", id), func(ctx context.Context) (bool, error) {
+		storage, err := op.GetStorage(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		return storage.Properties.Status == "active", nil
+	})
+}
+
+// waitForLoadBalancer blocks until the load balancer with given ID is
+// active. Does nothing unless --wait is given.
+func waitForLoadBalancer(ctx context.Context, op gsclient.LoadBalancerOperator, id string) error {
+	if !rootFlags.wait {
+		return nil
+	}
+	return waitFor(ctx, fmt.Sprintf("load balancer %s", id), func(ctx context.Context) (bool, error) {
+		lb, err := op.GetLoadBalancer(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		return lb.Properties.Status == "active", nil
+	})
+}
+
+// waitForSnapshot blocks until the snapshot with given ID is active. Does
+// nothing unless --wait is given.
+func waitForSnapshot(ctx context.Context, op gsclient.StorageSnapshotOperator, storageID, id string) error {
+	if !rootFlags.wait {
+		return nil
+	}
+	return waitForSnapshotActive(ctx, op, storageID, id)
+}
+
+// waitForSnapshotActive blocks until the snapshot with given ID is active,
+// whether or not --wait is given.
+func waitForSnapshotActive(ctx context.Context, op gsclient.StorageSnapshotOperator, storageID, id string) error {
+	return waitFor(ctx, fmt.Sprintf("snapshot %s", id), func(ctx context.Context) (bool, error) {
+		snapshot, err := op.GetStorageSnapshot(ctx, storageID, id)
+		if err != nil {
+			return false, err
+		}
+		return snapshot.Properties.Status == "active", nil
+	})
+}
+
+// waitForNetwork blocks until the network with given ID is active. Does
+// nothing unless --wait is given.
+func waitForNetwork(ctx context.Context, op gsclient.NetworkOperator, id string) error {
+	if !rootFlags.wait {
+		return nil
+	}
+	return waitFor(ctx, fmt.Sprintf("network %s", id), func(ctx context.Context) (bool, error) {
+		network, err := op.GetNetwork(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		return network.Properties.Status == "active", nil
+	})
+}
+
+// waitForPaaSService blocks until the platform service with given ID is
+// active. Does nothing unless --wait is given.
+func waitForPaaSService(ctx context.Context, op runtime.KubernetesOperator, id string) error {
+	if !rootFlags.wait {
+		return nil
+	}
+	return waitFor(ctx, fmt.Sprintf("platform service %s", id), func(ctx context.Context) (bool, error) {
+		service, err := op.GetPaaSService(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		return service.Properties.Status == "active", nil
+	})
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupWaitTest(t *testing.T) {
+	oldInterval, oldWriter, oldTimeout := pollInterval, progressWriter, rootFlags.timeout
+	pollInterval = time.Millisecond
+	progressWriter = ioutil.Discard
+	rootFlags.timeout = 50 * time.Millisecond
+	t.Cleanup(func() {
+		pollInterval, progressWriter, rootFlags.timeout = oldInterval, oldWriter, oldTimeout
+	})
+}
+
+func Test_WaitForDone(t *testing.T) {
+	setupWaitTest(t)
+	calls := 0
+	err := waitFor(context.Background(), "test", func(ctx context.Context) (bool, error) {
+		calls++
+		return calls == 3, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func Test_WaitForError(t *testing.T) {
+	setupWaitTest(t)
+	err := waitFor(context.Background(), "test", func(ctx context.Context) (bool, error) {
+		return false, errors.New("test")
+	})
+	assert.EqualError(t, err, "test")
+}
+
+func Test_WaitForTimeout(t *testing.T) {
+	setupWaitTest(t)
+	err := waitFor(context.Background(), "test", func(ctx context.Context) (bool, error) {
+		return false, nil
+	})
+	var timeoutErr *TimeoutError
+	assert.True(t, errors.As(err, &timeoutErr))
+	assert.Equal(t, "test", timeoutErr.What)
+}
+
+func Test_WaitDisabled(t *testing.T) {
+	rootFlags.wait = false
+	assert.Nil(t, waitForServer(context.Background(), nil, "id"))
+}
@@ -5,17 +5,89 @@
 package render
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
 	"strings"
+	"text/template"
 
 	"github.com/gridscale/gscloud/render/table"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
 )
 
 // Options holds parameters for rendering.
 type Options struct {
 	NoHeader bool
+
+	// Output selects the output format. One of "table" (the default),
+	// "wide", "json", "yaml", "csv", "tsv", "go-template=TEMPLATE", or
+	// "jsonpath=EXPRESSION".
+	Output string
+}
+
+// Formats lists the output formats known to Render.
+var Formats = []string{"table", "wide", "json", "yaml", "csv", "tsv", "go-template=...", "jsonpath=..."}
+
+// format splits the output format into its name and argument, if any.
+func (o Options) format() (string, string) {
+	parts := strings.SplitN(o.Output, "=", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// Tabular tells whether the output format is made from columns and rows,
+// rather than from the objects themselves.
+func (o Options) Tabular() bool {
+	switch name, _ := o.format(); name {
+	case "", "table", "wide", "csv", "tsv":
+		return true
+	}
+	return false
+}
+
+// Validate returns an error if the output format is unknown or its template
+// or expression cannot be parsed.
+func (o Options) Validate() error {
+	name, arg := o.format()
+	switch name {
+	case "", "table", "wide", "json", "yaml", "csv", "tsv":
+		return nil
+	case "go-template":
+		_, err := template.New("output").Parse(arg)
+		return err
+	case "jsonpath":
+		return jsonpath.New("output").Parse(jsonPathExpression(arg))
+	}
+	return fmt.Errorf("unknown output format %q, expected one of: %s", o.Output, strings.Join(Formats, ", "))
+}
+
+// Render writes output in the format selected by opts.Output. Tabular
+// formats are made from columns and rows, all other formats from objs.
+func Render(buf io.Writer, columns []string, rows [][]string, objs interface{}, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	switch name, arg := opts.format(); name {
+	case "json":
+		AsJSON(buf, objs)
+	case "yaml":
+		return AsYAML(buf, objs)
+	case "csv":
+		return AsCSV(buf, columns, rows, opts)
+	case "tsv":
+		return AsTSV(buf, columns, rows, opts)
+	case "go-template":
+		return AsTemplate(buf, objs, arg)
+	case "jsonpath":
+		return AsJSONPath(buf, objs, arg)
+	default:
+		AsTable(buf, columns, rows, opts)
+	}
+	return nil
 }
 
 // AsTable prints header and rows as table to given io.Writer.
@@ -48,6 +120,106 @@ func AsJSON(buf io.Writer, o interface{}) {
 	buf.Write(append(json, '\n'))
 }
 
+// AsYAML prints objects as YAML to given io.Writer.
+func AsYAML(buf io.Writer, o interface{}) error {
+	out, err := yaml.Marshal(o)
+	if err != nil {
+		return err
+	}
+	_, err = buf.Write(out)
+	return err
+}
+
+// AsCSV prints header and rows as comma-separated values (RFC 4180) to given
+// io.Writer. Fields containing commas, quotes, or line breaks are quoted.
+func AsCSV(buf io.Writer, columns []string, rows [][]string, opts Options) error {
+	w := csv.NewWriter(buf)
+	if !opts.NoHeader {
+		w.Write(columns)
+	}
+	w.WriteAll(rows)
+	return w.Error()
+}
+
+// tsvEscaper escapes the characters that cannot appear within a field of
+// tab-separated values.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// AsTSV prints header and rows as tab-separated values to given io.Writer.
+// Tabs, line breaks, and backslashes within fields are written as \t, \n,
+// \r, and \\, so that every line holds exactly one row.
+func AsTSV(buf io.Writer, columns []string, rows [][]string, opts Options) error {
+	write := func(fields []string) error {
+		escaped := make([]string, len(fields))
+		for i, f := range fields {
+			escaped[i] = tsvEscaper.Replace(f)
+		}
+		_, err := io.WriteString(buf, strings.Join(escaped, "\t")+"\n")
+		return err
+	}
+	if !opts.NoHeader {
+		if err := write(columns); err != nil {
+			return err
+		}
+	}
+	for _, row := range rows {
+		if err := write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AsTemplate executes the Go template text with objects to given io.Writer.
+// Objects are passed to the template the way they appear in JSON, so fields
+// are referred to by their JSON names.
+func AsTemplate(buf io.Writer, o interface{}, text string) error {
+	tmpl, err := template.New("output").Parse(text)
+	if err != nil {
+		return err
+	}
+	data, err := asGeneric(o)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(buf, data)
+}
+
+// AsJSONPath prints the values found by the JSONPath expression in objects
+// to given io.Writer. As with AsTemplate, fields are referred to by their
+// JSON names.
+func AsJSONPath(buf io.Writer, o interface{}, expr string) error {
+	jp := jsonpath.New("output").AllowMissingKeys(true)
+	if err := jp.Parse(jsonPathExpression(expr)); err != nil {
+		return err
+	}
+	data, err := asGeneric(o)
+	if err != nil {
+		return err
+	}
+	return jp.Execute(buf, data)
+}
+
+// jsonPathExpression wraps expr in braces unless it contains any. This
+// allows to write "jsonpath=.name" instead of "jsonpath={.name}".
+func jsonPathExpression(expr string) string {
+	if strings.Contains(expr, "{") {
+		return expr
+	}
+	return "{" + expr + "}"
+}
+
+// asGeneric converts o to the maps and slices it is decoded to from JSON.
+func asGeneric(o interface{}) (interface{}, error) {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	err = json.Unmarshal(data, &generic)
+	return generic, err
+}
+
 func init() {
 	table.DefaultHeaderFormatter = func(format string, vals ...interface{}) string {
 		return strings.ToUpper(fmt.Sprintf(format, vals...))
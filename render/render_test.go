@@ -2,10 +2,12 @@ package render
 
 import (
 	"bytes"
+	"encoding/csv"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
 )
 
 func Test_AsTable(t *testing.T) {
@@ -40,3 +42,111 @@ func Test_AsJSON(t *testing.T) {
 	AsJSON(buffer, val)
 	assert.Equal(t, expectedOutput, buffer.String())
 }
+
+type testObject struct {
+	Properties struct {
+		Name     string `json:"name"`
+		Capacity int    `json:"capacity"`
+	} `json:"storage"`
+}
+
+func testObjects() []testObject {
+	objs := make([]testObject, 2)
+	objs[0].Properties.Name = "a"
+	objs[0].Properties.Capacity = 10
+	objs[1].Properties.Name = "b, c"
+	objs[1].Properties.Capacity = 20
+	return objs
+}
+
+func Test_Render(t *testing.T) {
+	columns := []string{"name", "capacity"}
+	rows := [][]string{{"a", "10"}, {"b, c", "20"}}
+	type testCase struct {
+		output   string
+		expected string
+	}
+	testCases := []testCase{
+		{output: "json", expected: `[{"storage":{"name":"a","capacity":10}},{"storage":{"name":"b, c","capacity":20}}]` + "\n"},
+		{output: "yaml", expected: "- storage:\n    capacity: 10\n    name: a\n- storage:\n    capacity: 20\n    name: b, c\n"},
+		{output: "csv", expected: "name,capacity\na,10\n\"b, c\",20\n"},
+		{output: "go-template={{range .}}{{.storage.name}}={{.storage.capacity}};{{end}}", expected: "a=10;b, c=20;"},
+		{output: "jsonpath={[*].storage.name}", expected: "a b, c"},
+		{output: "jsonpath=[1].storage.capacity", expected: "20"},
+	}
+	for _, tc := range testCases {
+		out := new(bytes.Buffer)
+		err := Render(out, columns, rows, testObjects(), Options{Output: tc.output})
+		assert.Nil(t, err, tc.output)
+		assert.Equal(t, tc.expected, out.String(), tc.output)
+	}
+
+	for _, output := range []string{"", "table", "wide"} {
+		out := new(bytes.Buffer)
+		err := Render(out, columns, rows, testObjects(), Options{Output: output})
+		assert.Nil(t, err)
+		assert.Equal(t, "NAME", strings.Fields(out.String())[0])
+	}
+}
+
+func Test_OptionsValidate(t *testing.T) {
+	for _, output := range []string{"", "table", "wide", "json", "yaml", "csv", "go-template={{.}}", "jsonpath={.a}"} {
+		assert.Nil(t, Options{Output: output}.Validate(), output)
+	}
+	for _, output := range []string{"xml", "go-template={{", "jsonpath={.a"} {
+		assert.NotNil(t, Options{Output: output}.Validate(), output)
+	}
+}
+
+func Test_OptionsTabular(t *testing.T) {
+	assert.True(t, Options{}.Tabular())
+	assert.True(t, Options{Output: "csv"}.Tabular())
+	assert.False(t, Options{Output: "json"}.Tabular())
+	assert.False(t, Options{Output: "jsonpath={.a}"}.Tabular())
+}
+
+func Test_AsCSVQuoting(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := AsCSV(out, []string{"id", "name"}, [][]string{
+		{"1", "web, db"},
+		{"2", "line 1\nline 2"},
+		{"3", `say "hi"`},
+	}, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, "id,name\n1,\"web, db\"\n2,\"line 1\nline 2\"\n3,\"say \"\"hi\"\"\"\n", out.String())
+
+	records, err := csv.NewReader(out).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, "line 1\nline 2", records[2][1])
+}
+
+func Test_AsTSV(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := AsTSV(out, []string{"id", "name"}, [][]string{
+		{"1", "web\tdb"},
+		{"2", "line 1\nline 2"},
+		{"3", `C:\temp`},
+	}, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, "id\tname\n1\tweb\\tdb\n2\tline 1\\nline 2\n3\tC:\\\\temp\n", out.String())
+
+	out.Reset()
+	err = AsTSV(out, []string{"id"}, [][]string{{"1"}}, Options{NoHeader: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "1\n", out.String())
+}
+
+func Test_AsYAMLQuoting(t *testing.T) {
+	type someStruct struct {
+		Name string `json:"name"`
+	}
+	for _, name := range []string{"web, db", "line 1\nline 2", "yes", "- item", "#comment"} {
+		out := new(bytes.Buffer)
+		err := AsYAML(out, []someStruct{{Name: name}})
+		assert.Nil(t, err)
+
+		var parsed []someStruct
+		assert.Nil(t, yaml.Unmarshal(out.Bytes(), &parsed))
+		assert.Equal(t, name, parsed[0].Name)
+	}
+}
@@ -102,8 +102,10 @@ type WidthFunc func(string) int
 // will be output as its string representation as described in the fmt standard
 // package. Rows can have less cells than the total number of columns in the table;
 // subsequent cells will be rendered empty. Rows with more cells than the total
-// number of columns will be truncated. References to the data are not held, so
-// the passed in values can be modified without affecting the table's output.
+// number of columns will be truncated. Tabs and line breaks within cells are
+// replaced by blanks, so that every row takes a single line. References to the
+// data are not held, so the passed in values can be modified without affecting
+// the table's output.
 //
 //   New("foo", "bar").AddRow("fizz", "buzz").AddRow(time.Now()).AddRow(1, 2, 3).Print()
 //   // Output:
@@ -114,7 +116,8 @@ type WidthFunc func(string) int
 //
 // Print writes the string representation of the table to the provided writer.
 // Print can be called multiple times, even after subsequent mutations of the
-// provided data. The output is always preceded and followed by a new line.
+// provided data. Every row is followed by a new line. A table without columns
+// prints nothing.
 type Table interface {
 	WithHeaderFormatter(f Formatter) Table
 	WithFirstColumnFormatter(f Formatter) Table
@@ -139,7 +142,7 @@ func New(columnHeaders ...interface{}) Table {
 	t.WithWidthFunc(DefaultWidthFunc)
 
 	for i, col := range columnHeaders {
-		t.header[i] = fmt.Sprint(col)
+		t.header[i] = cell(col)
 	}
 
 	return &t
@@ -196,7 +199,7 @@ func (t *table) AddRow(vals ...interface{}) Table {
 		if i >= len(t.header) {
 			break
 		}
-		row[i] = fmt.Sprint(val)
+		row[i] = cell(val)
 	}
 	t.rows = append(t.rows, row)
 
@@ -204,6 +207,9 @@ func (t *table) AddRow(vals ...interface{}) Table {
 }
 
 func (t *table) Print(includeHeader bool) {
+	if len(t.header) == 0 {
+		return
+	}
 	format := strings.Repeat("%s", len(t.header)) + "\n"
 	t.calculateWidths()
 
@@ -267,3 +273,12 @@ func (t *table) lenOffset(s string, w int) string {
 	}
 	return strings.Repeat(" ", l)
 }
+
+// cellReplacer replaces the characters that would break a row across lines
+// or misalign its columns.
+var cellReplacer = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ")
+
+// cell returns the string representation of val as printed in a cell.
+func cell(val interface{}) string {
+	return cellReplacer.Replace(fmt.Sprint(val))
+}
@@ -181,3 +181,32 @@ func TestTable_WithWidthFunc(t *testing.T) {
 	assert.Contains(t, actual, "请求 alpha")
 	assert.Contains(t, actual, "abc  beta")
 }
+
+func TestTable_ManyColumns(t *testing.T) {
+	t.Parallel()
+
+	var header, short, long []interface{}
+	for i := 0; i < 20; i++ {
+		header = append(header, fmt.Sprintf("col%d", i))
+		short = append(short, "x")
+		long = append(long, strings.Repeat("y", i*5))
+	}
+	buf := bytes.Buffer{}
+	New(header...).WithWriter(&buf).AddRow(short...).AddRow(long...).Print(includeHeader)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, strings.Index(lines[0], "col19"), strings.LastIndex(lines[1], "x"))
+	assert.Equal(t, strings.Index(lines[0], "col19"), strings.Index(lines[2], strings.Repeat("y", 95)))
+}
+
+func TestTable_LineBreaks(t *testing.T) {
+	t.Parallel()
+
+	buf := bytes.Buffer{}
+	New("foo", "bar").WithWriter(&buf).AddRow("a\nb", "c\td").Print(includeHeader)
+
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+	assert.Contains(t, buf.String(), "a b")
+	assert.Contains(t, buf.String(), "c d")
+}
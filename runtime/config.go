@@ -1,13 +1,16 @@
 package runtime
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 
 	"github.com/kirsle/configdir"
 	"github.com/spf13/viper"
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -15,17 +18,62 @@ const (
 	configPath    = "gridscale"
 )
 
-// AccountEntry represents a single account in the config file.
+// ProjectEntry represents a single project in the config file. Token is
+// either the API token itself or a reference to it, see ResolveSecret.
 type ProjectEntry struct {
-	Name   string `yaml:"name" json:"name"`
-	UserID string `yaml:"userId" json:"userId"`
-	Token  string `yaml:"token" json:"token"`
-	URL    string `yaml:"url" json:"url"`
+	Name     string          `yaml:"name" json:"name"`
+	UserID   string          `yaml:"userId" json:"userId"`
+	Token    string          `yaml:"token" json:"token"`
+	URL      string          `yaml:"url" json:"url"`
+	Defaults ProjectDefaults `yaml:"defaults,omitempty" json:"defaults,omitempty"`
+}
+
+// ProjectDefaults are used in place of flags not given on the command line.
+// Output is the default output format, and Labels are given to all objects
+// created. Any other key names an object type, e.g. "server", and holds the
+// default values of flags of its create command, e.g. "cores".
+type ProjectDefaults struct {
+	Output string                 `yaml:"output,omitempty" json:"output,omitempty"`
+	Labels []string               `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Flags  map[string]interface{} `yaml:",inline" json:"-" mapstructure:",remain"`
 }
 
 // Config are all configuration settings parsed from a configuration file.
+// DefaultProject is the project used unless another one is selected.
+// ConfirmDestructive tells when removing objects needs to be confirmed, one
+// of ConfirmAlways, ConfirmNever, or ConfirmTTY, the default. Objects with
+// the label ProtectLabel, DefaultProtectLabel by default, are not removed.
 type Config struct {
-	Projects []ProjectEntry `yaml:"projects"`
+	DefaultProject     string         `yaml:"defaultProject,omitempty"`
+	ConfirmDestructive string         `yaml:"confirm_destructive,omitempty" mapstructure:"confirm_destructive"`
+	ProtectLabel       string         `yaml:"protect_label,omitempty" mapstructure:"protect_label"`
+	Projects           []ProjectEntry `yaml:"projects"`
+}
+
+// DefaultProtectLabel is the label protecting objects from being removed,
+// unless another one is configured.
+const DefaultProtectLabel = "gscloud/protect=true"
+
+// Settings of ConfirmDestructive.
+const (
+	// ConfirmAlways asks for confirmation, reading the answer from standard
+	// input even if it is not a terminal.
+	ConfirmAlways = "always"
+	// ConfirmNever removes objects without asking.
+	ConfirmNever = "never"
+	// ConfirmTTY asks for confirmation if standard input is a terminal, and
+	// refuses to remove objects otherwise.
+	ConfirmTTY = "tty"
+)
+
+// Project returns the project called name, or nil if there is none.
+func (c *Config) Project(name string) *ProjectEntry {
+	for i := range c.Projects {
+		if c.Projects[i].Name == name {
+			return &c.Projects[i]
+		}
+	}
+	return nil
 }
 
 // OldConfig are all configuration settings parsed from an old configuration file
@@ -75,18 +123,139 @@ func ParseConfig() (*Config, error) {
 	return &conf, nil
 }
 
+// WriteConfig writes conf to the configuration file at filePath. If the
+// file exists, comments and keys gscloud does not know about are kept, and
+// projects stay under "accounts" in an old configuration file.
 func WriteConfig(conf *Config, filePath string) error {
 	err := os.MkdirAll(filepath.Dir(filePath), os.FileMode(0700))
 	if err != nil {
 		return err
 	}
 
-	c, _ := yaml.Marshal(conf)
+	var doc yaml.Node
+	err = doc.Encode(conf)
+	if err != nil {
+		return err
+	}
+	if data, err := ioutil.ReadFile(filePath); err == nil {
+		doc = mergeConfig(data, doc)
+	}
+	var c bytes.Buffer
+	enc := yaml.NewEncoder(&c)
+	enc.SetIndent(2)
+	err = enc.Encode(&doc)
+	if err != nil {
+		return err
+	}
+	err = enc.Close()
+	if err != nil {
+		return err
+	}
 
-	err = ioutil.WriteFile(filePath, c, 0644)
+	// The file may hold API tokens, so only the user may read it. WriteFile
+	// keeps the mode of an existing file.
+	err = ioutil.WriteFile(filePath, c.Bytes(), 0600)
 	if err != nil {
 		return err
 	}
 
+	return os.Chmod(filePath, 0600)
+}
+
+// mergeConfig returns the configuration conf merged into the configuration
+// file data. Top-level keys that are not fields of Config are kept, as well
+// as the comments of everything that is still there. If data cannot be
+// parsed, conf is returned as it is.
+func mergeConfig(data []byte, conf yaml.Node) yaml.Node {
+	var doc yaml.Node
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil || doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return conf
+	}
+	old := doc.Content[0]
+	if mappingValue(old, "projects") == nil && mappingValue(old, "accounts") != nil {
+		for i := 0; i < len(conf.Content); i += 2 {
+			if conf.Content[i].Value == "projects" {
+				conf.Content[i].Value = "accounts"
+			}
+		}
+	}
+	known := map[string]bool{"accounts": true}
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		known[strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]] = true
+	}
+	doc.Content[0] = mergeNode(old, &conf, func(key string) bool { return !known[key] })
+	return doc
+}
+
+// mergeNode returns the node n with the comments of the node old it
+// replaces. Mappings are merged key by key, keeping the order of old and the
+// keys of old for which keep is true. Items of sequences are merged with the
+// item of old that has the same name.
+func mergeNode(old, n *yaml.Node, keep func(string) bool) *yaml.Node {
+	merged := *n
+	merged.HeadComment = old.HeadComment
+	merged.LineComment = old.LineComment
+	merged.FootComment = old.FootComment
+	if old.Kind != n.Kind {
+		return &merged
+	}
+	dropAll := func(string) bool { return false }
+	switch n.Kind {
+	case yaml.MappingNode:
+		merged.Content = nil
+		added := map[string]bool{}
+		for i := 0; i+1 < len(old.Content); i += 2 {
+			key := old.Content[i].Value
+			if val := mappingValue(n, key); val != nil {
+				merged.Content = append(merged.Content, old.Content[i], mergeNode(old.Content[i+1], val, dropAll))
+				added[key] = true
+			} else if keep(key) {
+				merged.Content = append(merged.Content, old.Content[i], old.Content[i+1])
+			}
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if !added[n.Content[i].Value] {
+				merged.Content = append(merged.Content, n.Content[i], n.Content[i+1])
+			}
+		}
+	case yaml.SequenceNode:
+		merged.Content = nil
+		for _, item := range n.Content {
+			oldItem := itemNamed(old, mappingValue(item, "name"))
+			if oldItem != nil {
+				item = mergeNode(oldItem, item, dropAll)
+			}
+			merged.Content = append(merged.Content, item)
+		}
+	}
+	return &merged
+}
+
+// mappingValue returns the value of key in the mapping n, or nil.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// itemNamed returns the item of the sequence n whose name is the same as
+// name, or nil.
+func itemNamed(n *yaml.Node, name *yaml.Node) *yaml.Node {
+	if name == nil {
+		return nil
+	}
+	for _, item := range n.Content {
+		if v := mappingValue(item, "name"); v != nil && v.Value == name.Value {
+			return item
+		}
+	}
 	return nil
 }
@@ -0,0 +1,504 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sync"
+
+	"github.com/gridscale/gsclient-go/v3"
+)
+
+// Paths of the API objects, as used by gsclient.
+const (
+	serverPath       = "/objects/servers"
+	storagePath      = "/objects/storages"
+	networkPath      = "/objects/networks"
+	ipPath           = "/objects/ips"
+	sshKeyPath       = "/objects/sshkeys"
+	templatePath     = "/objects/templates"
+	loadBalancerPath = "/objects/loadbalancers"
+	paasPath         = "/objects/paas"
+	isoImagePath     = "/objects/isoimages"
+	firewallPath     = "/objects/firewalls"
+)
+
+// Request is an API request that changes objects. In a dry run, requests are
+// recorded instead of being sent.
+type Request struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Body   interface{} `json:"body,omitempty"`
+}
+
+// dryRunClient sends requests that only read objects to the API and records
+// all others. Objects it pretends to create get IDs made up of zeros and a
+// serial number, so that they can be told apart in later requests.
+type dryRunClient struct {
+	*gsclient.Client
+
+	mu       sync.Mutex
+	requests []Request
+	lastID   int
+}
+
+// EnableDryRun makes the runtime record requests that change objects instead
+// of sending them. Requests that only read objects are still sent.
+func (r *Runtime) EnableDryRun() {
+	if _, ok := r.client.(*dryRunClient); ok {
+		return
+	}
+	r.client = &dryRunClient{Client: r.Client()}
+}
+
+// DryRunRequests returns the requests recorded since EnableDryRun, in the
+// order they were made.
+func (r *Runtime) DryRunRequests() []Request {
+	c, ok := r.client.(*dryRunClient)
+	if !ok {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Request(nil), c.requests...)
+}
+
+// record adds a request.
+func (c *dryRunClient) record(method, uri string, body interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, Request{Method: method, Path: uri, Body: body})
+}
+
+// create adds a request creating an object and returns the ID the object
+// is given.
+func (c *dryRunClient) create(method, uri string, body interface{}) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, Request{Method: method, Path: uri, Body: body})
+	c.lastID++
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", c.lastID)
+}
+
+// CreateServer records creating a server.
+func (c *dryRunClient) CreateServer(ctx context.Context, body gsclient.ServerCreateRequest) (gsclient.ServerCreateResponse, error) {
+	id := c.create(http.MethodPost, serverPath, body)
+	return gsclient.ServerCreateResponse{ObjectUUID: id, ServerUUID: id}, nil
+}
+
+// UpdateServer records changing a server.
+func (c *dryRunClient) UpdateServer(ctx context.Context, id string, body gsclient.ServerUpdateRequest) error {
+	c.record(http.MethodPatch, path.Join(serverPath, id), body)
+	return nil
+}
+
+// DeleteServer records removing a server.
+func (c *dryRunClient) DeleteServer(ctx context.Context, id string) error {
+	c.record(http.MethodDelete, path.Join(serverPath, id), nil)
+	return nil
+}
+
+// StartServer records powering a server on, unless it is on already.
+func (c *dryRunClient) StartServer(ctx context.Context, id string) error {
+	return c.setServerPower(ctx, id, true)
+}
+
+// StopServer records powering a server off, unless it is off already.
+func (c *dryRunClient) StopServer(ctx context.Context, id string) error {
+	return c.setServerPower(ctx, id, false)
+}
+
+func (c *dryRunClient) setServerPower(ctx context.Context, id string, power bool) error {
+	on, err := c.Client.IsServerOn(ctx, id)
+	if err != nil {
+		return err
+	}
+	if on != power {
+		c.record(http.MethodPatch, path.Join(serverPath, id, "power"), gsclient.ServerPowerUpdateRequest{Power: power})
+	}
+	return nil
+}
+
+// ShutdownServer records shutting a server down, unless it is off already.
+func (c *dryRunClient) ShutdownServer(ctx context.Context, id string) error {
+	server, err := c.Client.GetServer(ctx, id)
+	if err != nil {
+		return err
+	}
+	if server.Properties.Power {
+		c.record(http.MethodPatch, path.Join(serverPath, id, "shutdown"), map[string]string{})
+	}
+	return nil
+}
+
+// CreateStorage records creating a storage.
+func (c *dryRunClient) CreateStorage(ctx context.Context, body gsclient.StorageCreateRequest) (gsclient.CreateResponse, error) {
+	return gsclient.CreateResponse{ObjectUUID: c.create(http.MethodPost, storagePath, body)}, nil
+}
+
+// CreateStorageFromBackup records creating a storage from a backup.
+func (c *dryRunClient) CreateStorageFromBackup(ctx context.Context, backupID, storageName string) (gsclient.CreateResponse, error) {
+	body := gsclient.CreateStorageFromBackupRequest{
+		RequestProperties: gsclient.CreateStorageFromBackupProperties{
+			Name:       storageName,
+			BackupUUID: backupID,
+		},
+	}
+	return gsclient.CreateResponse{ObjectUUID: c.create(http.MethodPost, path.Join(storagePath, "import"), body)}, nil
+}
+
+// UpdateStorage records changing a storage.
+func (c *dryRunClient) UpdateStorage(ctx context.Context, id string, body gsclient.StorageUpdateRequest) error {
+	c.record(http.MethodPatch, path.Join(storagePath, id), body)
+	return nil
+}
+
+// CloneStorage records cloning a storage.
+func (c *dryRunClient) CloneStorage(ctx context.Context, id string) (gsclient.CreateResponse, error) {
+	return gsclient.CreateResponse{ObjectUUID: c.create(http.MethodPost, path.Join(storagePath, id, "clone"), nil)}, nil
+}
+
+// DeleteStorage records removing a storage.
+func (c *dryRunClient) DeleteStorage(ctx context.Context, id string) error {
+	c.record(http.MethodDelete, path.Join(storagePath, id), nil)
+	return nil
+}
+
+// CreateTemplate records creating a template.
+func (c *dryRunClient) CreateTemplate(ctx context.Context, body gsclient.TemplateCreateRequest) (gsclient.CreateResponse, error) {
+	return gsclient.CreateResponse{ObjectUUID: c.create(http.MethodPost, templatePath, body)}, nil
+}
+
+// UpdateTemplate records changing a template.
+func (c *dryRunClient) UpdateTemplate(ctx context.Context, id string, body gsclient.TemplateUpdateRequest) error {
+	c.record(http.MethodPatch, path.Join(templatePath, id), body)
+	return nil
+}
+
+// DeleteTemplate records removing a template.
+func (c *dryRunClient) DeleteTemplate(ctx context.Context, id string) error {
+	c.record(http.MethodDelete, path.Join(templatePath, id), nil)
+	return nil
+}
+
+// CreateSshkey records creating an SSH key.
+func (c *dryRunClient) CreateSshkey(ctx context.Context, body gsclient.SshkeyCreateRequest) (gsclient.CreateResponse, error) {
+	return gsclient.CreateResponse{ObjectUUID: c.create(http.MethodPost, sshKeyPath, body)}, nil
+}
+
+// UpdateSshkey records changing an SSH key.
+func (c *dryRunClient) UpdateSshkey(ctx context.Context, id string, body gsclient.SshkeyUpdateRequest) error {
+	c.record(http.MethodPatch, path.Join(sshKeyPath, id), body)
+	return nil
+}
+
+// DeleteSshkey records removing an SSH key.
+func (c *dryRunClient) DeleteSshkey(ctx context.Context, id string) error {
+	c.record(http.MethodDelete, path.Join(sshKeyPath, id), nil)
+	return nil
+}
+
+// CreateISOImage records creating an ISO image.
+func (c *dryRunClient) CreateISOImage(ctx context.Context, body gsclient.ISOImageCreateRequest) (gsclient.ISOImageCreateResponse, error) {
+	return gsclient.ISOImageCreateResponse{ObjectUUID: c.create(http.MethodPost, isoImagePath, body)}, nil
+}
+
+// UpdateISOImage records changing an ISO image.
+func (c *dryRunClient) UpdateISOImage(ctx context.Context, id string, body gsclient.ISOImageUpdateRequest) error {
+	c.record(http.MethodPatch, path.Join(isoImagePath, id), body)
+	return nil
+}
+
+// DeleteISOImage records removing an ISO image.
+func (c *dryRunClient) DeleteISOImage(ctx context.Context, id string) error {
+	c.record(http.MethodDelete, path.Join(isoImagePath, id), nil)
+	return nil
+}
+
+// CreateNetwork records creating a network.
+func (c *dryRunClient) CreateNetwork(ctx context.Context, body gsclient.NetworkCreateRequest) (gsclient.NetworkCreateResponse, error) {
+	return gsclient.NetworkCreateResponse{ObjectUUID: c.create(http.MethodPost, networkPath, body)}, nil
+}
+
+// UpdateNetwork records changing a network.
+func (c *dryRunClient) UpdateNetwork(ctx context.Context, id string, body gsclient.NetworkUpdateRequest) error {
+	c.record(http.MethodPatch, path.Join(networkPath, id), body)
+	return nil
+}
+
+// DeleteNetwork records removing a network.
+func (c *dryRunClient) DeleteNetwork(ctx context.Context, id string) error {
+	c.record(http.MethodDelete, path.Join(networkPath, id), nil)
+	return nil
+}
+
+// UpdateNetworkPinnedServer records pinning a server to a network.
+func (c *dryRunClient) UpdateNetworkPinnedServer(ctx context.Context, networkUUID, serverUUID string, body gsclient.PinServerRequest) error {
+	c.record(http.MethodPatch, path.Join(networkPath, networkUUID, "pinned_servers", serverUUID), body)
+	return nil
+}
+
+// DeleteNetworkPinnedServer records unpinning a server from a network.
+func (c *dryRunClient) DeleteNetworkPinnedServer(ctx context.Context, networkUUID, serverUUID string) error {
+	c.record(http.MethodDelete, path.Join(networkPath, networkUUID, "pinned_servers", serverUUID), nil)
+	return nil
+}
+
+// CreateIP records creating an IP address.
+func (c *dryRunClient) CreateIP(ctx context.Context, body gsclient.IPCreateRequest) (gsclient.IPCreateResponse, error) {
+	return gsclient.IPCreateResponse{ObjectUUID: c.create(http.MethodPost, ipPath, body)}, nil
+}
+
+// UpdateIP records changing an IP address.
+func (c *dryRunClient) UpdateIP(ctx context.Context, id string, body gsclient.IPUpdateRequest) error {
+	c.record(http.MethodPatch, path.Join(ipPath, id), body)
+	return nil
+}
+
+// DeleteIP records removing an IP address.
+func (c *dryRunClient) DeleteIP(ctx context.Context, id string) error {
+	c.record(http.MethodDelete, path.Join(ipPath, id), nil)
+	return nil
+}
+
+// CreateLoadBalancer records creating a load balancer.
+func (c *dryRunClient) CreateLoadBalancer(ctx context.Context, body gsclient.LoadBalancerCreateRequest) (gsclient.LoadBalancerCreateResponse, error) {
+	return gsclient.LoadBalancerCreateResponse{ObjectUUID: c.create(http.MethodPost, loadBalancerPath, body)}, nil
+}
+
+// UpdateLoadBalancer records changing a load balancer.
+func (c *dryRunClient) UpdateLoadBalancer(ctx context.Context, id string, body gsclient.LoadBalancerUpdateRequest) error {
+	c.record(http.MethodPatch, path.Join(loadBalancerPath, id), body)
+	return nil
+}
+
+// DeleteLoadBalancer records removing a load balancer.
+func (c *dryRunClient) DeleteLoadBalancer(ctx context.Context, id string) error {
+	c.record(http.MethodDelete, path.Join(loadBalancerPath, id), nil)
+	return nil
+}
+
+// CreateFirewall records creating a firewall.
+func (c *dryRunClient) CreateFirewall(ctx context.Context, body gsclient.FirewallCreateRequest) (gsclient.FirewallCreateResponse, error) {
+	return gsclient.FirewallCreateResponse{ObjectUUID: c.create(http.MethodPost, firewallPath, body)}, nil
+}
+
+// UpdateFirewall records changing a firewall.
+func (c *dryRunClient) UpdateFirewall(ctx context.Context, id string, body gsclient.FirewallUpdateRequest) error {
+	c.record(http.MethodPatch, path.Join(firewallPath, id), body)
+	return nil
+}
+
+// DeleteFirewall records removing a firewall.
+func (c *dryRunClient) DeleteFirewall(ctx context.Context, id string) error {
+	c.record(http.MethodDelete, path.Join(firewallPath, id), nil)
+	return nil
+}
+
+// CreateStorageSnapshot records taking a snapshot of a storage.
+func (c *dryRunClient) CreateStorageSnapshot(ctx context.Context, id string, body gsclient.StorageSnapshotCreateRequest) (gsclient.StorageSnapshotCreateResponse, error) {
+	return gsclient.StorageSnapshotCreateResponse{ObjectUUID: c.create(http.MethodPost, path.Join(storagePath, id, "snapshots"), body)}, nil
+}
+
+// UpdateStorageSnapshot records changing a snapshot.
+func (c *dryRunClient) UpdateStorageSnapshot(ctx context.Context, storageID, snapshotID string, body gsclient.StorageSnapshotUpdateRequest) error {
+	c.record(http.MethodPatch, path.Join(storagePath, storageID, "snapshots", snapshotID), body)
+	return nil
+}
+
+// DeleteStorageSnapshot records removing a snapshot.
+func (c *dryRunClient) DeleteStorageSnapshot(ctx context.Context, storageID, snapshotID string) error {
+	c.record(http.MethodDelete, path.Join(storagePath, storageID, "snapshots", snapshotID), nil)
+	return nil
+}
+
+// RollbackStorage records rolling a storage back to a snapshot.
+func (c *dryRunClient) RollbackStorage(ctx context.Context, storageID, snapshotID string, body gsclient.StorageRollbackRequest) error {
+	c.record(http.MethodPatch, path.Join(storagePath, storageID, "snapshots", snapshotID, "rollback"), body)
+	return nil
+}
+
+// ExportStorageSnapshotToS3 records exporting a snapshot to S3.
+func (c *dryRunClient) ExportStorageSnapshotToS3(ctx context.Context, storageID, snapshotID string, body gsclient.StorageSnapshotExportToS3Request) error {
+	c.record(http.MethodPatch, path.Join(storagePath, storageID, "snapshots", snapshotID, "export_to_s3"), body)
+	return nil
+}
+
+// CreateStorageSnapshotSchedule records creating a snapshot schedule.
+func (c *dryRunClient) CreateStorageSnapshotSchedule(ctx context.Context, id string, body gsclient.StorageSnapshotScheduleCreateRequest) (gsclient.StorageSnapshotScheduleCreateResponse, error) {
+	return gsclient.StorageSnapshotScheduleCreateResponse{ObjectUUID: c.create(http.MethodPost, path.Join(storagePath, id, "snapshot_schedules"), body)}, nil
+}
+
+// UpdateStorageSnapshotSchedule records changing a snapshot schedule.
+func (c *dryRunClient) UpdateStorageSnapshotSchedule(ctx context.Context, storageID, scheduleID string, body gsclient.StorageSnapshotScheduleUpdateRequest) error {
+	c.record(http.MethodPatch, path.Join(storagePath, storageID, "snapshot_schedules", scheduleID), body)
+	return nil
+}
+
+// DeleteStorageSnapshotSchedule records removing a snapshot schedule.
+func (c *dryRunClient) DeleteStorageSnapshotSchedule(ctx context.Context, storageID, scheduleID string) error {
+	c.record(http.MethodDelete, path.Join(storagePath, storageID, "snapshot_schedules", scheduleID), nil)
+	return nil
+}
+
+// CreateServerStorage records attaching a storage to a server.
+func (c *dryRunClient) CreateServerStorage(ctx context.Context, id string, body gsclient.ServerStorageRelationCreateRequest) error {
+	c.record(http.MethodPost, path.Join(serverPath, id, "storages"), body)
+	return nil
+}
+
+// UpdateServerStorage records changing how a storage is attached to a server.
+func (c *dryRunClient) UpdateServerStorage(ctx context.Context, serverID, storageID string, body gsclient.ServerStorageRelationUpdateRequest) error {
+	c.record(http.MethodPatch, path.Join(serverPath, serverID, "storages", storageID), body)
+	return nil
+}
+
+// DeleteServerStorage records detaching a storage from a server.
+func (c *dryRunClient) DeleteServerStorage(ctx context.Context, serverID, storageID string) error {
+	c.record(http.MethodDelete, path.Join(serverPath, serverID, "storages", storageID), nil)
+	return nil
+}
+
+// LinkStorage records attaching a storage to a server.
+func (c *dryRunClient) LinkStorage(ctx context.Context, serverID string, storageID string, bootdevice bool) error {
+	return c.CreateServerStorage(ctx, serverID, gsclient.ServerStorageRelationCreateRequest{
+		ObjectUUID: storageID,
+		BootDevice: bootdevice,
+	})
+}
+
+// UnlinkStorage records detaching a storage from a server.
+func (c *dryRunClient) UnlinkStorage(ctx context.Context, serverID string, storageID string) error {
+	return c.DeleteServerStorage(ctx, serverID, storageID)
+}
+
+// CreateServerIsoImage records inserting an ISO image into a server.
+func (c *dryRunClient) CreateServerIsoImage(ctx context.Context, id string, body gsclient.ServerIsoImageRelationCreateRequest) error {
+	c.record(http.MethodPost, path.Join(serverPath, id, "isoimages"), body)
+	return nil
+}
+
+// UpdateServerIsoImage records changing how an ISO image is inserted into a
+// server.
+func (c *dryRunClient) UpdateServerIsoImage(ctx context.Context, serverID, isoImageID string, body gsclient.ServerIsoImageRelationUpdateRequest) error {
+	c.record(http.MethodPatch, path.Join(serverPath, serverID, "isoimages", isoImageID), body)
+	return nil
+}
+
+// DeleteServerIsoImage records ejecting an ISO image from a server.
+func (c *dryRunClient) DeleteServerIsoImage(ctx context.Context, serverID, isoImageID string) error {
+	c.record(http.MethodDelete, path.Join(serverPath, serverID, "isoimages", isoImageID), nil)
+	return nil
+}
+
+// LinkIsoImage records inserting an ISO image into a server.
+func (c *dryRunClient) LinkIsoImage(ctx context.Context, serverID string, isoimageID string) error {
+	return c.CreateServerIsoImage(ctx, serverID, gsclient.ServerIsoImageRelationCreateRequest{ObjectUUID: isoimageID})
+}
+
+// UnlinkIsoImage records ejecting an ISO image from a server.
+func (c *dryRunClient) UnlinkIsoImage(ctx context.Context, serverID string, isoimageID string) error {
+	return c.DeleteServerIsoImage(ctx, serverID, isoimageID)
+}
+
+// CreateServerNetwork records connecting a server to a network.
+func (c *dryRunClient) CreateServerNetwork(ctx context.Context, id string, body gsclient.ServerNetworkRelationCreateRequest) error {
+	c.record(http.MethodPost, path.Join(serverPath, id, "networks"), body)
+	return nil
+}
+
+// UpdateServerNetwork records changing how a server is connected to a network.
+func (c *dryRunClient) UpdateServerNetwork(ctx context.Context, serverID, networkID string, body gsclient.ServerNetworkRelationUpdateRequest) error {
+	c.record(http.MethodPatch, path.Join(serverPath, serverID, "networks", networkID), body)
+	return nil
+}
+
+// DeleteServerNetwork records disconnecting a server from a network.
+func (c *dryRunClient) DeleteServerNetwork(ctx context.Context, serverID, networkID string) error {
+	c.record(http.MethodDelete, path.Join(serverPath, serverID, "networks", networkID), nil)
+	return nil
+}
+
+// LinkNetwork records connecting a server to a network.
+func (c *dryRunClient) LinkNetwork(ctx context.Context, serverID, networkID, firewallTemplate string, bootdevice bool, order int,
+	l3security []string, firewall *gsclient.FirewallRules) error {
+	return c.CreateServerNetwork(ctx, serverID, gsclient.ServerNetworkRelationCreateRequest{
+		ObjectUUID:           networkID,
+		Ordering:             order,
+		BootDevice:           bootdevice,
+		L3security:           l3security,
+		FirewallTemplateUUID: firewallTemplate,
+		Firewall:             firewall,
+	})
+}
+
+// UnlinkNetwork records disconnecting a server from a network.
+func (c *dryRunClient) UnlinkNetwork(ctx context.Context, serverID string, networkID string) error {
+	return c.DeleteServerNetwork(ctx, serverID, networkID)
+}
+
+// CreateServerIP records assigning an IP address to a server.
+func (c *dryRunClient) CreateServerIP(ctx context.Context, id string, body gsclient.ServerIPRelationCreateRequest) error {
+	c.record(http.MethodPost, path.Join(serverPath, id, "ips"), body)
+	return nil
+}
+
+// DeleteServerIP records releasing an IP address from a server.
+func (c *dryRunClient) DeleteServerIP(ctx context.Context, serverID, ipID string) error {
+	c.record(http.MethodDelete, path.Join(serverPath, serverID, "ips", ipID), nil)
+	return nil
+}
+
+// LinkIP records assigning an IP address to a server.
+func (c *dryRunClient) LinkIP(ctx context.Context, serverID string, ipID string) error {
+	return c.CreateServerIP(ctx, serverID, gsclient.ServerIPRelationCreateRequest{ObjectUUID: ipID})
+}
+
+// UnlinkIP records releasing an IP address from a server.
+func (c *dryRunClient) UnlinkIP(ctx context.Context, serverID string, ipID string) error {
+	return c.DeleteServerIP(ctx, serverID, ipID)
+}
+
+// CreatePaaSService records creating a PaaS service.
+func (c *dryRunClient) CreatePaaSService(ctx context.Context, body gsclient.PaaSServiceCreateRequest) (gsclient.PaaSServiceCreateResponse, error) {
+	id := c.create(http.MethodPost, path.Join(paasPath, "services"), body)
+	return gsclient.PaaSServiceCreateResponse{ObjectUUID: id, PaaSServiceUUID: id}, nil
+}
+
+// UpdatePaaSService records changing a PaaS service.
+func (c *dryRunClient) UpdatePaaSService(ctx context.Context, id string, body gsclient.PaaSServiceUpdateRequest) error {
+	c.record(http.MethodPatch, path.Join(paasPath, "services", id), body)
+	return nil
+}
+
+// DeletePaaSService records removing a PaaS service.
+func (c *dryRunClient) DeletePaaSService(ctx context.Context, id string) error {
+	c.record(http.MethodDelete, path.Join(paasPath, "services", id), nil)
+	return nil
+}
+
+// RenewK8sCredentials records renewing the credentials of a Kubernetes
+// cluster.
+func (c *dryRunClient) RenewK8sCredentials(ctx context.Context, id string) error {
+	c.record(http.MethodPatch, path.Join(paasPath, "services", id, "renew_credentials"), struct{}{})
+	return nil
+}
+
+// CreatePaaSSecurityZone records creating a PaaS security zone.
+func (c *dryRunClient) CreatePaaSSecurityZone(ctx context.Context, body gsclient.PaaSSecurityZoneCreateRequest) (gsclient.PaaSSecurityZoneCreateResponse, error) {
+	id := c.create(http.MethodPost, path.Join(paasPath, "security_zones"), body)
+	return gsclient.PaaSSecurityZoneCreateResponse{ObjectUUID: id, PaaSSecurityZoneUUID: id}, nil
+}
+
+// UpdatePaaSSecurityZone records changing a PaaS security zone.
+func (c *dryRunClient) UpdatePaaSSecurityZone(ctx context.Context, id string, body gsclient.PaaSSecurityZoneUpdateRequest) error {
+	c.record(http.MethodPatch, path.Join(paasPath, "security_zones", id), body)
+	return nil
+}
+
+// DeletePaaSSecurityZone records removing a PaaS security zone.
+func (c *dryRunClient) DeletePaaSSecurityZone(ctx context.Context, id string) error {
+	c.record(http.MethodDelete, path.Join(paasPath, "security_zones", id), nil)
+	return nil
+}
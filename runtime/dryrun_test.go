@@ -0,0 +1,54 @@
+package runtime
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gridscale/gsclient-go/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DryRun(t *testing.T) {
+	const serverID = "37d53278-8e5f-47e1-a63f-54513e4b4d53"
+	const ipID = "8e0c2ba1-4e4c-43d8-a8ba-3f5c2a7b8d60"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"server": {"object_uuid": "` + serverID + `", "power": false}}`))
+	}))
+	defer srv.Close()
+
+	rt := &Runtime{client: newClient(ProjectEntry{URL: srv.URL, UserID: "u", Token: "t"})}
+	assert.Nil(t, rt.DryRunRequests())
+	rt.EnableDryRun()
+	ctx := context.Background()
+
+	resp, err := rt.StorageOperator().CreateStorage(ctx, gsclient.StorageCreateRequest{Name: "disk", Capacity: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, "00000000-0000-4000-8000-000000000001", resp.ObjectUUID)
+	err = rt.ServerStorageRelationOperator().LinkStorage(ctx, serverID, resp.ObjectUUID, true)
+	assert.Nil(t, err)
+	err = rt.ServerIPRelationOperator().UnlinkIP(ctx, serverID, ipID)
+	assert.Nil(t, err)
+	err = rt.ServerOperator().StartServer(ctx, serverID)
+	assert.Nil(t, err)
+	// Already off.
+	err = rt.ServerOperator().StopServer(ctx, serverID)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []Request{
+		{Method: "POST", Path: "/objects/storages", Body: gsclient.StorageCreateRequest{Name: "disk", Capacity: 10}},
+		{Method: "POST", Path: "/objects/servers/" + serverID + "/storages", Body: gsclient.ServerStorageRelationCreateRequest{
+			ObjectUUID: "00000000-0000-4000-8000-000000000001",
+			BootDevice: true,
+		}},
+		{Method: "DELETE", Path: "/objects/servers/" + serverID + "/ips/" + ipID},
+		{Method: "PATCH", Path: "/objects/servers/" + serverID + "/power", Body: gsclient.ServerPowerUpdateRequest{Power: true}},
+	}, rt.DryRunRequests())
+}
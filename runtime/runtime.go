@@ -15,6 +15,8 @@ import (
 type Runtime struct {
 	account ProjectEntry
 	client  interface{}
+	confirm string
+	protect string
 }
 
 // KubernetesOperator amalgamates operations for Kubernetes PaaS.
@@ -23,12 +25,21 @@ type KubernetesOperator interface {
 	GetPaaSService(ctx context.Context, id string) (gsclient.PaaSService, error)
 }
 
+// StorageSnapshotScheduleOperator amalgamates operations on storage snapshot
+// schedules. gsclient.StorageSnapshotScheduleOperator lacks the return values
+// of CreateStorageSnapshotSchedule and UpdateStorageSnapshotSchedule and
+// cannot be implemented by gsclient.Client.
+type StorageSnapshotScheduleOperator interface {
+	GetStorageSnapshotScheduleList(ctx context.Context, id string) ([]gsclient.StorageSnapshotSchedule, error)
+	GetStorageSnapshotSchedule(ctx context.Context, storageID, scheduleID string) (gsclient.StorageSnapshotSchedule, error)
+	CreateStorageSnapshotSchedule(ctx context.Context, id string, body gsclient.StorageSnapshotScheduleCreateRequest) (gsclient.StorageSnapshotScheduleCreateResponse, error)
+	UpdateStorageSnapshotSchedule(ctx context.Context, storageID, scheduleID string, body gsclient.StorageSnapshotScheduleUpdateRequest) error
+	DeleteStorageSnapshotSchedule(ctx context.Context, storageID, scheduleID string) error
+}
+
 // PaaSOperator return an operation to Get a PaaS.
 func (r *Runtime) PaaSOperator() gsclient.PaaSOperator {
-	if utils.UnderTest() {
-		return r.client.(gsclient.PaaSOperator)
-	}
-	return r.client.(*gsclient.Client)
+	return r.client.(gsclient.PaaSOperator)
 }
 
 // SetPaaSOperator set operation to Create PaaS.
@@ -44,17 +55,35 @@ func (r *Runtime) Project() ProjectEntry {
 	return r.account
 }
 
-// Client provides access to the API client.
+// ConfirmDestructive tells when removing objects needs to be confirmed, one
+// of ConfirmAlways, ConfirmNever, or ConfirmTTY.
+func (r *Runtime) ConfirmDestructive() string {
+	if r.confirm == "" {
+		return ConfirmTTY
+	}
+	return r.confirm
+}
+
+// ProtectLabel returns the label protecting objects from being removed.
+func (r *Runtime) ProtectLabel() string {
+	if r.protect == "" {
+		return DefaultProtectLabel
+	}
+	return r.protect
+}
+
+// Client provides access to the API client. In a dry run, requests sent
+// through it directly are not recorded but sent.
 func (r *Runtime) Client() *gsclient.Client {
+	if c, ok := r.client.(*dryRunClient); ok {
+		return c.Client
+	}
 	return r.client.(*gsclient.Client)
 }
 
 // ServerIPRelationOperator return an operation to remove a storage.
 func (r *Runtime) ServerIPRelationOperator() gsclient.ServerIPRelationOperator {
-	if utils.UnderTest() {
-		return r.client.(gsclient.ServerIPRelationOperator)
-	}
-	return r.client.(*gsclient.Client)
+	return r.client.(gsclient.ServerIPRelationOperator)
 }
 
 // SetServerIPRelationOperator set operation to delete storages.
@@ -67,10 +96,7 @@ func (r *Runtime) SetServerIPRelationOperator(op gsclient.ServerIPRelationOperat
 
 // StorageOperator return an operation to remove a storage.
 func (r *Runtime) StorageOperator() gsclient.StorageOperator {
-	if utils.UnderTest() {
-		return r.client.(gsclient.StorageOperator)
-	}
-	return r.client.(*gsclient.Client)
+	return r.client.(gsclient.StorageOperator)
 }
 
 // SetStorageOperator set operation to delete storages.
@@ -83,10 +109,7 @@ func (r *Runtime) SetStorageOperator(op gsclient.StorageOperator) {
 
 // TemplateOperator return an operation to remove a storage.
 func (r *Runtime) TemplateOperator() gsclient.TemplateOperator {
-	if utils.UnderTest() {
-		return r.client.(gsclient.TemplateOperator)
-	}
-	return r.client.(*gsclient.Client)
+	return r.client.(gsclient.TemplateOperator)
 }
 
 // SetTemplateOperator set operation to delete storages.
@@ -99,10 +122,7 @@ func (r *Runtime) SetTemplateOperator(op gsclient.TemplateOperator) {
 
 // KubernetesOperator return operation relating to Kubernetes managed services.
 func (r *Runtime) KubernetesOperator() KubernetesOperator {
-	if utils.UnderTest() {
-		return r.client.(KubernetesOperator)
-	}
-	return r.client.(*gsclient.Client)
+	return r.client.(KubernetesOperator)
 }
 
 // SetKubernetesOperator set Kubernetes PaaS operation.
@@ -115,10 +135,7 @@ func (r *Runtime) SetKubernetesOperator(op KubernetesOperator) {
 
 // SSHKeyOperator return operation to manipulate SSH keys.
 func (r *Runtime) SSHKeyOperator() gsclient.SSHKeyOperator {
-	if utils.UnderTest() {
-		return r.client.(gsclient.SSHKeyOperator)
-	}
-	return r.client.(*gsclient.Client)
+	return r.client.(gsclient.SSHKeyOperator)
 }
 
 // SetSSHKeyOperator set operation to manipulate SSH keys.
@@ -131,10 +148,7 @@ func (r *Runtime) SetSSHKeyOperator(op gsclient.SSHKeyOperator) {
 
 // ServerOperator return operation for server objects.
 func (r *Runtime) ServerOperator() gsclient.ServerOperator {
-	if utils.UnderTest() {
-		return r.client.(gsclient.ServerOperator)
-	}
-	return r.client.(*gsclient.Client)
+	return r.client.(gsclient.ServerOperator)
 }
 
 // SetServerOperator set operation for server objects.
@@ -147,10 +161,7 @@ func (r *Runtime) SetServerOperator(op gsclient.ServerOperator) {
 
 // ISOImageOperator return operation for server objects.
 func (r *Runtime) ISOImageOperator() gsclient.ISOImageOperator {
-	if utils.UnderTest() {
-		return r.client.(gsclient.ISOImageOperator)
-	}
-	return r.client.(*gsclient.Client)
+	return r.client.(gsclient.ISOImageOperator)
 }
 
 // SetISOImageOperator set operation for ISO image objects.
@@ -163,10 +174,7 @@ func (r *Runtime) SetISOImageOperator(op gsclient.ISOImageOperator) {
 
 // NetworkOperator return operations for network objects.
 func (r *Runtime) NetworkOperator() gsclient.NetworkOperator {
-	if utils.UnderTest() {
-		return r.client.(gsclient.NetworkOperator)
-	}
-	return r.client.(*gsclient.Client)
+	return r.client.(gsclient.NetworkOperator)
 }
 
 // SetNetworkOperator set operations to work on network objects.
@@ -179,10 +187,7 @@ func (r *Runtime) SetNetworkOperator(op gsclient.NetworkOperator) {
 
 // IPOperator return operations to manipulate IP addresses.
 func (r *Runtime) IPOperator() gsclient.IPOperator {
-	if utils.UnderTest() {
-		return r.client.(gsclient.IPOperator)
-	}
-	return r.client.(*gsclient.Client)
+	return r.client.(gsclient.IPOperator)
 }
 
 // SetIPOperator set operations to manipulate IP addresses.
@@ -195,10 +200,7 @@ func (r *Runtime) SetIPOperator(op gsclient.IPOperator) {
 
 // ServerStorageRelationOperator return an operation to associate server objects with storages.
 func (r *Runtime) ServerStorageRelationOperator() gsclient.ServerStorageRelationOperator {
-	if utils.UnderTest() {
-		return r.client.(gsclient.ServerStorageRelationOperator)
-	}
-	return r.client.(*gsclient.Client)
+	return r.client.(gsclient.ServerStorageRelationOperator)
 }
 
 // SetServerStorageRelationOperator set operation to delete storages.
@@ -209,19 +211,99 @@ func (r *Runtime) SetServerStorageRelationOperator(op gsclient.ServerStorageRela
 	r.client = op
 }
 
+// LoadBalancerOperator return operations for load balancer objects.
+func (r *Runtime) LoadBalancerOperator() gsclient.LoadBalancerOperator {
+	return r.client.(gsclient.LoadBalancerOperator)
+}
+
+// SetLoadBalancerOperator set operations to work on load balancer objects.
+func (r *Runtime) SetLoadBalancerOperator(op gsclient.LoadBalancerOperator) {
+	if !utils.UnderTest() {
+		panic("unexpected use")
+	}
+	r.client = op
+}
+
+// FirewallOperator return operations for firewall objects.
+func (r *Runtime) FirewallOperator() gsclient.FirewallOperator {
+	return r.client.(gsclient.FirewallOperator)
+}
+
+// SetFirewallOperator set operations to work on firewall objects.
+func (r *Runtime) SetFirewallOperator(op gsclient.FirewallOperator) {
+	if !utils.UnderTest() {
+		panic("unexpected use")
+	}
+	r.client = op
+}
+
+// StorageSnapshotOperator return operations for storage snapshots.
+func (r *Runtime) StorageSnapshotOperator() gsclient.StorageSnapshotOperator {
+	return r.client.(gsclient.StorageSnapshotOperator)
+}
+
+// SetStorageSnapshotOperator set operations to work on storage snapshots.
+func (r *Runtime) SetStorageSnapshotOperator(op gsclient.StorageSnapshotOperator) {
+	if !utils.UnderTest() {
+		panic("unexpected use")
+	}
+	r.client = op
+}
+
+// StorageSnapshotScheduleOperator return operations for storage snapshot schedules.
+func (r *Runtime) StorageSnapshotScheduleOperator() StorageSnapshotScheduleOperator {
+	return r.client.(StorageSnapshotScheduleOperator)
+}
+
+// SetStorageSnapshotScheduleOperator set operations to work on storage snapshot schedules.
+func (r *Runtime) SetStorageSnapshotScheduleOperator(op StorageSnapshotScheduleOperator) {
+	if !utils.UnderTest() {
+		panic("unexpected use")
+	}
+	r.client = op
+}
+
+// ServerIsoImageRelationOperator return an operation to associate server objects with ISO images.
+func (r *Runtime) ServerIsoImageRelationOperator() gsclient.ServerIsoImageRelationOperator {
+	return r.client.(gsclient.ServerIsoImageRelationOperator)
+}
+
+// SetServerIsoImageRelationOperator set operation to associate server objects with ISO images.
+func (r *Runtime) SetServerIsoImageRelationOperator(op gsclient.ServerIsoImageRelationOperator) {
+	if !utils.UnderTest() {
+		panic("unexpected use")
+	}
+	r.client = op
+}
+
+// ServerNetworkRelationOperator return an operation to associate server objects with networks.
+func (r *Runtime) ServerNetworkRelationOperator() gsclient.ServerNetworkRelationOperator {
+	return r.client.(gsclient.ServerNetworkRelationOperator)
+}
+
+// SetServerNetworkRelationOperator set operation to associate server objects with networks.
+func (r *Runtime) SetServerNetworkRelationOperator(op gsclient.ServerNetworkRelationOperator) {
+	if !utils.UnderTest() {
+		panic("unexpected use")
+	}
+	r.client = op
+}
+
 // NewRuntime creates a new runtime for a given account. Usually there should be
 // only one runtime instance in the program.
 func NewRuntime(conf Config, accountName string, commandWithoutConfig bool) (*Runtime, error) {
 	var ac ProjectEntry
+	found := false
 
 	for _, a := range conf.Projects {
 		if accountName == a.Name {
 			ac = a
+			found = true
 			break
 		}
 	}
 
-	if (ac == ProjectEntry{}) {
+	if !found {
 		if len(conf.Projects) > 0 && !commandWithoutConfig {
 			return nil, fmt.Errorf("account '%s' does not exist", accountName)
 		}
@@ -229,14 +311,31 @@ func NewRuntime(conf Config, accountName string, commandWithoutConfig bool) (*Ru
 
 	ac = LoadEnvVariables(ac)
 
+	token, err := ResolveSecret(ac.Token)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get token of account '%s': %w", accountName, err)
+	}
+	ac.Token = token
+
 	if ac.UserID == "" || ac.Token == "" {
 		return nil, errors.New("cannot find UserID or Token in config file or environment variables")
 	}
 
+	confirm := conf.ConfirmDestructive
+	switch confirm {
+	case "":
+		confirm = ConfirmTTY
+	case ConfirmAlways, ConfirmNever, ConfirmTTY:
+	default:
+		return nil, fmt.Errorf("confirm_destructive must be one of %s, %s, or %s, not '%s'", ConfirmAlways, ConfirmNever, ConfirmTTY, confirm)
+	}
+
 	client := newClient(ac)
 	rt := &Runtime{
 		account: ac,
 		client:  client,
+		confirm: confirm,
+		protect: conf.ProtectLabel,
 	}
 	return rt, nil
 }
@@ -1,10 +1,13 @@
 package runtime
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,7 +30,7 @@ func Test_NewRuntime(t *testing.T) {
 
 	testCases := []testCase{
 		{
-			Configuration:        Config{[]ProjectEntry{testAccount}},
+			Configuration:        Config{Projects: []ProjectEntry{testAccount}},
 			AccountName:          testAccount.Name,
 			Environment:          []string{},
 			ExpectedRuntimeIsNil: false,
@@ -35,7 +38,7 @@ func Test_NewRuntime(t *testing.T) {
 			ExpectedErrorIsNil:   true,
 		},
 		{
-			Configuration:        Config{[]ProjectEntry{testAccount}},
+			Configuration:        Config{Projects: []ProjectEntry{testAccount}},
 			AccountName:          "default",
 			Environment:          []string{},
 			ExpectedRuntimeIsNil: true,
@@ -43,7 +46,7 @@ func Test_NewRuntime(t *testing.T) {
 			ExpectedErrorIsNil:   false,
 		},
 		{
-			Configuration:        Config{[]ProjectEntry{}},
+			Configuration:        Config{Projects: []ProjectEntry{}},
 			AccountName:          "default",
 			Environment:          []string{},
 			ExpectedRuntimeIsNil: true,
@@ -51,7 +54,7 @@ func Test_NewRuntime(t *testing.T) {
 			ExpectedErrorIsNil:   false,
 		},
 		{
-			Configuration:        Config{[]ProjectEntry{testAccount}},
+			Configuration:        Config{Projects: []ProjectEntry{testAccount}},
 			AccountName:          testAccount.Name,
 			Environment:          []string{"GRIDSCALE_UUID=envUserId", "GRIDSCALE_TOKEN=envToken", "GRIDSCALE_URL=env.example.com"},
 			ExpectedRuntimeIsNil: false,
@@ -59,12 +62,20 @@ func Test_NewRuntime(t *testing.T) {
 			ExpectedErrorIsNil:   true,
 		},
 		{
-			Configuration:        Config{[]ProjectEntry{}},
+			Configuration:        Config{Projects: []ProjectEntry{}},
 			Environment:          []string{"GRIDSCALE_UUID=envUserId", "GRIDSCALE_TOKEN=envToken", "GRIDSCALE_URL=env.example.com"},
 			ExpectedRuntimeIsNil: false,
 			ExpectedAccount:      ProjectEntry{UserID: "envUserId", Token: "envToken", URL: "env.example.com"},
 			ExpectedErrorIsNil:   true,
 		},
+		{
+			Configuration:        Config{ConfirmDestructive: "sometimes", Projects: []ProjectEntry{testAccount}},
+			AccountName:          testAccount.Name,
+			Environment:          []string{},
+			ExpectedRuntimeIsNil: true,
+			ExpectedAccount:      ProjectEntry{},
+			ExpectedErrorIsNil:   false,
+		},
 	}
 
 	for _, test := range testCases {
@@ -94,3 +105,67 @@ func resetEnv(environ []string) {
 		}
 	}
 }
+
+func Test_ParseConfigDefaults(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.SetConfigType("yaml")
+	err := viper.ReadConfig(strings.NewReader(`
+projects:
+  - name: prod
+    userId: u1
+    token: t1
+    defaults:
+      output: json
+      labels: [env=prod]
+      server:
+        cores: 2
+        template: Ubuntu 22.04 LTS
+`))
+	assert.Nil(t, err)
+	conf, err := ParseConfig()
+	assert.Nil(t, err)
+	defaults := conf.Projects[0].Defaults
+	assert.Equal(t, "json", defaults.Output)
+	assert.Equal(t, []string{"env=prod"}, defaults.Labels)
+	assert.Equal(t, map[string]interface{}{"server": map[string]interface{}{"cores": 2, "template": "Ubuntu 22.04 LTS"}}, defaults.Flags)
+}
+
+func Test_WriteConfigKeepsComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`# gscloud configuration
+editor: vim # not used by gscloud
+defaultProject: staging
+accounts:
+  # Staging project
+  - name: staging
+    userId: user-1
+    token: token-1 # rotated monthly
+    url: https://api.gridscale.io
+  - name: prod
+    userId: user-2
+    token: token-2
+    url: https://api.gridscale.io
+`), 0600))
+
+	conf := &Config{Projects: []ProjectEntry{
+		{Name: "staging", UserID: "user-1", Token: "token-3", URL: "https://api.gridscale.io"},
+		{Name: "dev", UserID: "user-3", Token: "token-4", URL: "https://api.gridscale.io"},
+	}}
+	assert.Nil(t, WriteConfig(conf, path))
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, `# gscloud configuration
+editor: vim # not used by gscloud
+accounts:
+  # Staging project
+  - name: staging
+    userId: user-1
+    token: token-3 # rotated monthly
+    url: https://api.gridscale.io
+  - name: dev
+    userId: user-3
+    token: token-4
+    url: https://api.gridscale.io
+`, string(data))
+}
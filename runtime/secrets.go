@@ -0,0 +1,110 @@
+package runtime
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// keyringService is the service name tokens are stored under in the
+// keyring of the operating system.
+const keyringService = "gscloud"
+
+// Prefixes of references to secrets kept outside of the configuration file.
+const (
+	keyringPrefix = "keyring:"
+	envPrefix     = "env:"
+	filePrefix    = "file:"
+	cmdPrefix     = "cmd:"
+)
+
+// IsSecretReference tells whether val refers to a secret kept elsewhere
+// rather than being the secret itself.
+func IsSecretReference(val string) bool {
+	for _, prefix := range []string{keyringPrefix, envPrefix, filePrefix, cmdPrefix} {
+		if strings.HasPrefix(val, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveSecret returns the secret val refers to:
+//   - keyring:NAME, the secret stored as NAME in the keyring of the
+//     operating system
+//   - env:VAR, the value of the environment variable VAR
+//   - file:PATH, the contents of the file at PATH
+//   - cmd:COMMAND, the output of COMMAND run by the shell
+//
+// Surrounding white space is removed. Any other value is returned as it is.
+func ResolveSecret(val string) (string, error) {
+	var secret string
+	switch {
+	case strings.HasPrefix(val, keyringPrefix):
+		name := strings.TrimPrefix(val, keyringPrefix)
+		s, err := keyringGet(keyringService, name)
+		if err != nil {
+			return "", fmt.Errorf("cannot read %q from keyring: %w", name, err)
+		}
+		secret = s
+	case strings.HasPrefix(val, envPrefix):
+		name := strings.TrimPrefix(val, envPrefix)
+		s, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		secret = s
+	case strings.HasPrefix(val, filePrefix):
+		data, err := ioutil.ReadFile(strings.TrimPrefix(val, filePrefix))
+		if err != nil {
+			return "", err
+		}
+		secret = string(data)
+	case strings.HasPrefix(val, cmdPrefix):
+		command := strings.TrimPrefix(val, cmdPrefix)
+		var stderr bytes.Buffer
+		c := shellCommand(command)
+		c.Stderr = &stderr
+		out, err := c.Output()
+		if err != nil {
+			return "", fmt.Errorf("command %q failed: %w: %s", command, err, strings.TrimSpace(stderr.String()))
+		}
+		secret = string(out)
+	default:
+		return val, nil
+	}
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return "", fmt.Errorf("%s is empty", val)
+	}
+	return secret, nil
+}
+
+// StoreInKeyring stores secret as name in the keyring of the operating system
+// and returns a reference to it.
+func StoreInKeyring(name, secret string) (string, error) {
+	err := keyringSet(keyringService, name, secret)
+	if err != nil {
+		return "", err
+	}
+	return keyringPrefix + name, nil
+}
+
+// StoreInFile writes secret to a new file at path, readable only by the
+// user, and returns a reference to it. An existing file is not overwritten.
+func StoreInFile(path, secret string) (string, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	_, err = f.WriteString(secret + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	return filePrefix + path, nil
+}
@@ -0,0 +1,49 @@
+//go:build darwin
+// +build darwin
+
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keyringGet reads a secret from the macOS keychain.
+func keyringGet(service, name string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", name, "-w").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// keyringSet stores a secret in the macOS keychain, replacing any secret
+// stored under the same name. The command is given to security(1) on
+// standard input, so the secret does not show up in the list of processes.
+func keyringSet(service, name, secret string) error {
+	for _, arg := range []string{service, name, secret} {
+		if strings.ContainsAny(arg, "\r\n") {
+			return errors.New("keychain entries must not contain line breaks")
+		}
+	}
+	c := exec.Command("security", "-i")
+	c.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		securityQuote(service), securityQuote(name), securityQuote(secret)))
+	out, err := c.CombinedOutput()
+	if err != nil {
+		return err
+	}
+	// In interactive mode, security(1) reports errors of commands on its
+	// output but exits successfully. It may also print its prompt.
+	if msg := strings.TrimSpace(strings.ReplaceAll(string(out), "security>", "")); msg != "" {
+		return fmt.Errorf("security: %s", msg)
+	}
+	return nil
+}
+
+// securityQuote quotes s as an argument of a command read by security -i.
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,27 @@
+//go:build linux || freebsd || openbsd
+// +build linux freebsd openbsd
+
+package runtime
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// keyringGet reads a secret from the Secret Service, e.g. GNOME Keyring or
+// KWallet, using secret-tool(1).
+func keyringGet(service, name string) (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", service, "account", name).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// keyringSet stores a secret in the Secret Service using secret-tool(1),
+// replacing any secret stored under the same name.
+func keyringSet(service, name, secret string) error {
+	c := exec.Command("secret-tool", "store", "--label", service+" "+name, "service", service, "account", name)
+	c.Stdin = strings.NewReader(secret)
+	return c.Run()
+}
@@ -0,0 +1,58 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token")
+	ref, err := StoreInFile(path, "fileToken")
+	assert.Nil(t, err)
+	assert.Equal(t, "file:"+path, ref)
+	_, err = StoreInFile(path, "other")
+	assert.NotNil(t, err)
+	info, _ := os.Stat(path)
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	os.Setenv("GSCLOUD_TEST_TOKEN", " envToken\n")
+	defer os.Unsetenv("GSCLOUD_TEST_TOKEN")
+
+	for val, expected := range map[string]string{
+		"plainToken":             "plainToken",
+		"env:GSCLOUD_TEST_TOKEN": "envToken",
+		ref:                      "fileToken",
+		"cmd:echo cmdToken":      "cmdToken",
+	} {
+		secret, err := ResolveSecret(val)
+		assert.Nil(t, err, val)
+		assert.Equal(t, expected, secret, val)
+	}
+
+	for _, val := range []string{"env:GSCLOUD_TEST_UNSET", "file:" + filepath.Join(dir, "missing"), "cmd:exit 1", "cmd:echo"} {
+		_, err := ResolveSecret(val)
+		assert.NotNil(t, err, val)
+	}
+
+	assert.True(t, IsSecretReference("keyring:default"))
+	assert.False(t, IsSecretReference("0123abcd"))
+}
+
+func Test_WriteConfigMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(t, ioutil.WriteFile(path, nil, 0644))
+	assert.Nil(t, WriteConfig(&Config{Projects: []ProjectEntry{{Name: "default", Token: "keyring:default"}}}, path))
+	info, _ := os.Stat(path)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package runtime
+
+import "os/exec"
+
+// shellCommand returns the command running command in the shell.
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("/bin/sh", "-c", command)
+}
@@ -0,0 +1,24 @@
+//go:build windows
+// +build windows
+
+package runtime
+
+import (
+	"errors"
+	"os/exec"
+)
+
+var errNoKeyring = errors.New("keyring is not supported on Windows, use env:, file:, or cmd: instead")
+
+// shellCommand returns the command running command in the shell.
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("cmd", "/C", command)
+}
+
+func keyringGet(service, name string) (string, error) {
+	return "", errNoKeyring
+}
+
+func keyringSet(service, name, secret string) error {
+	return errNoKeyring
+}